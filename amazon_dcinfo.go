@@ -16,7 +16,8 @@
 package goEurekaClient
 
 const (
-	amazonInstanceID = "instance-id"
+	amazonInstanceID    = "instance-id"
+	availabilityZoneKey = "availability-zone"
 )
 
 // Amazon datacenter information
@@ -28,6 +29,6 @@ func (amazon *amazonInfo) GetID(dcinfo *DatacenterInfo) string {
 		return ""
 	}
 
-	uid := dcinfo.Metadata[amazonInstanceID].(string)
+	uid, _ := dcinfo.Metadata[amazonInstanceID].(string)
 	return uid
 }
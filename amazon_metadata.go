@@ -0,0 +1,135 @@
+// Copyright 2016 IBM Corporation
+//
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+//
+//       http://www.apache.org/licenses/LICENSE-2.0
+//
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+
+//Package goEurekaClient Implements a go client that interacts with a eureka server
+package goEurekaClient
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultAmazonMetadataURL is the base URL of the EC2 instance metadata service
+	DefaultAmazonMetadataURL = "http://169.254.169.254"
+
+	amazonDatacenterClass = "com.netflix.appinfo.AmazonInfo"
+	amazonDatacenterName  = "Amazon"
+	amazonTokenTTL        = 6 * time.Hour
+	amazonTokenHeader     = "X-aws-ec2-metadata-token"
+	amazonTokenTTLHeader  = "X-aws-ec2-metadata-token-ttl-seconds"
+)
+
+// amazonMetadataPaths maps the metadata keys expected by eureka to their path in the metadata service.
+// Optional keys (e.g. the public addresses of instances in a private subnet) may be missing.
+var amazonMetadataPaths = []struct {
+	key      string
+	path     string
+	optional bool
+}{
+	{amazonInstanceID, "instance-id", false},
+	{"ami-id", "ami-id", false},
+	{"instance-type", "instance-type", false},
+	{availabilityZoneKey, "placement/availability-zone", false},
+	{"local-ipv4", "local-ipv4", false},
+	{"local-hostname", "local-hostname", false},
+	{"public-ipv4", "public-ipv4", true},
+	{"public-hostname", "public-hostname", true},
+	{"ami-launch-index", "ami-launch-index", true},
+}
+
+type amazonMetadataProvider struct {
+	sync.Mutex
+	baseURL     string
+	httpClient  *http.Client
+	token       string
+	tokenExpiry time.Time
+}
+
+// NewAmazonMetadataProvider creates a provider which reads the datacenter information from the
+// EC2 instance metadata service, using an IMDSv2 session token.
+// An empty baseURL means DefaultAmazonMetadataURL.
+func NewAmazonMetadataProvider(baseURL string, timeout time.Duration) DatacenterInfoProvider {
+	if baseURL == "" {
+		baseURL = DefaultAmazonMetadataURL
+	}
+	return &amazonMetadataProvider{
+		baseURL:    strings.TrimSuffix(baseURL, "/"),
+		httpClient: &http.Client{Timeout: timeout},
+	}
+}
+
+// DatacenterInfo returns the Amazon datacenter information of the running EC2 instance.
+func (p *amazonMetadataProvider) DatacenterInfo() (*DatacenterInfo, error) {
+	token, err := p.sessionToken()
+	if err != nil {
+		return nil, fmt.Errorf("Failed to get metadata session token. error: %s", err)
+	}
+
+	metadata := DatacenterMetadata{}
+	for _, entry := range amazonMetadataPaths {
+		value, found, err := p.get(token, entry.path)
+		if err != nil {
+			return nil, fmt.Errorf("Failed to read metadata %s. error: %s", entry.path, err)
+		}
+		if !found {
+			if !entry.optional {
+				return nil, fmt.Errorf("metadata %s not found", entry.path)
+			}
+			continue
+		}
+		metadata[entry.key] = value
+	}
+
+	return &DatacenterInfo{
+		Class:    amazonDatacenterClass,
+		Name:     amazonDatacenterName,
+		Metadata: metadata,
+	}, nil
+}
+
+// sessionToken returns the current session token, requesting a new one when it is about to expire.
+func (p *amazonMetadataProvider) sessionToken() (string, error) {
+	p.Lock()
+	defer p.Unlock()
+
+	if p.token != "" && time.Now().Before(p.tokenExpiry) {
+		return p.token, nil
+	}
+
+	headers := map[string]string{amazonTokenTTLHeader: strconv.Itoa(int(amazonTokenTTL / time.Second))}
+	body, found, err := getMetadata(p.httpClient, "PUT", p.baseURL+"/latest/api/token", headers)
+	if err != nil {
+		return "", err
+	}
+	if !found {
+		return "", errors.New("token endpoint not found")
+	}
+
+	p.token = string(body)
+	// Renew the token a minute before it expires
+	p.tokenExpiry = time.Now().Add(amazonTokenTTL - time.Minute)
+	return p.token, nil
+}
+
+func (p *amazonMetadataProvider) get(token, path string) (string, bool, error) {
+	headers := map[string]string{amazonTokenHeader: token}
+	body, found, err := getMetadata(p.httpClient, "GET", p.baseURL+"/latest/meta-data/"+path, headers)
+	return strings.TrimSpace(string(body)), found, err
+}
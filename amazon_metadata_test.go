@@ -0,0 +1,110 @@
+// Copyright 2016 IBM Corporation
+//
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+//
+//       http://www.apache.org/licenses/LICENSE-2.0
+//
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+
+//Package goEurekaClient Implements a go client that interacts with a eureka server
+package goEurekaClient
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newMetadataStub creates a stub of the EC2 instance metadata service, which requires an IMDSv2 token.
+func newMetadataStub(metadata map[string]string) (*httptest.Server, *int) {
+	tokenRequests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/latest/api/token" {
+			if r.Method != "PUT" || r.Header.Get(amazonTokenTTLHeader) == "" {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			tokenRequests++
+			w.Write([]byte("token1"))
+			return
+		}
+		if r.Header.Get(amazonTokenHeader) != "token1" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		value, ok := metadata[strings.TrimPrefix(r.URL.Path, "/latest/meta-data/")]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Write([]byte(value))
+	}))
+	return server, &tokenRequests
+}
+
+func TestAmazonMetadataProvider(t *testing.T) {
+	server, tokenRequests := newMetadataStub(map[string]string{
+		"instance-id":                 "i-1234",
+		"ami-id":                      "ami-5678",
+		"instance-type":               "m4.large",
+		"placement/availability-zone": "us-east-1c",
+		"local-ipv4":                  "10.0.0.1",
+		"local-hostname":              "ip-10-0-0-1.ec2.internal",
+	})
+	defer server.Close()
+
+	provider := NewAmazonMetadataProvider(server.URL, 5*time.Second)
+	dcinfo, err := provider.DatacenterInfo()
+	if err != nil {
+		t.Fatalf("error = %v", err)
+	}
+
+	if dcinfo.Name != amazonDatacenterName || dcinfo.Class != amazonDatacenterClass {
+		t.Errorf("unexpected datacenter %s %s", dcinfo.Name, dcinfo.Class)
+	}
+	if dcinfo.Metadata[availabilityZoneKey] != "us-east-1c" || dcinfo.Metadata["local-ipv4"] != "10.0.0.1" {
+		t.Errorf("unexpected metadata %v", dcinfo.Metadata)
+	}
+	if _, ok := dcinfo.Metadata["public-ipv4"]; ok {
+		t.Errorf("missing optional metadata should be skipped, got %v", dcinfo.Metadata)
+	}
+
+	id, err := resolveInstanceID(&Instance{HostName: "host", Datacenter: dcinfo})
+	if err != nil || id != "i-1234" {
+		t.Errorf("instance id should be resolved from the metadata, got %s (%v)", id, err)
+	}
+
+	if _, err = provider.DatacenterInfo(); err != nil {
+		t.Fatalf("error = %v", err)
+	}
+	if *tokenRequests != 1 {
+		t.Errorf("session token should be reused, requested %d times", *tokenRequests)
+	}
+}
+
+func TestAmazonMetadataProviderMissingInstanceID(t *testing.T) {
+	server, _ := newMetadataStub(map[string]string{})
+	defer server.Close()
+
+	if _, err := NewAmazonMetadataProvider(server.URL, 5*time.Second).DatacenterInfo(); err == nil {
+		t.Error("missing required metadata should be reported")
+	}
+}
+
+func TestAmazonInfoGetIDWithoutInstanceID(t *testing.T) {
+	var info amazonInfo
+	if id := info.GetID(&DatacenterInfo{Name: "Amazon", Metadata: DatacenterMetadata{"ami-id": "ami-5678"}}); id != "" {
+		t.Errorf("id should be empty, got %s", id)
+	}
+	if id := info.GetID(&DatacenterInfo{Name: "Amazon", Metadata: DatacenterMetadata{amazonInstanceID: 12}}); id != "" {
+		t.Errorf("id should be empty, got %s", id)
+	}
+}
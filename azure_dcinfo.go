@@ -0,0 +1,128 @@
+// Copyright 2016 IBM Corporation
+//
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+//
+//       http://www.apache.org/licenses/LICENSE-2.0
+//
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+
+//Package goEurekaClient Implements a go client that interacts with a eureka server
+package goEurekaClient
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	// DefaultAzureMetadataURL is the base URL of the Azure instance metadata service
+	DefaultAzureMetadataURL = "http://169.254.169.254"
+
+	azureDatacenterName = "Azure"
+	azureAPIVersion     = "2021-02-01"
+	azureVMID           = "vm-id"
+)
+
+// Azure datacenter information
+type azureIdentifier struct{}
+
+// Returns the unique identifier of this datacenter info
+func (azure *azureIdentifier) GetID(dcinfo *DatacenterInfo) string {
+	return metadataString(dcinfo, azureVMID)
+}
+
+// azureInstanceMetadata is the subset of the instance metadata document used by the provider
+type azureInstanceMetadata struct {
+	Compute struct {
+		VMID              string `json:"vmId"`
+		Name              string `json:"name"`
+		Location          string `json:"location"`
+		Zone              string `json:"zone"`
+		VMSize            string `json:"vmSize"`
+		ResourceGroupName string `json:"resourceGroupName"`
+		SubscriptionID    string `json:"subscriptionId"`
+	} `json:"compute"`
+	Network struct {
+		Interface []struct {
+			IPv4 struct {
+				IPAddress []struct {
+					PrivateIPAddress string `json:"privateIpAddress"`
+					PublicIPAddress  string `json:"publicIpAddress"`
+				} `json:"ipAddress"`
+			} `json:"ipv4"`
+		} `json:"interface"`
+	} `json:"network"`
+}
+
+type azureMetadataProvider struct {
+	baseURL    string
+	httpClient *http.Client
+}
+
+// NewAzureMetadataProvider creates a provider which reads the datacenter information from the Azure
+// instance metadata service. An empty baseURL means DefaultAzureMetadataURL.
+func NewAzureMetadataProvider(baseURL string, timeout time.Duration) DatacenterInfoProvider {
+	if baseURL == "" {
+		baseURL = DefaultAzureMetadataURL
+	}
+	return &azureMetadataProvider{
+		baseURL:    strings.TrimSuffix(baseURL, "/"),
+		httpClient: &http.Client{Timeout: timeout},
+	}
+}
+
+// DatacenterInfo returns the Azure datacenter information of the running VM.
+func (p *azureMetadataProvider) DatacenterInfo() (*DatacenterInfo, error) {
+	url := p.baseURL + "/metadata/instance?api-version=" + azureAPIVersion
+	body, found, err := getMetadata(p.httpClient, "GET", url, map[string]string{"Metadata": "true"})
+	if err != nil {
+		return nil, fmt.Errorf("Failed to read instance metadata. error: %s", err)
+	}
+	if !found {
+		return nil, fmt.Errorf("instance metadata not found")
+	}
+
+	var doc azureInstanceMetadata
+	if err = json.Unmarshal(body, &doc); err != nil {
+		return nil, fmt.Errorf("parsing instance metadata %v", err)
+	}
+	if doc.Compute.VMID == "" {
+		return nil, fmt.Errorf("metadata vmId not found")
+	}
+
+	metadata := DatacenterMetadata{
+		azureVMID:        doc.Compute.VMID,
+		"vm-name":        doc.Compute.Name,
+		"location":       doc.Compute.Location,
+		"vm-size":        doc.Compute.VMSize,
+		"resource-group": doc.Compute.ResourceGroupName,
+		"subscription":   doc.Compute.SubscriptionID,
+	}
+	// Azure zones are numbered within a location, thus the location prefixes the zone
+	// the same way a region prefixes an availability zone
+	if doc.Compute.Zone != "" {
+		metadata[availabilityZoneKey] = doc.Compute.Location + "-" + doc.Compute.Zone
+	}
+	if len(doc.Network.Interface) > 0 && len(doc.Network.Interface[0].IPv4.IPAddress) > 0 {
+		address := doc.Network.Interface[0].IPv4.IPAddress[0]
+		metadata["local-ipv4"] = address.PrivateIPAddress
+		if address.PublicIPAddress != "" {
+			metadata["public-ipv4"] = address.PublicIPAddress
+		}
+	}
+
+	return &DatacenterInfo{
+		Class:    customDatacenterClass,
+		Name:     azureDatacenterName,
+		Metadata: metadata,
+	}, nil
+}
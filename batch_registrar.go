@@ -0,0 +1,399 @@
+// Copyright 2016 IBM Corporation
+//
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+//
+//       http://www.apache.org/licenses/LICENSE-2.0
+//
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+
+//Package goEurekaClient Implements a go client that interacts with a eureka server
+package goEurekaClient
+
+import (
+	"context"
+	"errors"
+	"hash/fnv"
+	"log"
+	"sync"
+	"time"
+)
+
+const defaultBatchWorkers = 8
+
+// Operations reported in registration results.
+const (
+	RegisterOperation   = "register"
+	HeartbeatOperation  = "heartbeat"
+	DeregisterOperation = "deregister"
+)
+
+// RegistrationResult is the outcome of a request sent by a batch registrar for an instance.
+type RegistrationResult struct {
+	Key       string // application name and instance ID, such as APP1/inst1
+	Operation string
+	Time      time.Time
+	Error     error
+}
+
+// BatchRegistrarConfig defines the way a batch registrar keeps its instances alive.
+type BatchRegistrarConfig struct {
+	HeartbeatInterval time.Duration // default 30s
+	Workers           int           // maximum number of concurrent requests, default 8
+	// ResultHandler is called with the result of every request, from the worker sending it. nil indicates
+	// that no notifications are needed.
+	ResultHandler func(RegistrationResult)
+}
+
+// BatchRegistrar registers a set of instances and renews their leases with a bounded pool of workers.
+// The heartbeats of each instance are sent at a fixed offset within the heartbeat interval, derived from
+// the instance key, so that the renewals of many instances are spread over the interval.
+// An instance whose heartbeat fails is registered again.
+type BatchRegistrar interface {
+	// Add adds an instance, which is registered by the next free worker. Adding an instance with the key
+	// of a managed instance replaces it, and registers it again.
+	Add(inst *Instance) error
+	// Remove removes the instance with the key of inst, and deregisters it.
+	Remove(inst *Instance) error
+	// Instances returns the managed instances.
+	Instances() []*Instance
+	// Results returns the result of the last request of each instance, by key. The results of removed
+	// instances are dropped once they are deregistered.
+	Results() map[string]RegistrationResult
+	// Run sends the requests until the context is done. It then deregisters the registered instances.
+	Run(ctx context.Context)
+}
+
+type batchEntry struct {
+	key        string
+	instance   *Instance
+	registered bool // the registry holds the instance
+	update     bool // the instance must be registered again
+	removed    bool
+	busy       bool // a request is in flight
+	due        time.Time
+}
+
+type batchJob struct {
+	entry     *batchEntry
+	operation string
+	instance  *Instance
+}
+
+type batchRegistrar struct {
+	sync.Mutex
+	registrator Registrator
+	config      BatchRegistrarConfig
+	entries     map[string]*batchEntry
+	results     map[string]RegistrationResult
+	wake        chan struct{}
+	now         func() time.Time
+}
+
+// NewBatchRegistrar creates a batch registrar sending its requests through the registrator.
+func NewBatchRegistrar(registrator Registrator, config BatchRegistrarConfig) (BatchRegistrar, error) {
+	if registrator == nil {
+		return nil, errors.New("registrator must be defined")
+	}
+	if config.HeartbeatInterval <= 0 {
+		config.HeartbeatInterval = defaultHeartbeatInterval
+	}
+	if config.Workers <= 0 {
+		config.Workers = defaultBatchWorkers
+	}
+
+	return &batchRegistrar{
+		registrator: registrator,
+		config:      config,
+		entries:     map[string]*batchEntry{},
+		results:     map[string]RegistrationResult{},
+		wake:        make(chan struct{}, 1),
+		now:         time.Now,
+	}, nil
+}
+
+func batchKey(inst *Instance) (string, error) {
+	if inst == nil {
+		return "", errors.New("instance must be defined")
+	}
+	id, err := resolveInstanceID(inst)
+	if err != nil {
+		return "", err
+	}
+	return inst.Application + "/" + id, nil
+}
+
+// Add adds an instance, which is registered by the next free worker.
+func (b *batchRegistrar) Add(inst *Instance) error {
+	key, err := batchKey(inst)
+	if err != nil {
+		return err
+	}
+	copied := *inst
+
+	b.Lock()
+	entry, ok := b.entries[key]
+	if !ok {
+		entry = &batchEntry{key: key}
+		b.entries[key] = entry
+	}
+	entry.instance = &copied
+	entry.update = true
+	entry.removed = false
+	entry.due = time.Time{}
+	b.Unlock()
+
+	b.wakeUp()
+	return nil
+}
+
+// Remove removes the instance with the key of inst, and deregisters it.
+func (b *batchRegistrar) Remove(inst *Instance) error {
+	key, err := batchKey(inst)
+	if err != nil {
+		return err
+	}
+
+	b.Lock()
+	entry, ok := b.entries[key]
+	if !ok || entry.removed {
+		b.Unlock()
+		return errors.New("instance " + key + " is not managed")
+	}
+	entry.removed = true
+	entry.due = time.Time{}
+	b.Unlock()
+
+	b.wakeUp()
+	return nil
+}
+
+// Instances returns the managed instances.
+func (b *batchRegistrar) Instances() []*Instance {
+	b.Lock()
+	defer b.Unlock()
+
+	instances := make([]*Instance, 0, len(b.entries))
+	for _, entry := range b.entries {
+		if !entry.removed {
+			copied := *entry.instance
+			instances = append(instances, &copied)
+		}
+	}
+	return instances
+}
+
+// Results returns the result of the last request of each instance, by key.
+func (b *batchRegistrar) Results() map[string]RegistrationResult {
+	b.Lock()
+	defer b.Unlock()
+
+	results := make(map[string]RegistrationResult, len(b.results))
+	for key, result := range b.results {
+		results[key] = result
+	}
+	return results
+}
+
+// Run sends the requests until the context is done, and then deregisters the registered instances.
+func (b *batchRegistrar) Run(ctx context.Context) {
+	jobs, workers := b.startWorkers()
+	for {
+		for _, job := range b.dueJobs() {
+			select {
+			case jobs <- job:
+			case <-ctx.Done():
+				b.release(job)
+			}
+		}
+
+		timer := time.NewTimer(b.nextDue())
+		select {
+		case <-timer.C:
+		case <-b.wake:
+		case <-ctx.Done():
+			timer.Stop()
+			log.Printf("stop chan received. stop running batch registrar...")
+			close(jobs)
+			workers.Wait()
+
+			// The requests in flight are done, so that no heartbeat registers an instance again
+			jobs, workers = b.startWorkers()
+			for _, job := range b.deregistrations() {
+				jobs <- job
+			}
+			close(jobs)
+			workers.Wait()
+			return
+		}
+		timer.Stop()
+	}
+}
+
+// startWorkers starts the workers executing the jobs sent to the returned channel, until it is closed.
+func (b *batchRegistrar) startWorkers() (chan<- *batchJob, *sync.WaitGroup) {
+	jobs := make(chan *batchJob)
+	workers := &sync.WaitGroup{}
+	for i := 0; i < b.config.Workers; i++ {
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			for job := range jobs {
+				b.execute(job)
+			}
+		}()
+	}
+	return jobs, workers
+}
+
+func (b *batchRegistrar) wakeUp() {
+	select {
+	case b.wake <- struct{}{}:
+	default:
+	}
+}
+
+// dueJobs marks the idle entries whose request is due as busy, and returns their requests.
+// Removed entries which were never registered are dropped.
+func (b *batchRegistrar) dueJobs() []*batchJob {
+	b.Lock()
+	defer b.Unlock()
+
+	now := b.now()
+	var jobs []*batchJob
+	for key, entry := range b.entries {
+		if entry.busy || entry.due.After(now) {
+			continue
+		}
+		if entry.removed && !entry.registered {
+			delete(b.entries, key)
+			continue
+		}
+		job := &batchJob{entry: entry, operation: HeartbeatOperation, instance: entry.instance}
+		switch {
+		case entry.removed:
+			job.operation = DeregisterOperation
+		case !entry.registered || entry.update:
+			job.operation = RegisterOperation
+		}
+		entry.busy = true
+		jobs = append(jobs, job)
+	}
+	return jobs
+}
+
+// deregistrations marks the registered entries as removed, and returns their deregistration requests.
+func (b *batchRegistrar) deregistrations() []*batchJob {
+	b.Lock()
+	defer b.Unlock()
+
+	var jobs []*batchJob
+	for _, entry := range b.entries {
+		if entry.registered {
+			entry.removed = true
+			jobs = append(jobs, &batchJob{entry: entry, operation: DeregisterOperation, instance: entry.instance})
+		}
+	}
+	return jobs
+}
+
+func (b *batchRegistrar) release(job *batchJob) {
+	b.Lock()
+	defer b.Unlock()
+	job.entry.busy = false
+}
+
+// nextDue returns the time until the earliest request of the idle entries.
+func (b *batchRegistrar) nextDue() time.Duration {
+	b.Lock()
+	defer b.Unlock()
+
+	next := b.config.HeartbeatInterval
+	now := b.now()
+	for _, entry := range b.entries {
+		if !entry.busy {
+			if wait := entry.due.Sub(now); wait < next {
+				next = wait
+			}
+		}
+	}
+	if next < 0 {
+		next = 0
+	}
+	return next
+}
+
+// renewalTime returns the first time after now at the offset of the key within the heartbeat interval.
+func (b *batchRegistrar) renewalTime(key string, now time.Time) time.Time {
+	interval := b.config.HeartbeatInterval
+	hash := fnv.New64a()
+	hash.Write([]byte(key))
+	offset := time.Duration(hash.Sum64() % uint64(interval))
+
+	due := now.Truncate(interval).Add(offset)
+	for !due.After(now) {
+		due = due.Add(interval)
+	}
+	return due
+}
+
+// execute sends the request of a job, and schedules the next request of its entry.
+func (b *batchRegistrar) execute(job *batchJob) {
+	var results []RegistrationResult
+	var err error
+	switch job.operation {
+	case RegisterOperation:
+		err = b.registrator.Register(job.instance)
+	case HeartbeatOperation:
+		if err = b.registrator.Heartbeat(job.instance); err != nil {
+			log.Printf("Failed to send heartbeat for instance %s, registering again. error: %s\n", job.entry.key, err)
+			results = append(results, RegistrationResult{Key: job.entry.key, Operation: job.operation,
+				Time: b.now(), Error: err})
+			job.operation = RegisterOperation
+			err = b.registrator.Register(job.instance)
+		}
+	case DeregisterOperation:
+		err = b.registrator.Deregister(job.instance)
+	}
+	if err != nil {
+		log.Printf("Failed to %s instance %s. error: %s\n", job.operation, job.entry.key, err)
+	}
+	results = append(results, RegistrationResult{Key: job.entry.key, Operation: job.operation, Time: b.now(),
+		Error: err})
+
+	b.Lock()
+	entry := job.entry
+	entry.busy = false
+	switch job.operation {
+	case RegisterOperation:
+		entry.registered = err == nil
+		if err == nil && entry.instance == job.instance {
+			entry.update = false
+		}
+	case DeregisterOperation:
+		entry.registered = err != nil
+	}
+	b.results[entry.key] = results[len(results)-1]
+	switch {
+	case entry.removed && !entry.registered:
+		delete(b.entries, entry.key)
+		delete(b.results, entry.key)
+	case entry.instance != job.instance || entry.removed && job.operation != DeregisterOperation:
+		// Replaced or removed during the request, the next request is due
+	default:
+		entry.due = b.renewalTime(entry.key, b.now())
+	}
+	b.Unlock()
+
+	if b.config.ResultHandler != nil {
+		for _, result := range results {
+			b.config.ResultHandler(result)
+		}
+	}
+	b.wakeUp()
+}
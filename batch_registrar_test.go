@@ -0,0 +1,173 @@
+// Copyright 2016 IBM Corporation
+//
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+//
+//       http://www.apache.org/licenses/LICENSE-2.0
+//
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+
+//Package goEurekaClient Implements a go client that interacts with a eureka server
+package goEurekaClient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+)
+
+// requestCounter is a Registrator which counts the requests of each instance, and the requests in flight.
+type requestCounter struct {
+	Registrator
+	sync.Mutex
+	counts         map[string]int
+	inFlight       int
+	maxInFlight    int
+	failHeartbeats map[string]bool
+}
+
+func (c *requestCounter) request(operation string, inst *Instance) error {
+	c.Lock()
+	c.counts[operation+" "+inst.HostName]++
+	c.inFlight++
+	if c.inFlight > c.maxInFlight {
+		c.maxInFlight = c.inFlight
+	}
+	fail := operation == HeartbeatOperation && c.failHeartbeats[inst.HostName]
+	c.Unlock()
+
+	time.Sleep(5 * time.Millisecond)
+
+	c.Lock()
+	c.inFlight--
+	c.Unlock()
+	if fail {
+		return errors.New("instance not found")
+	}
+	return nil
+}
+
+func (c *requestCounter) Register(inst *Instance) error {
+	return c.request(RegisterOperation, inst)
+}
+
+func (c *requestCounter) Heartbeat(inst *Instance) error {
+	return c.request(HeartbeatOperation, inst)
+}
+
+func (c *requestCounter) Deregister(inst *Instance) error {
+	return c.request(DeregisterOperation, inst)
+}
+
+func (c *requestCounter) count(request string) int {
+	c.Lock()
+	defer c.Unlock()
+	return c.counts[request]
+}
+
+func waitFor(t *testing.T, description string, condition func() bool) {
+	deadline := time.Now().Add(5 * time.Second)
+	for !condition() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", description)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestBatchRegistrar(t *testing.T) {
+	counter := &requestCounter{counts: map[string]int{}, failHeartbeats: map[string]bool{"inst3": true}}
+	var handled sync.Map
+	batch, err := NewBatchRegistrar(counter, BatchRegistrarConfig{
+		HeartbeatInterval: 50 * time.Millisecond,
+		Workers:           3,
+		ResultHandler: func(result RegistrationResult) {
+			handled.Store(result.Key+" "+result.Operation, result.Error)
+		},
+	})
+	if err != nil {
+		t.Fatalf("error = %v", err)
+	}
+
+	for i := 0; i < 10; i++ {
+		if err = batch.Add(&Instance{Application: "APP1", HostName: fmt.Sprintf("inst%d", i)}); err != nil {
+			t.Fatalf("error = %v", err)
+		}
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	stopped := make(chan struct{})
+	go func() {
+		batch.Run(ctx)
+		close(stopped)
+	}()
+
+	waitFor(t, "heartbeats", func() bool { return counter.count("heartbeat inst9") >= 2 })
+	if counter.count("register inst9") != 1 || counter.count("register inst3") < 2 {
+		t.Errorf("expected a single registration, and registrations after failed heartbeats, got %v", counter.counts)
+	}
+	results := batch.Results()
+	if len(results) != 10 || results["APP1/inst9"].Error != nil || results["APP1/inst9"].Operation != HeartbeatOperation {
+		t.Errorf("unexpected results %v", results)
+	}
+	if err, ok := handled.Load("APP1/inst3 heartbeat"); !ok || err == nil {
+		t.Error("the failed heartbeat should be reported")
+	}
+
+	if err = batch.Remove(&Instance{Application: "APP1", HostName: "inst0"}); err != nil {
+		t.Fatalf("error = %v", err)
+	}
+	waitFor(t, "deregistration", func() bool { return counter.count("deregister inst0") == 1 })
+	heartbeats := counter.count("heartbeat inst0")
+	if len(batch.Instances()) != 9 {
+		t.Errorf("expected 9 instances, got %d", len(batch.Instances()))
+	}
+	if err = batch.Remove(&Instance{Application: "APP1", HostName: "inst0"}); err == nil {
+		t.Error("a removed instance should not be removed again")
+	}
+
+	batch.Add(&Instance{Application: "APP1", HostName: "inst10"})
+	waitFor(t, "registration", func() bool { return counter.count("register inst10") == 1 })
+
+	cancel()
+	<-stopped
+	if counter.count("heartbeat inst0") != heartbeats {
+		t.Error("a removed instance should not be renewed")
+	}
+	for i := 1; i <= 10; i++ {
+		if counter.count(fmt.Sprintf("deregister inst%d", i)) != 1 {
+			t.Errorf("inst%d should be deregistered when the registrar stops", i)
+		}
+	}
+	if counter.maxInFlight > 3 {
+		t.Errorf("%d requests were in flight, expected at most 3", counter.maxInFlight)
+	}
+}
+
+func TestBatchRegistrarSpreadsRenewals(t *testing.T) {
+	b := &batchRegistrar{config: BatchRegistrarConfig{HeartbeatInterval: 10 * time.Second}}
+	now := time.Unix(1000, 0)
+
+	slots := map[int64]bool{}
+	for i := 0; i < 50; i++ {
+		key := fmt.Sprintf("APP1/inst%d", i)
+		due := b.renewalTime(key, now)
+		if !due.After(now) || due.Sub(now) > 10*time.Second {
+			t.Fatalf("renewal of %s at %v, expected within the interval after %v", key, due, now)
+		}
+		if next := b.renewalTime(key, due); next.Sub(due) != 10*time.Second {
+			t.Errorf("renewals of %s should be an interval apart, got %v and %v", key, due, next)
+		}
+		slots[due.Unix()] = true
+	}
+	if len(slots) < 7 {
+		t.Errorf("renewals should be spread over the interval, got the seconds %v", slots)
+	}
+}
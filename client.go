@@ -20,14 +20,17 @@ import (
 	"context"
 	"crypto/tls"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"io/ioutil"
+	"io"
 	"log"
 	"net/http"
 	"net/url"
+	"reflect"
 	"sort"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -40,15 +43,47 @@ const (
 
 type client struct {
 	sync.Mutex
-	httpClient   *http.Client
-	eurekaURLs   []string
-	dictionary   dictionary
-	versionDelta int64
-	handler      InstanceEventHandler
-	UseJSON	     bool
+	settings      atomic.Value // *clientSettings
+	configLock    sync.Mutex   // serializes config updates
+	pollIntervals chan time.Duration
+	dictionary    dictionary
+	versionDelta  int64
+	handler       InstanceEventHandler
+	region        string
+	remoteRegions []string
+	filled        bool // the registry was fetched at least once
+
+	watchersLock sync.Mutex
+	watchers     map[chan struct{}]struct{} // notified after each refresh
+}
+
+// clientSettings holds the settings derived from a config which may be updated at runtime.
+// They are replaced as a whole, so that each request uses a consistent set of settings.
+type clientSettings struct {
+	httpClient    *http.Client
+	endpoints     *endpointManager
+	useJSON       bool
+	pollInterval  time.Duration // the poll interval of the config, 0 when not set
+	watchInterval time.Duration
 }
 
 func newClient(config *Config, handler InstanceEventHandler) (*client, error) {
+	settings, err := newClientSettings(config)
+	if err != nil {
+		return nil, err
+	}
+
+	cl := &client{
+		pollIntervals: make(chan time.Duration, 1),
+		handler:       handler,
+		region:        config.Region,
+		remoteRegions: config.FetchRemoteRegions,
+	}
+	cl.settings.Store(settings)
+	return cl, nil
+}
+
+func newClientSettings(config *Config) (*clientSettings, error) {
 	eurekaURLs, err := config.createUrlsList()
 	if eurekaURLs == nil {
 		return nil, err
@@ -84,13 +119,53 @@ func newClient(config *Config, handler InstanceEventHandler) (*client, error) {
 		}
 	}
 
-	cl := &client{
-		httpClient: hc,
-		eurekaURLs: urls,
-		handler:    handler,
-		UseJSON:    config.UseJSON,
+	return &clientSettings{httpClient: hc, endpoints: newEndpointManager(config, urls), useJSON: config.UseJSON,
+		pollInterval: config.PollInterval, watchInterval: config.WatchInterval}, nil
+}
+
+func (cl *client) currentSettings() *clientSettings {
+	return cl.settings.Load().(*clientSettings)
+}
+
+// updateConfig replaces the server list and HTTP client settings, and the poll interval when it is set.
+// The states of the servers which are still listed are kept.
+// The regions cannot change, since the cached instances are indexed by region.
+func (cl *client) updateConfig(config *Config) error {
+	if config == nil {
+		return errors.New("config must be defined")
 	}
-	return cl, nil
+	if config.Region != cl.region || !reflect.DeepEqual(nonNilStrings(config.FetchRemoteRegions), nonNilStrings(cl.remoteRegions)) {
+		return errors.New("regions cannot be changed at runtime")
+	}
+	if config.PollInterval < 0 {
+		return fmt.Errorf("poll interval %v must not be negative", config.PollInterval)
+	}
+	settings, err := newClientSettings(config)
+	if err != nil {
+		return err
+	}
+
+	cl.configLock.Lock()
+	defer cl.configLock.Unlock()
+	settings.endpoints.keepStates(cl.currentSettings().endpoints)
+	cl.settings.Store(settings)
+	if config.PollInterval > 0 {
+		// Only the latest interval matters when the run loop did not receive the previous one yet. The updates
+		// are serialized, so that the channel is empty once drained, even when no run loop receives it.
+		select {
+		case <-cl.pollIntervals:
+		default:
+		}
+		cl.pollIntervals <- config.PollInterval
+	}
+	return nil
+}
+
+func nonNilStrings(s []string) []string {
+	if s == nil {
+		return []string{}
+	}
+	return s
 }
 
 func (cl *client) run(pollInterval time.Duration, context context.Context) {
@@ -101,8 +176,11 @@ func (cl *client) run(pollInterval time.Duration, context context.Context) {
 		select {
 		case <-ticker.C:
 			cl.refresh(cl.handler)
+		case interval := <-cl.pollIntervals:
+			ticker.Stop()
+			ticker = time.NewTicker(interval)
 		case <-context.Done():
-			log.Printf("stop chan revieved. stop running discovery cache...")
+			log.Printf("stop chan received. stop running discovery cache...")
 			return
 		}
 	}
@@ -133,40 +211,43 @@ func (cl *client) refresh(handler InstanceEventHandler) {
 		cl.versionDelta = 0
 	}
 
+	cl.apply(dict, diff, handler)
+}
+
+// apply replaces the cache contents with a fetched dictionary, and notifies the handler of the differences.
+func (cl *client) apply(dict *dictionary, diff map[string]*Instance, handler InstanceEventHandler) {
 	oldDict := cl.dictionary.copyDictionary()
+	cl.Lock()
 	if dict.appNameIndex != nil || dict.vipIndex != nil || dict.svipIndex != nil {
-		cl.Lock()
 		cl.dictionary.vipIndex = dict.vipIndex
 		cl.dictionary.appNameIndex = dict.appNameIndex
 		cl.dictionary.svipIndex = dict.svipIndex
-		cl.Unlock()
+		cl.dictionary.groupIndex = dict.groupIndex
 	}
+	cl.filled = true
+	cl.Unlock()
+	defer cl.notifyWatchers()
 
 	// Send notifications
-	if len(diff) > 0 {
+	if len(diff) > 0 && handler != nil {
 		for name := range diff {
 			if diff[name].ActionType == actionAdded {
-				cl.handler.OnAdd(diff[name])
+				handler.OnAdd(diff[name])
 			} else if diff[name].ActionType == actionModified {
 				oldObj := oldDict.vipIndex[diff[name].VIPAddr][name]
-				cl.handler.OnUpdate(oldObj, diff[name])
+				handler.OnUpdate(oldObj, diff[name])
 			} else if diff[name].ActionType == actionDeleted {
-				cl.handler.OnDelete(diff[name])
+				handler.OnDelete(diff[name])
 			}
 		}
 	}
 }
 
+// fetchAll fetches the full registry, indexing the instances of each application as soon as it is decoded.
 func (cl *client) fetchAll() (*dictionary, error) {
-	apps, err := cl.fetchApps("apps")
-	if err != nil {
-		log.Printf("Faild to update full registry. %s\n", err)
-		return &cl.dictionary, err
-	}
-
 	dict := newDictionary()
-	if apps != nil && apps.Application != nil {
-		for _, app := range apps.Application {
+	err := cl.getStream("apps"+cl.regionsQuery(), func(body io.Reader) error {
+		_, err := decodeApplicationsList(body, func(app *Application) {
 			for _, inst := range app.Instances {
 				id, err := resolveInstanceID(inst)
 				if err != nil {
@@ -174,30 +255,15 @@ func (cl *client) fetchAll() (*dictionary, error) {
 					continue
 				}
 				inst.ID = id
-				if inst.VIPAddr != "" {
-					instances := dict.vipIndex[inst.VIPAddr]
-					if instances == nil {
-						instances = map[string]*Instance{}
-
-						dict.vipIndex[inst.VIPAddr] = instances
-						dict.vipIndex[inst.VIPAddr][id] = inst
-					}
-				}
-
-				if inst.SecVIPAddr != "" {
-					if dict.svipIndex[inst.SecVIPAddr] == nil {
-						dict.svipIndex[inst.SecVIPAddr] = map[string]*Instance{}
-						dict.svipIndex[inst.SecVIPAddr][id] = inst
-					}
-				}
-				if inst.Application != "" {
-					if dict.appNameIndex[app.Name] == nil {
-						dict.appNameIndex[app.Name] = map[string]*Instance{}
-						dict.appNameIndex[app.Name][id] = inst
-					}
-				}
+				inst.Region = cl.instanceRegion(inst)
+				dict.Add(inst, id, app)
 			}
-		}
+		})
+		return err
+	})
+	if err != nil {
+		log.Printf("Faild to update full registry. %s\n", err)
+		return &cl.dictionary, err
 	}
 
 	hashcode := calculateHashcode(dict.vipIndex)
@@ -206,7 +272,7 @@ func (cl *client) fetchAll() (*dictionary, error) {
 }
 
 func (cl *client) fetchDelta() (*dictionary, map[string]*Instance) {
-	apps, err := cl.fetchApps("apps/delta")
+	apps, err := cl.fetchApps("apps/delta" + cl.regionsQuery())
 	if err != nil {
 		log.Printf("Faild to update delta. %s\n", err)
 
@@ -237,6 +303,7 @@ func (cl *client) fetchDelta() (*dictionary, map[string]*Instance) {
 			}
 
 			inst.ID = id
+			inst.Region = cl.instanceRegion(inst)
 			switch inst.ActionType {
 			case actionDeleted:
 				dict.Delete(inst, id, app)
@@ -268,91 +335,151 @@ func (cl *client) fetchDelta() (*dictionary, map[string]*Instance) {
 	return dict, diff
 }
 
-// fetchApps function return all the applications from the server.
-func (cl *client) fetchApps(path string) (*Applications, error) {
-	var err error
+// regionsQuery returns the query string requesting the registries of the configured remote regions.
+func (cl *client) regionsQuery() string {
+	if len(cl.remoteRegions) == 0 {
+		return ""
+	}
+	return "?regions=" + url.QueryEscape(strings.Join(cl.remoteRegions, ","))
+}
 
-	for _, eurl := range cl.eurekaURLs {
-		req, _ := http.NewRequest("GET", fmt.Sprintf("%s/%s", eurl, path), nil)
-		cl.setJasonRequestHeader(req,"Accept")
-		resp, err2 := cl.httpClient.Do(req)
-		if err2 != nil {
-			err = err2
-			continue
-		}
-		defer resp.Body.Close()
-		body, err2 := ioutil.ReadAll(resp.Body)
-		if err2 != nil {
-			err = err2
-			continue
-		}
-		var appsList applicationsList
-		err2 = json.Unmarshal(body, &appsList)
-		if err2 != nil {
-			err = err2
-			continue
+// instanceRegion resolves the region an instance belongs to, according to the availability zone
+// reported in its datacenter metadata. Instances which can not be matched to one of the remote
+// regions are considered local.
+func (cl *client) instanceRegion(inst *Instance) string {
+	if len(cl.remoteRegions) == 0 {
+		return cl.region
+	}
+
+	zone := inst.AvailabilityZone()
+	if zone == "" {
+		return cl.region
+	}
+
+	// Availability zones are named after their region (e.g. us-east-1c belongs to us-east-1),
+	// so the longest region name prefixing the zone wins
+	var region string
+	for _, r := range append([]string{cl.region}, cl.remoteRegions...) {
+		if r != "" && strings.HasPrefix(zone, r) && len(r) > len(region) {
+			region = r
 		}
-		return appsList.Applications, nil
 	}
-	return nil, err
+	if region == "" {
+		return cl.region
+	}
+	return region
 }
 
-// fetchApp function fetches all applications with the name app_name, where path = "apps/app_name"
-func (cl *client) fetchApp(path string) (*Applications, error) {
-	var err error
-	for _, eurl := range cl.eurekaURLs {
-		req, _ := http.NewRequest("GET", fmt.Sprintf("%s/%s", eurl, path), nil)
-		cl.setJasonRequestHeader(req,"Accept")
-		resp, err2 := cl.httpClient.Do(req)
+// do sends a request to the eureka servers, starting with the last working one, until a server responds
+// without a server error. Servers which fail are skipped by the following requests during a cool-down.
+// accept is the header announcing the JSON body or response, Accept or Content-Type.
+func (cl *client) do(method, path string, body []byte, accept string) (*http.Response, error) {
+	settings := cl.currentSettings()
+	err := errors.New("no eureka server available")
+	for _, ep := range settings.endpoints.candidates() {
+		var reader io.Reader
+		if body != nil {
+			reader = bytes.NewReader(body)
+		}
+		req, err2 := http.NewRequest(method, fmt.Sprintf("%s/%s", ep.url, path), reader)
 		if err2 != nil {
-			err = err2
-			continue
+			return nil, err2
 		}
-		defer resp.Body.Close()
-		body, err2 := ioutil.ReadAll(resp.Body)
-		if err2 != nil {
-			err = err2
-			continue
+		settings.setJasonRequestHeader(req, accept)
+		resp, err2 := settings.httpClient.Do(req)
+		if err2 == nil && resp.StatusCode >= http.StatusInternalServerError {
+			resp.Body.Close()
+			err2 = fmt.Errorf("server error. response is %v", resp.Status)
 		}
-		var apps Applications
-		err2 = json.Unmarshal(body, &apps)
 		if err2 != nil {
 			err = err2
+			settings.endpoints.failed(ep, err2)
 			continue
 		}
-		return &apps, nil
+		settings.endpoints.succeeded(ep)
+		return resp, nil
 	}
-
 	return nil, err
 }
 
-func (cl *client) fetchInstance(appID, id string) (*Instance, error) {
-	var err error
-	path := "apps/" + appID + "/" + id
-	for _, eurl := range cl.eurekaURLs {
-		req, _ := http.NewRequest("GET", fmt.Sprintf("%s/%s", eurl, path), nil)
-		cl.setJasonRequestHeader(req,"Accept")
-		resp, err2 := cl.httpClient.Do(req)
-		if err2 != nil {
-			err = err2
-			continue
-		}
-		defer resp.Body.Close()
-		body, err2 := ioutil.ReadAll(resp.Body)
-		if err2 != nil {
-			err = err2
-			continue
-		}
-		var inst instanceWrapper
-		err2 = json.Unmarshal(body, &inst)
-		if err2 != nil {
-			err = err2
-			continue
+// statusError reports an unexpected response code of a fetch request.
+type statusError struct {
+	code   int
+	status string
+}
+
+func (e *statusError) Error() string {
+	return fmt.Sprintf("bad response for fetch request. response is %v", e.status)
+}
+
+// get fetches a path and decodes its JSON response into v.
+func (cl *client) get(path string, v interface{}) error {
+	return cl.getStream(path, func(body io.Reader) error {
+		return json.NewDecoder(body).Decode(v)
+	})
+}
+
+// getStream fetches a path and passes its response body to decode.
+func (cl *client) getStream(path string, decode func(body io.Reader) error) error {
+	resp, err := cl.do("GET", path, nil, "Accept")
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return &statusError{code: resp.StatusCode, status: resp.Status}
+	}
+	return decode(resp.Body)
+}
+
+// send sends a request without body, and verifies the response code.
+func (cl *client) send(method, path, request string) error {
+	resp, err := cl.do(method, path, nil, "Accept")
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("bad response for %s request. response is %v", request, resp.Status)
+	}
+	return nil
+}
+
+// fetchApps function return all the applications from the server.
+// The response is decoded as it is read, see decodeApplicationsList.
+func (cl *client) fetchApps(path string) (*Applications, error) {
+	var apps *Applications
+	err := cl.getStream(path, func(body io.Reader) error {
+		var list []*Application
+		version, err := decodeApplicationsList(body, func(app *Application) {
+			list = append(list, app)
+		})
+		if version != nil {
+			apps = &Applications{appVersion: *version, Application: list}
 		}
-		return inst.Inst, nil
+		return err
+	})
+	if err != nil {
+		return nil, err
 	}
+	return apps, nil
+}
 
-	return nil, err
+// fetchApp function fetches all applications with the name app_name, where path = "apps/app_name"
+func (cl *client) fetchApp(path string) (*Applications, error) {
+	var apps Applications
+	if err := cl.get(path, &apps); err != nil {
+		return nil, err
+	}
+	return &apps, nil
+}
+
+func (cl *client) fetchInstance(appID, id string) (*Instance, error) {
+	var inst instanceWrapper
+	if err := cl.get("apps/"+appID+"/"+id, &inst); err != nil {
+		return nil, err
+	}
+	return inst.Inst, nil
 }
 func (cl *client) getListOfInstsFromAppList(appList applicationsList) []*Instance {
 	var instsToReturn []*Instance
@@ -366,116 +493,45 @@ func (cl *client) getListOfInstsFromAppList(appList applicationsList) []*Instanc
 	return instsToReturn
 }
 func (cl *client) fetchInstancesByVip(vipAddress string) ([]*Instance, error) {
-	var err error
-	path := "vips/" + vipAddress
-	for _, eurl := range cl.eurekaURLs {
-		req, _ := http.NewRequest("GET", fmt.Sprintf("%s/%s", eurl, path), nil)
-		cl.setJasonRequestHeader(req,"Accept")
-		resp, err2 := cl.httpClient.Do(req)
-		if err2 != nil {
-			err = err2
-			continue
-		}
-		defer resp.Body.Close()
-		body, err2 := ioutil.ReadAll(resp.Body)
-		if err2 != nil {
-			err = err2
-			continue
-		}
-		var appsList applicationsList
-		err2 = json.Unmarshal(body, &appsList)
-		if err2 != nil {
-			err = err2
-			continue
-		}
-		insts := cl.getListOfInstsFromAppList(appsList)
-		return insts, nil
+	var appsList applicationsList
+	if err := cl.get("vips/"+vipAddress, &appsList); err != nil {
+		return nil, err
 	}
-
-	return nil, err
+	return cl.getListOfInstsFromAppList(appsList), nil
 }
 func (cl *client) fetchInstancesBySVip(vipAddress string) ([]*Instance, error) {
-	var err error
-	path := "svips/" + vipAddress
-	for _, eurl := range cl.eurekaURLs {
-		req, _ := http.NewRequest("GET", fmt.Sprintf("%s/%s", eurl, path), nil)
-		cl.setJasonRequestHeader(req,"Accept")
-		resp, err2 := cl.httpClient.Do(req)
-		if err2 != nil {
-			err = err2
-			continue
-		}
-		defer resp.Body.Close()
-		body, err2 := ioutil.ReadAll(resp.Body)
-		if err2 != nil {
-			err = err2
-			continue
-		}
-		var appsList applicationsList
-		err2 = json.Unmarshal(body, &appsList)
-		if err2 != nil {
-			err = err2
-			continue
-		}
-		insts := cl.getListOfInstsFromAppList(appsList)
-		return insts, nil
+	var appsList applicationsList
+	if err := cl.get("svips/"+vipAddress, &appsList); err != nil {
+		return nil, err
 	}
-
-	return nil, err
+	return cl.getListOfInstsFromAppList(appsList), nil
 }
 func (cl *client) register(instance *Instance) error {
-	var err error
 	instanceWrapper := instanceWrapper{Inst: instance}
 	body, err := json.Marshal(instanceWrapper)
-	r := bytes.NewReader(body)
-	appName := instance.Application
 	if err != nil {
 		return err
 	}
-	path := "apps/" + appName
-	for _, eurl := range cl.eurekaURLs {
-		req, _ := http.NewRequest("POST", fmt.Sprintf("%s/%s", eurl, path), r)
-		cl.setJasonRequestHeader(req,"Content-Type")
-		resp, err2 := cl.httpClient.Do(req)
-		if err2 != nil {
-			err = err2
-			continue
-		}
-		if resp.StatusCode != 204 {
-			err = fmt.Errorf("response code unexcpeted: %d", resp.StatusCode)
-			continue
-		}
-
+	resp, err := cl.do("POST", "apps/"+instance.Application, body, "Content-Type")
+	if err != nil {
+		return err
 	}
-	return err
+	resp.Body.Close()
+	if resp.StatusCode != 204 {
+		return fmt.Errorf("response code unexcpeted: %d", resp.StatusCode)
+	}
+	return nil
 }
 
 func (cl *client) deregister(instance *Instance) error {
-	var err error
 	appName := instance.Application
 	instID, err := resolveInstanceID(instance)
 	if err != nil {
 		return fmt.Errorf("Failed to resolve instance ID. error: %s\n", err)
 	}
-	path := "apps/" + appName + "/" + instID
-	for _, eurl := range cl.eurekaURLs {
-		req, _ := http.NewRequest("DELETE", fmt.Sprintf("%s/%s", eurl, path), nil)
-		cl.setJasonRequestHeader(req,"Accept")
-		resp, err2 := cl.httpClient.Do(req)
-		if err2 != nil {
-			err = err2
-			continue
-		}
-		if resp.StatusCode != http.StatusOK {
-			err = fmt.Errorf("bad response for deregister request. response is %v", resp.Status)
-		}
-
-	}
-
-	return err
+	return cl.send("DELETE", "apps/"+appName+"/"+instID, "deregister")
 }
 func (cl *client) heartbeat(instance *Instance) error {
-	var err error
 	appName := instance.Application
 
 	instID, err := resolveInstanceID(instance)
@@ -483,74 +539,64 @@ func (cl *client) heartbeat(instance *Instance) error {
 		return fmt.Errorf("Failed to resolve instance ID. error: %s\n", err)
 	}
 
-	path := "apps/" + appName + "/" + instID
-	for _, eurl := range cl.eurekaURLs {
-		req, _ := http.NewRequest("PUT", fmt.Sprintf("%s/%s", eurl, path), nil)
-		cl.setJasonRequestHeader(req, "Accept")
-		resp, err2 := cl.httpClient.Do(req)
-		if err2 != nil {
-			err = err2
-			continue
-		}
-		if resp.StatusCode != http.StatusOK {
-			err = fmt.Errorf("bad response for heartbeat request. response is %v", resp.Status)
-		}
+	return cl.send("PUT", "apps/"+appName+"/"+instID, "heartbeat")
+}
 
+func validateStatus(status StatusType) error {
+	if status != UP && status != DOWN && status != UNKNOWN && status != OUTOFSERVICE && status != STARTING {
+		return fmt.Errorf("requested status %v is not valid", status)
 	}
-	return err
+	return nil
 }
 
 func (cl *client) setStatusForInstance(instance *Instance, status StatusType) error {
-	if status != UP && status != DOWN && status != UNKNOWN && status != OUTOFSERVICE && status != STARTING {
-		return fmt.Errorf("requested status %v is not valid", status)
+	if err := validateStatus(status); err != nil {
+		return err
 	}
-	var err error
 	appName := instance.Application
 	instID, err := resolveInstanceID(instance)
 	if err != nil {
 		return fmt.Errorf("Failed to resolve instance ID. error: %s\n", err)
 	}
 	path := "apps/" + appName + "/" + instID + "/status?value=" + fmt.Sprintf("%v", status)
-	for _, eurl := range cl.eurekaURLs {
-		req, _ := http.NewRequest("PUT", fmt.Sprintf("%s/%s", eurl, path), nil)
-		cl.setJasonRequestHeader(req,"Accept")
-		resp, err2 := cl.httpClient.Do(req)
-		if err2 != nil {
-			err = err2
-			continue
-		}
-		if resp.StatusCode != http.StatusOK {
-			err = fmt.Errorf("bad response for changing status request. response is %v", resp.Status)
-		}
+	return cl.send("PUT", path, "changing status")
+}
 
+// removeStatusOverride deletes the overridden status of the instance. If status is not empty,
+// it is sent as the value the instance status falls back to.
+func (cl *client) removeStatusOverride(instance *Instance, status StatusType) error {
+	if status != "" {
+		if err := validateStatus(status); err != nil {
+			return err
+		}
+	}
+	appName := instance.Application
+	instID, err := resolveInstanceID(instance)
+	if err != nil {
+		return fmt.Errorf("Failed to resolve instance ID. error: %s\n", err)
+	}
+	path := "apps/" + appName + "/" + instID + "/status"
+	if status != "" {
+		path += "?value=" + string(status)
 	}
-	return err
+	return cl.send("DELETE", path, "removing status override")
 }
 
 func (cl *client) setMetadataKey(inst *Instance, key string, value string) error {
-	var err error
 	appName := inst.Application
 	instID, err := resolveInstanceID(inst)
 	if err != nil {
 		return fmt.Errorf("Failed to resolve instance ID. error: %s\n", err)
 	}
 	path := "apps/" + appName + "/" + instID + "/metadata?" + key + "=" + value
-	for _, eurl := range cl.eurekaURLs {
-		req, _ := http.NewRequest("PUT", fmt.Sprintf("%s/%s", eurl, path), nil)
-		cl.setJasonRequestHeader(req,"Accept")
-		resp, err2 := cl.httpClient.Do(req)
-		if err2 != nil {
-			err = err2
-			continue
-		}
-		if resp.StatusCode != http.StatusOK {
-			err = fmt.Errorf("bad response for changing metadata request. response is %v", resp.Status)
-		}
-
-	}
-	return err
+	return cl.send("PUT", path, "changing metadata")
+}
 
+// endpointStates returns the states of the eureka server endpoints.
+func (cl *client) endpointStates() []EndpointState {
+	return cl.currentSettings().endpoints.states()
 }
+
 func calculateHashcode(dict map[string]map[string]*Instance) string {
 	var hashcode string
 
@@ -598,14 +644,17 @@ func (cl *client) populateDiff(dict *dictionary) map[string]*Instance {
 			for id, newInst := range newInsts {
 				if srcInst, ok := srcInsts[id]; ok {
 					if newInst.Status != srcInst.Status {
+						newInst.ActionType = actionModified
 						diff[id] = newInst
 					}
 				} else {
+					newInst.ActionType = actionAdded
 					diff[id] = newInst
 				}
 			}
 		} else {
 			for id, newInst := range newInsts {
+				newInst.ActionType = actionAdded
 				diff[id] = newInst
 			}
 		}
@@ -616,12 +665,16 @@ func (cl *client) populateDiff(dict *dictionary) map[string]*Instance {
 		if _, ok := dict.vipIndex[vip]; !ok {
 
 			for id, delInsts := range cl.dictionary.vipIndex[vip] {
-				diff[id] = delInsts
+				deleted := delInsts.deepCopy()
+				deleted.ActionType = actionDeleted
+				diff[id] = &deleted
 			}
 		} else {
 			for id, inst := range cl.dictionary.vipIndex[vip] {
 				if _, ok := dict.vipIndex[vip][id]; !ok {
-					diff[id] = inst
+					deleted := inst.deepCopy()
+					deleted.ActionType = actionDeleted
+					diff[id] = &deleted
 				}
 			}
 		}
@@ -630,8 +683,8 @@ func (cl *client) populateDiff(dict *dictionary) map[string]*Instance {
 	return diff
 }
 
-func (cl *client) setJasonRequestHeader(req *http.Request, key string) {
-	if cl.UseJSON {
+func (s *clientSettings) setJasonRequestHeader(req *http.Request, key string) {
+	if s.useJSON {
 		req.Header.Set(key, "application/json")
 	}
 	// TODO: ADD xml support.
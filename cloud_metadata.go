@@ -0,0 +1,62 @@
+// Copyright 2016 IBM Corporation
+//
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+//
+//       http://www.apache.org/licenses/LICENSE-2.0
+//
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+
+//Package goEurekaClient Implements a go client that interacts with a eureka server
+package goEurekaClient
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+)
+
+// customDatacenterClass is the datacenter class of datacenters which are not known to the eureka server
+const customDatacenterClass = "com.netflix.appinfo.MyDataCenterInfo"
+
+// getMetadata reads a value from a cloud metadata service.
+// The returned bool is false if the value doesn't exist.
+func getMetadata(httpClient *http.Client, method, url string, headers map[string]string) ([]byte, bool, error) {
+	req, err := http.NewRequest(method, url, nil)
+	if err != nil {
+		return nil, false, err
+	}
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, false, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, false, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, false, fmt.Errorf("bad response for metadata request. response is %v", resp.Status)
+	}
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, false, err
+	}
+	return body, true, nil
+}
+
+// metadataString returns the value of a metadata key, if it is a string.
+func metadataString(dcinfo *DatacenterInfo, key string) string {
+	if dcinfo == nil || dcinfo.Metadata == nil {
+		return ""
+	}
+	value, _ := dcinfo.Metadata[key].(string)
+	return value
+}
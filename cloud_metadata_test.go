@@ -0,0 +1,117 @@
+// Copyright 2016 IBM Corporation
+//
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+//
+//       http://www.apache.org/licenses/LICENSE-2.0
+//
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+
+//Package goEurekaClient Implements a go client that interacts with a eureka server
+package goEurekaClient
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestGCPMetadataProvider(t *testing.T) {
+	metadata := map[string]string{
+		"instance/id":                      "1234567890",
+		"instance/name":                    "vm1",
+		"instance/zone":                    "projects/42/zones/us-central1-a",
+		"instance/machine-type":            "projects/42/machineTypes/n1-standard-1",
+		"instance/hostname":                "vm1.c.project.internal",
+		"instance/network-interfaces/0/ip": "10.128.0.2",
+		"project/project-id":               "project",
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		value, ok := metadata[strings.TrimPrefix(r.URL.Path, "/computeMetadata/v1/")]
+		if r.Header.Get("Metadata-Flavor") != "Google" {
+			w.WriteHeader(http.StatusForbidden)
+		} else if !ok {
+			w.WriteHeader(http.StatusNotFound)
+		} else {
+			w.Write([]byte(value))
+		}
+	}))
+	defer server.Close()
+
+	dcinfo, err := NewGCPMetadataProvider(server.URL, 5*time.Second).DatacenterInfo()
+	if err != nil {
+		t.Fatalf("error = %v", err)
+	}
+	if dcinfo.Metadata[availabilityZoneKey] != "us-central1-a" || dcinfo.Metadata["machine-type"] != "n1-standard-1" {
+		t.Errorf("unexpected metadata %v", dcinfo.Metadata)
+	}
+	if id, _ := resolveInstanceID(&Instance{HostName: "vm1", Datacenter: dcinfo}); id != "1234567890" {
+		t.Errorf("instance id should be resolved from the metadata, got %s", id)
+	}
+}
+
+func TestAzureMetadataProvider(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Metadata") != "true" || r.URL.Query().Get("api-version") == "" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		w.Write([]byte(`{"compute":{"vmId":"vm-uuid","name":"vm1","location":"westeurope","zone":"2","vmSize":"Standard_D2"},
+			"network":{"interface":[{"ipv4":{"ipAddress":[{"privateIpAddress":"10.1.0.4","publicIpAddress":""}]}}]}}`))
+	}))
+	defer server.Close()
+
+	dcinfo, err := NewAzureMetadataProvider(server.URL, 5*time.Second).DatacenterInfo()
+	if err != nil {
+		t.Fatalf("error = %v", err)
+	}
+	if dcinfo.Metadata[availabilityZoneKey] != "westeurope-2" || dcinfo.Metadata["local-ipv4"] != "10.1.0.4" {
+		t.Errorf("unexpected metadata %v", dcinfo.Metadata)
+	}
+	if _, ok := dcinfo.Metadata["public-ipv4"]; ok {
+		t.Errorf("empty public IP should be skipped, got %v", dcinfo.Metadata)
+	}
+	if id, _ := resolveInstanceID(&Instance{HostName: "vm1", Datacenter: dcinfo}); id != "vm-uuid" {
+		t.Errorf("instance id should be resolved from the metadata, got %s", id)
+	}
+}
+
+func TestKubernetesMetadataProvider(t *testing.T) {
+	dir, err := ioutil.TempDir("", "podinfo")
+	if err != nil {
+		t.Fatalf("error = %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	provider := NewKubernetesMetadataProvider(dir)
+	if _, err = provider.DatacenterInfo(); err == nil {
+		t.Error("missing pod uid should be reported")
+	}
+
+	ioutil.WriteFile(filepath.Join(dir, "uid"), []byte("uid-1\n"), 0644)
+	ioutil.WriteFile(filepath.Join(dir, "name"), []byte("pod1"), 0644)
+	ioutil.WriteFile(filepath.Join(dir, "namespace"), []byte("default"), 0644)
+	os.Setenv("POD_NAME", "pod2")
+	defer os.Unsetenv("POD_NAME")
+
+	dcinfo, err := provider.DatacenterInfo()
+	if err != nil {
+		t.Fatalf("error = %v", err)
+	}
+	if dcinfo.Metadata[kubernetesPodUID] != "uid-1" || dcinfo.Metadata["pod-name"] != "pod2" {
+		t.Errorf("unexpected metadata %v", dcinfo.Metadata)
+	}
+	if id, _ := resolveInstanceID(&Instance{HostName: "pod2", Datacenter: dcinfo}); id != "uid-1" {
+		t.Errorf("instance id should be the pod uid, got %s", id)
+	}
+}
@@ -0,0 +1,312 @@
+// Copyright 2016 IBM Corporation
+//
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+//
+//       http://www.apache.org/licenses/LICENSE-2.0
+//
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"strings"
+	"time"
+
+	eureka "github.com/amalgam8/go-eureka-client"
+)
+
+func (c *cli) apps(args []string) error {
+	if len(args) != 0 {
+		return errUsage
+	}
+	discovery, err := eureka.NewDiscovery(c.config, nil)
+	if err != nil {
+		return err
+	}
+	apps, err := discovery.GetApplications()
+	if err != nil {
+		return err
+	}
+	return c.printer.applications(apps)
+}
+
+func (c *cli) app(args []string) error {
+	if len(args) != 1 {
+		return errUsage
+	}
+	discovery, err := eureka.NewDiscovery(c.config, nil)
+	if err != nil {
+		return err
+	}
+	app, err := discovery.GetApplication(args[0])
+	if err != nil {
+		return err
+	}
+	return c.printer.instances(app.Instances)
+}
+
+func (c *cli) instance(args []string) error {
+	if len(args) != 2 {
+		return errUsage
+	}
+	inst, err := c.fetchInstance(args[0], args[1])
+	if err != nil {
+		return err
+	}
+	return c.printer.instances([]*eureka.Instance{inst})
+}
+
+func (c *cli) vip(args []string) error {
+	if len(args) != 1 {
+		return errUsage
+	}
+	discovery, err := eureka.NewDiscovery(c.config, nil)
+	if err != nil {
+		return err
+	}
+	insts, err := discovery.GetInstancesByVip(args[0])
+	if err != nil {
+		return err
+	}
+	return c.printer.instances(insts)
+}
+
+func (c *cli) svip(args []string) error {
+	if len(args) != 1 {
+		return errUsage
+	}
+	discovery, err := eureka.NewDiscovery(c.config, nil)
+	if err != nil {
+		return err
+	}
+	insts, err := discovery.GetInstancesBySecVip(args[0])
+	if err != nil {
+		return err
+	}
+	return c.printer.instances(insts)
+}
+
+func (c *cli) register(args []string) error {
+	flags := flag.NewFlagSet("register", flag.ContinueOnError)
+	flags.SetOutput(c.stderr)
+	file := flags.String("f", "", "JSON file describing the instance, either bare or wrapped in an \"instance\" object")
+	if err := flags.Parse(args); err != nil || *file == "" || flags.NArg() != 0 {
+		return errUsage
+	}
+
+	inst, err := readInstance(*file)
+	if err != nil {
+		return err
+	}
+	registrator, err := eureka.NewRegistrator(c.config, nil)
+	if err != nil {
+		return err
+	}
+	if err = registrator.Register(inst); err != nil {
+		return err
+	}
+	fmt.Fprintf(c.stdout, "registered instance %s of application %s\n", inst.HostName, inst.Application)
+	return nil
+}
+
+func (c *cli) deregister(args []string) error {
+	if len(args) != 2 {
+		return errUsage
+	}
+	return c.mutate(args[0], args[1], "deregistered", func(r eureka.Registrator, inst *eureka.Instance) error {
+		return r.Deregister(inst)
+	})
+}
+
+func (c *cli) heartbeat(args []string) error {
+	if len(args) != 2 {
+		return errUsage
+	}
+	return c.mutate(args[0], args[1], "renewed lease of", func(r eureka.Registrator, inst *eureka.Instance) error {
+		return r.Heartbeat(inst)
+	})
+}
+
+func (c *cli) status(args []string) error {
+	if len(args) == 0 {
+		return errUsage
+	}
+	switch args[0] {
+	case "set":
+		if len(args) != 4 {
+			return errUsage
+		}
+		status := eureka.StatusType(strings.ToUpper(args[3]))
+		return c.mutate(args[1], args[2], "set status "+string(status)+" of", func(r eureka.Registrator, inst *eureka.Instance) error {
+			return r.SetStatus(inst, status)
+		})
+	case "clear":
+		flags := flag.NewFlagSet("status clear", flag.ContinueOnError)
+		flags.SetOutput(c.stderr)
+		value := flags.String("value", "", "status the instance falls back to")
+		if err := flags.Parse(args[1:]); err != nil || flags.NArg() != 2 {
+			return errUsage
+		}
+		status := eureka.StatusType(strings.ToUpper(*value))
+		return c.mutate(flags.Arg(0), flags.Arg(1), "cleared status override of", func(r eureka.Registrator, inst *eureka.Instance) error {
+			if status == "" {
+				return r.RemoveStatusOverride(inst)
+			}
+			return r.RemoveStatusOverrideWithValue(inst, status)
+		})
+	default:
+		return errUsage
+	}
+}
+
+func (c *cli) metadata(args []string) error {
+	if len(args) < 4 || args[0] != "set" {
+		return errUsage
+	}
+	pairs := make([][2]string, 0, len(args)-3)
+	for _, arg := range args[3:] {
+		kv := strings.SplitN(arg, "=", 2)
+		if len(kv) != 2 || kv[0] == "" {
+			return errUsage
+		}
+		pairs = append(pairs, [2]string{kv[0], kv[1]})
+	}
+	return c.mutate(args[1], args[2], "set metadata of", func(r eureka.Registrator, inst *eureka.Instance) error {
+		for _, kv := range pairs {
+			if err := r.SetMetadataKey(inst, kv[0], kv[1]); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (c *cli) watch(args []string) error {
+	flags := flag.NewFlagSet("watch", flag.ContinueOnError)
+	flags.SetOutput(c.stderr)
+	interval := flags.Duration("interval", 30*time.Second, "registry poll interval")
+	app := flags.String("app", "", "only report events of this application")
+	if err := flags.Parse(args); err != nil || flags.NArg() != 0 {
+		return errUsage
+	}
+
+	handler := &eventPrinter{printer: c.printer, app: strings.ToUpper(*app), stderr: c.stderr}
+	cache, err := eureka.NewDiscoveryCache(c.config, *interval, handler)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := eureka.ContextWithSignals(context.Background())
+	defer cancel()
+	cache.Run(ctx)
+	<-ctx.Done()
+	return nil
+}
+
+func (c *cli) diff(args []string) error {
+	flags := flag.NewFlagSet("diff", flag.ContinueOnError)
+	flags.SetOutput(c.stderr)
+	interval := flags.Duration("interval", 0, "compare the registries continuously at this interval")
+	if err := flags.Parse(args); err != nil || flags.NArg() != 2 {
+		return errUsage
+	}
+
+	left := *c.config
+	left.ServiceUrls = map[string][]string{"left": strings.Split(flags.Arg(0), ",")}
+	right := *c.config
+	right.ServiceUrls = map[string][]string{"right": strings.Split(flags.Arg(1), ",")}
+
+	if *interval <= 0 {
+		diff, err := eureka.DiffRegistries(&left, &right)
+		if err != nil {
+			return err
+		}
+		if err = c.printer.diff(diff); err != nil {
+			return err
+		}
+		if !diff.Empty() {
+			return errDifferences
+		}
+		return nil
+	}
+
+	ctx, cancel := eureka.ContextWithSignals(context.Background())
+	defer cancel()
+	eureka.RunRegistryDiff(ctx, &left, &right, *interval, func(diff *eureka.RegistryDiff, err error) {
+		if err == nil {
+			err = c.printer.diff(diff)
+		}
+		if err != nil {
+			fmt.Fprintf(c.stderr, "error: %v\n", err)
+		}
+	})
+	return nil
+}
+
+// mutate fetches an instance from the registry, so that its datacenter information is available
+// to resolve its ID, and applies a registrator operation to it.
+func (c *cli) mutate(appName, id, done string, op func(eureka.Registrator, *eureka.Instance) error) error {
+	inst, err := c.fetchInstance(appName, id)
+	if err != nil {
+		return err
+	}
+	registrator, err := eureka.NewRegistrator(c.config, nil)
+	if err != nil {
+		return err
+	}
+	if err = op(registrator, inst); err != nil {
+		return err
+	}
+	fmt.Fprintf(c.stdout, "%s instance %s of application %s\n", done, id, inst.Application)
+	return nil
+}
+
+func (c *cli) fetchInstance(appName, id string) (*eureka.Instance, error) {
+	discovery, err := eureka.NewDiscovery(c.config, nil)
+	if err != nil {
+		return nil, err
+	}
+	inst, err := discovery.GetInstance(appName, id)
+	if err != nil {
+		return nil, err
+	}
+	if inst == nil {
+		return nil, fmt.Errorf("instance %s of application %s not found", id, appName)
+	}
+	return inst, nil
+}
+
+// readInstance reads an instance from a JSON file, either bare or wrapped in an "instance" object.
+func readInstance(fileName string) (*eureka.Instance, error) {
+	data, err := ioutil.ReadFile(fileName)
+	if err != nil {
+		return nil, err
+	}
+
+	var wrapper struct {
+		Instance *eureka.Instance `json:"instance"`
+	}
+	if err = json.Unmarshal(data, &wrapper); err != nil {
+		return nil, fmt.Errorf("parsing instance file %v", err)
+	}
+	if wrapper.Instance != nil {
+		return wrapper.Instance, nil
+	}
+
+	var inst eureka.Instance
+	if err = json.Unmarshal(data, &inst); err != nil {
+		return nil, fmt.Errorf("parsing instance file %v", err)
+	}
+	return &inst, nil
+}
@@ -0,0 +1,149 @@
+// Copyright 2016 IBM Corporation
+//
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+//
+//       http://www.apache.org/licenses/LICENSE-2.0
+//
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+
+// Command eurekactl queries and manages a eureka registry.
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	eureka "github.com/amalgam8/go-eureka-client"
+)
+
+const (
+	defaultServerURL = "http://localhost:8080/eureka/v2/"
+	serverURLEnv     = "EUREKA_SERVER_URL"
+)
+
+var (
+	// errUsage is returned by commands invoked with invalid arguments.
+	errUsage = errors.New("invalid usage")
+	// errDifferences is returned by the diff command when the registries differ.
+	errDifferences = errors.New("registries differ")
+)
+
+type command struct {
+	usage       string
+	description string
+	run         func(c *cli, args []string) error
+}
+
+var commands = map[string]command{
+	"apps":       {"apps", "list the registered applications", (*cli).apps},
+	"app":        {"app <name>", "list the instances of an application", (*cli).app},
+	"instance":   {"instance <app> <id>", "show an instance", (*cli).instance},
+	"vip":        {"vip <address>", "list the instances of a vip address", (*cli).vip},
+	"svip":       {"svip <address>", "list the instances of a secure vip address", (*cli).svip},
+	"register":   {"register -f <instance.json>", "register an instance", (*cli).register},
+	"diff":       {"diff [-interval <duration>] <left-urls> <right-urls>", "compare two registries, continuously if an interval is given", (*cli).diff},
+	"deregister": {"deregister <app> <id>", "deregister an instance", (*cli).deregister},
+	"heartbeat":  {"heartbeat <app> <id>", "renew the lease of an instance", (*cli).heartbeat},
+	"status":     {"status set <app> <id> <status> | status clear [-value <status>] <app> <id>", "set or clear the overridden status of an instance", (*cli).status},
+	"metadata":   {"metadata set <app> <id> <key>=<value>...", "set metadata keys of an instance", (*cli).metadata},
+	"watch":      {"watch [-interval <duration>] [-app <name>]", "stream instance events until interrupted", (*cli).watch},
+}
+
+// cli holds the global options shared by all commands.
+type cli struct {
+	config  *eureka.Config
+	printer *printer
+	stdout  io.Writer
+	stderr  io.Writer
+}
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+// run executes the command line and returns the process exit code.
+func run(args []string, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("eurekactl", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	servers := flags.String("server", envOrDefault(serverURLEnv, defaultServerURL), "comma separated eureka server URLs")
+	output := flags.String("o", "table", "output format: table, json or yaml")
+	timeout := flags.Duration("timeout", 10*time.Second, "timeout of requests to the eureka server")
+	flags.Usage = func() { usage(flags, stderr) }
+
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if flags.NArg() == 0 {
+		flags.Usage()
+		return 2
+	}
+
+	cmd, ok := commands[flags.Arg(0)]
+	if !ok {
+		fmt.Fprintf(stderr, "unknown command %q\n", flags.Arg(0))
+		flags.Usage()
+		return 2
+	}
+
+	p, err := newPrinter(*output, stdout)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 2
+	}
+
+	config := eureka.DefaultConfig()
+	config.ConnectTimeoutSeconds = *timeout
+	config.ServiceUrls = map[string][]string{"default": strings.Split(*servers, ",")}
+	c := &cli{
+		config:  config,
+		printer: p,
+		stdout:  stdout,
+		stderr:  stderr,
+	}
+
+	if err = cmd.run(c, flags.Args()[1:]); err != nil {
+		if err == errUsage {
+			fmt.Fprintf(stderr, "usage: eurekactl %s\n", cmd.usage)
+			return 2
+		}
+		if err == errDifferences {
+			return 1
+		}
+		fmt.Fprintf(stderr, "error: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+func usage(flags *flag.FlagSet, w io.Writer) {
+	fmt.Fprintf(w, "usage: eurekactl [options] <command> [arguments]\n\ncommands:\n")
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(w, "  %-12s %s\n", name, commands[name].description)
+	}
+	fmt.Fprintf(w, "\noptions:\n")
+	flags.PrintDefaults()
+}
+
+func envOrDefault(key, value string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return value
+}
@@ -0,0 +1,147 @@
+// Copyright 2016 IBM Corporation
+//
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+//
+//       http://www.apache.org/licenses/LICENSE-2.0
+//
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+const testInstanceJSON = `{"instanceId":"inst1","hostName":"inst1","app":"APP1","ipAddr":"10.0.0.1",` +
+	`"vipAddress":"vip1","status":"UP","port":{"@enabled":"true","$":8080},` +
+	`"dataCenterInfo":{"@class":"com.netflix.appinfo.InstanceInfo$DefaultDataCenterInfo","name":"MyOwn"}}`
+
+// newTestServer serves APP1 with a single instance, and records the mutating requests it receives.
+func newTestServer(requests *[]string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == "GET" && strings.TrimSuffix(r.URL.Path, "/") == "/apps":
+			fmt.Fprintf(w, `{"applications":{"application":{"name":"APP1","instance":%s}}}`, testInstanceJSON)
+		case r.Method == "GET" && r.URL.Path == "/apps/APP1/inst1":
+			fmt.Fprintf(w, `{"instance":%s}`, testInstanceJSON)
+		case r.Method == "GET":
+			w.WriteHeader(http.StatusNotFound)
+		default:
+			*requests = append(*requests, r.Method+" "+r.URL.RequestURI())
+		}
+	}))
+}
+
+func TestAppsTable(t *testing.T) {
+	server := newTestServer(&[]string{})
+	defer server.Close()
+
+	var stdout, stderr bytes.Buffer
+	if code := run([]string{"-server", server.URL, "apps"}, &stdout, &stderr); code != 0 {
+		t.Fatalf("exit code = %d, stderr = %s", code, stderr.String())
+	}
+	lines := strings.Split(strings.TrimSpace(stdout.String()), "\n")
+	if len(lines) != 2 || !strings.HasPrefix(lines[1], "APP1") || strings.Fields(lines[1])[2] != "1" {
+		t.Errorf("unexpected output:\n%s", stdout.String())
+	}
+}
+
+func TestInstanceOutputFormats(t *testing.T) {
+	server := newTestServer(&[]string{})
+	defer server.Close()
+
+	var stdout, stderr bytes.Buffer
+	if code := run([]string{"-server", server.URL, "-o", "json", "instance", "APP1", "inst1"}, &stdout, &stderr); code != 0 {
+		t.Fatalf("exit code = %d, stderr = %s", code, stderr.String())
+	}
+	var insts []map[string]interface{}
+	if err := json.Unmarshal(stdout.Bytes(), &insts); err != nil || len(insts) != 1 || insts[0]["hostName"] != "inst1" {
+		t.Errorf("unexpected JSON output (%v):\n%s", err, stdout.String())
+	}
+
+	stdout.Reset()
+	if code := run([]string{"-server", server.URL, "-o", "yaml", "instance", "APP1", "inst1"}, &stdout, &stderr); code != 0 {
+		t.Fatalf("exit code = %d, stderr = %s", code, stderr.String())
+	}
+	if !strings.Contains(stdout.String(), "hostName: inst1") {
+		t.Errorf("unexpected YAML output:\n%s", stdout.String())
+	}
+}
+
+func TestMutatingCommands(t *testing.T) {
+	var requests []string
+	server := newTestServer(&requests)
+	defer server.Close()
+
+	for _, args := range [][]string{
+		{"status", "set", "APP1", "inst1", "out_of_service"},
+		{"status", "clear", "-value", "UP", "APP1", "inst1"},
+		{"metadata", "set", "APP1", "inst1", "zone=a"},
+		{"deregister", "APP1", "inst1"},
+	} {
+		var stdout, stderr bytes.Buffer
+		run(append([]string{"-server", server.URL}, args...), &stdout, &stderr)
+	}
+
+	expected := []string{
+		"PUT /apps/APP1/inst1/status?value=OUT_OF_SERVICE",
+		"DELETE /apps/APP1/inst1/status?value=UP",
+		"PUT /apps/APP1/inst1/metadata?zone=a",
+		"DELETE /apps/APP1/inst1",
+	}
+	if strings.Join(requests, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("requests = %v, expected %v", requests, expected)
+	}
+}
+
+func TestUsageErrors(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	if code := run([]string{"unknown"}, &stdout, &stderr); code != 2 {
+		t.Errorf("unknown command exit code = %d", code)
+	}
+	if code := run([]string{"app"}, &stdout, &stderr); code != 2 {
+		t.Errorf("missing argument exit code = %d", code)
+	}
+	if code := run([]string{"-o", "xml", "apps"}, &stdout, &stderr); code != 2 {
+		t.Errorf("unknown format exit code = %d", code)
+	}
+}
+
+func TestDiff(t *testing.T) {
+	left := newTestServer(&[]string{})
+	defer left.Close()
+	right := newTestServer(&[]string{})
+	defer right.Close()
+	empty := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"applications":{}}`)
+	}))
+	defer empty.Close()
+
+	var stdout, stderr bytes.Buffer
+	if code := run([]string{"diff", left.URL, right.URL}, &stdout, &stderr); code != 0 {
+		t.Errorf("identical registries exit code = %d, stderr = %s", code, stderr.String())
+	}
+
+	stdout.Reset()
+	if code := run([]string{"-o", "json", "diff", left.URL, empty.URL}, &stdout, &stderr); code != 1 {
+		t.Errorf("different registries exit code = %d, stderr = %s", code, stderr.String())
+	}
+	var report struct {
+		ApplicationsOnlyInLeft []string `json:"applicationsOnlyInLeft"`
+	}
+	if err := json.Unmarshal(stdout.Bytes(), &report); err != nil || len(report.ApplicationsOnlyInLeft) != 1 {
+		t.Errorf("unexpected report (%v):\n%s", err, stdout.String())
+	}
+}
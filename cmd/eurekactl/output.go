@@ -0,0 +1,204 @@
+// Copyright 2016 IBM Corporation
+//
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+//
+//       http://www.apache.org/licenses/LICENSE-2.0
+//
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	eureka "github.com/amalgam8/go-eureka-client"
+	yaml "gopkg.in/yaml.v2"
+)
+
+const (
+	formatTable = "table"
+	formatJSON  = "json"
+	formatYAML  = "yaml"
+)
+
+// printer writes registry objects in the selected output format.
+type printer struct {
+	sync.Mutex
+	format string
+	out    io.Writer
+}
+
+func newPrinter(format string, out io.Writer) (*printer, error) {
+	switch format {
+	case formatTable, formatJSON, formatYAML:
+		return &printer{format: format, out: out}, nil
+	default:
+		return nil, fmt.Errorf("unknown output format %q", format)
+	}
+}
+
+func (p *printer) applications(apps []*eureka.Application) error {
+	if p.format != formatTable {
+		return p.encode(apps)
+	}
+
+	w := tabwriter.NewWriter(p.out, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "APPLICATION\tINSTANCES\tUP")
+	for _, app := range apps {
+		up := 0
+		for _, inst := range app.Instances {
+			if inst.EffectiveStatus() == eureka.UP {
+				up++
+			}
+		}
+		fmt.Fprintf(w, "%s\t%d\t%d\n", app.Name, len(app.Instances), up)
+	}
+	return w.Flush()
+}
+
+func (p *printer) instances(insts []*eureka.Instance) error {
+	if p.format != formatTable {
+		return p.encode(insts)
+	}
+
+	w := tabwriter.NewWriter(p.out, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "APPLICATION\tID\tHOST\tIP\tPORT\tVIP\tSTATUS")
+	for _, inst := range insts {
+		fmt.Fprintln(w, instanceRow(inst))
+	}
+	return w.Flush()
+}
+
+// diff writes a registry comparison. JSON and YAML reports are written as a whole,
+// while the table lists one difference per line.
+func (p *printer) diff(diff *eureka.RegistryDiff) error {
+	p.Lock()
+	defer p.Unlock()
+
+	if p.format != formatTable {
+		if p.format == formatYAML {
+			fmt.Fprintln(p.out, "---")
+		}
+		return p.encode(diff)
+	}
+
+	w := tabwriter.NewWriter(p.out, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(w, "# %s left=%s right=%s\n", diff.Time.Format(time.RFC3339), diff.Left, diff.Right)
+	fmt.Fprintln(w, "DIFFERENCE\tAPPLICATION\tID\tFIELD\tLEFT\tRIGHT")
+	for _, app := range diff.ApplicationsOnlyInLeft {
+		fmt.Fprintf(w, "only-in-left\t%s\t\t\t\t\n", app)
+	}
+	for _, app := range diff.ApplicationsOnlyInRight {
+		fmt.Fprintf(w, "only-in-right\t%s\t\t\t\t\n", app)
+	}
+	for _, inst := range diff.InstancesOnlyInLeft {
+		fmt.Fprintf(w, "only-in-left\t%s\t%s\t\t\t\n", inst.Application, inst.ID)
+	}
+	for _, inst := range diff.InstancesOnlyInRight {
+		fmt.Fprintf(w, "only-in-right\t%s\t%s\t\t\t\n", inst.Application, inst.ID)
+	}
+	for _, m := range diff.Mismatches {
+		fmt.Fprintf(w, "mismatch\t%s\t%s\t%s\t%s\t%s\n", m.Application, m.ID, m.Field, m.Left, m.Right)
+	}
+	return w.Flush()
+}
+
+// event writes an instance event. Table events are single lines, JSON events are JSON lines,
+// and YAML events are separate documents.
+func (p *printer) event(action string, inst *eureka.Instance) error {
+	p.Lock()
+	defer p.Unlock()
+
+	timestamp := time.Now().Format(time.RFC3339)
+	switch p.format {
+	case formatTable:
+		_, err := fmt.Fprintf(p.out, "%s\t%s\t%s\n", timestamp, action, instanceRow(inst))
+		return err
+	case formatYAML:
+		fmt.Fprintln(p.out, "---")
+	}
+	return p.encode(map[string]interface{}{"time": timestamp, "action": action, "instance": inst})
+}
+
+// encode writes v as JSON or YAML. YAML output is converted from the JSON encoding,
+// so that both formats use the field names of the eureka REST API.
+func (p *printer) encode(v interface{}) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	if p.format == formatJSON {
+		_, err = fmt.Fprintln(p.out, string(data))
+		return err
+	}
+
+	var generic interface{}
+	if err = yaml.Unmarshal(data, &generic); err != nil {
+		return err
+	}
+	data, err = yaml.Marshal(generic)
+	if err != nil {
+		return err
+	}
+	_, err = p.out.Write(data)
+	return err
+}
+
+func instanceRow(inst *eureka.Instance) string {
+	id := inst.ID
+	if id == "" {
+		id = inst.HostName
+	}
+	port := "-"
+	if inst.Port != nil && inst.Port.Enabled == "true" {
+		port = fmt.Sprintf("%v", inst.Port.Value)
+	}
+	if inst.SecPort != nil && inst.SecPort.Enabled == "true" {
+		if port == "-" {
+			port = fmt.Sprintf("%v(s)", inst.SecPort.Value)
+		} else {
+			port = fmt.Sprintf("%s,%v(s)", port, inst.SecPort.Value)
+		}
+	}
+	return fmt.Sprintf("%s\t%s\t%s\t%s\t%s\t%s\t%s", inst.Application, id, inst.HostName, inst.IPAddr, port,
+		inst.VIPAddr, inst.EffectiveStatus())
+}
+
+// eventPrinter is an instance event handler printing the events it receives.
+type eventPrinter struct {
+	printer *printer
+	app     string
+	stderr  io.Writer
+}
+
+func (e *eventPrinter) OnAdd(inst *eureka.Instance) {
+	e.print("ADDED", inst)
+}
+
+func (e *eventPrinter) OnUpdate(oldInst, newInst *eureka.Instance) {
+	e.print("MODIFIED", newInst)
+}
+
+func (e *eventPrinter) OnDelete(inst *eureka.Instance) {
+	e.print("DELETED", inst)
+}
+
+func (e *eventPrinter) print(action string, inst *eureka.Instance) {
+	if e.app != "" && inst.Application != e.app {
+		return
+	}
+	if err := e.printer.event(action, inst); err != nil {
+		fmt.Fprintf(e.stderr, "error: %v\n", err)
+	}
+}
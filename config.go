@@ -20,81 +20,270 @@ import (
 	"errors"
 	"fmt"
 	"math/rand"
-	"os"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
 	"time"
 )
 
 // Config struct defines configurations of the eureka client in order to interact with the server.
 type Config struct {
 	ConnectTimeoutSeconds time.Duration       `json:"connection_timeout_seconds"` // default 10s
-	UseDNSForServiceUrls  bool                `json:"use_dns_for_service_urls"`     // default false
+	UseDNSForServiceUrls  bool                `json:"use_dns_for_service_urls"`   // not supported, must be false
 	DNSDiscoveryZone      string              `json:"dns_discovery_zone"`
 	ServerDNSName         string              `json:"server_dns_name"`
-	ServiceUrls           map[string][]string `json:"service_urls"`    // map from Zone to array of server Urls
-	ServerPort            int                 `json:"server_port"`     // default 8080
+	ServiceUrls           map[string][]string `json:"service_urls"`     // map from Zone to array of server Urls
+	ServerPort            int                 `json:"server_port"`      // default 8080
 	PreferSameZone        bool                `json:"prefer_same_zone"` // default false
-	RetriesCount          int                 `json:"retries_count"`   // default 3
-	UseJSON               bool                `json:"use_json"`        // default True
+	RetriesCount          int                 `json:"retries_count"`    // default 3
+	UseJSON               bool                `json:"use_json"`         // default True
+	Region                string              `json:"region"`
+	FetchRemoteRegions    []string            `json:"fetch_remote_regions"`  // remote regions whose registry is also fetched
+	Zone                  string              `json:"zone"`                  // zone of the client, see LocalZone
+	AvailabilityZones     map[string][]string `json:"availability_zones"`    // map from region to its zones, in failover order
+	PollInterval          time.Duration       `json:"poll_interval_seconds"` // overrides the discovery cache poll interval when set
+	// EndpointCoolDown is the time a server is skipped after a failed request, default 30s
+	EndpointCoolDown time.Duration `json:"endpoint_cool_down_seconds"`
+	// EndpointReshuffleInterval is the interval of the server order randomization, default 20m
+	EndpointReshuffleInterval time.Duration `json:"endpoint_reshuffle_interval_seconds"`
+	// WatchInterval, when set, makes the cache watches fetch their VIP from the servers at this interval,
+	// instead of following the cache refresh
+	WatchInterval time.Duration `json:"watch_interval_seconds"`
 }
 
-// NewConfigFromFile reads JSON data from file and creates from it a config object.
+const (
+	defaultConnectTimeout = 10 * time.Second
+	defaultServerPort     = 8080
+	defaultRetriesCount   = 3
+)
+
+// DefaultConfig returns a config with the documented defaults and no service URLs.
+func DefaultConfig() *Config {
+	return &Config{
+		ConnectTimeoutSeconds: defaultConnectTimeout,
+		ServerPort:            defaultServerPort,
+		RetriesCount:          defaultRetriesCount,
+		UseJSON:               true,
+	}
+}
+
+// NewConfigFromFile reads a JSON or YAML config file on top of the defaults, and validates the resulting config.
 func NewConfigFromFile(fileName string) (*Config, error) {
-	configFile, err := os.Open(fileName)
-	if err != nil {
-		return nil, fmt.Errorf("opening config file %v", err.Error())
+	return ConfigLoader{File: fileName}.Load()
+}
+
+// UnmarshalJSON decodes a config, reading the *_seconds durations as numbers of seconds or as duration strings
+// such as "10s". Fields missing from the data keep their current value.
+func (c *Config) UnmarshalJSON(data []byte) error {
+	type plainConfig Config
+	aux := struct {
+		*plainConfig
+		ConnectTimeout json.RawMessage `json:"connection_timeout_seconds"`
+		PollInterval   json.RawMessage `json:"poll_interval_seconds"`
+		CoolDown       json.RawMessage `json:"endpoint_cool_down_seconds"`
+		Reshuffle      json.RawMessage `json:"endpoint_reshuffle_interval_seconds"`
+		WatchInterval  json.RawMessage `json:"watch_interval_seconds"`
+	}{plainConfig: (*plainConfig)(c)}
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
 	}
-	var conf *Config
-	jsonParser := json.NewDecoder(configFile)
-	if err = jsonParser.Decode(conf); err != nil {
-		return nil, fmt.Errorf("parsing config file %v", err.Error())
+
+	for _, field := range []struct {
+		name  string
+		raw   json.RawMessage
+		value *time.Duration
+	}{
+		{"connection_timeout_seconds", aux.ConnectTimeout, &c.ConnectTimeoutSeconds},
+		{"poll_interval_seconds", aux.PollInterval, &c.PollInterval},
+		{"endpoint_cool_down_seconds", aux.CoolDown, &c.EndpointCoolDown},
+		{"endpoint_reshuffle_interval_seconds", aux.Reshuffle, &c.EndpointReshuffleInterval},
+		{"watch_interval_seconds", aux.WatchInterval, &c.WatchInterval},
+	} {
+		if len(field.raw) == 0 || string(field.raw) == "null" {
+			continue
+		}
+		var value interface{}
+		if err := json.Unmarshal(field.raw, &value); err != nil {
+			return err
+		}
+		duration, err := parseSeconds(value)
+		if err != nil {
+			return fmt.Errorf("%s %v", field.name, err)
+		}
+		*field.value = duration
 	}
+	return nil
+}
 
-	return conf, nil
+// MarshalJSON encodes a config, writing the *_seconds durations as numbers of seconds.
+func (c Config) MarshalJSON() ([]byte, error) {
+	type plainConfig Config
+	return json.Marshal(struct {
+		plainConfig
+		ConnectTimeout float64 `json:"connection_timeout_seconds"`
+		PollInterval   float64 `json:"poll_interval_seconds"`
+		CoolDown       float64 `json:"endpoint_cool_down_seconds"`
+		Reshuffle      float64 `json:"endpoint_reshuffle_interval_seconds"`
+		WatchInterval  float64 `json:"watch_interval_seconds"`
+	}{
+		plainConfig:    plainConfig(c),
+		ConnectTimeout: c.ConnectTimeoutSeconds.Seconds(),
+		PollInterval:   c.PollInterval.Seconds(),
+		CoolDown:       c.EndpointCoolDown.Seconds(),
+		Reshuffle:      c.EndpointReshuffleInterval.Seconds(),
+		WatchInterval:  c.WatchInterval.Seconds(),
+	})
 }
 
-//createUrlsList creates an array of urls from the ServiceUrls map according to the following settings:
-func (c *Config) createUrlsList() ([]string, error) {
-	if c.ServiceUrls == nil {
-		return nil, errors.New("Service URLs must be defined")
-	}
-	urls := []string{}
-	indMap := map[int]string{}
-	i := 0
-	for k := range c.ServiceUrls {
-		indMap[i] = k
+// parseSeconds converts a number of seconds, a numeric string or a duration string into a duration.
+func parseSeconds(value interface{}) (time.Duration, error) {
+	switch v := value.(type) {
+	case float64:
+		return time.Duration(v * float64(time.Second)), nil
+	case int:
+		return time.Duration(v) * time.Second, nil
+	case string:
+		if seconds, err := strconv.ParseFloat(v, 64); err == nil {
+			return time.Duration(seconds * float64(time.Second)), nil
+		}
+		timeout, err := time.ParseDuration(v)
+		if err != nil {
+			return 0, fmt.Errorf("%q is neither a number of seconds nor a duration", v)
+		}
+		return timeout, nil
 	}
+	return 0, fmt.Errorf("%v is neither a number of seconds nor a duration", value)
+}
 
-	if c.PreferSameZone == false && c.UseDNSForServiceUrls == false {
-		zonesPerm := rand.Perm(len(c.ServiceUrls))
-		for _, v := range zonesPerm {
-			urlsOfZone := c.ServiceUrls[indMap[v]]
-			urlsPerm := rand.Perm(len(urlsOfZone))
+// Validate verifies that the config defines reachable servers and sane settings.
+func (c *Config) Validate() error {
+	var problems []string
+	if c.UseDNSForServiceUrls {
+		problems = append(problems, "DNS discovery of the service URLs is not supported")
+	}
+	if len(c.ServiceUrls) == 0 {
+		problems = append(problems, "service URLs must be defined")
+	}
 
-			for _, p := range urlsPerm {
-				urls = append(urls, urlsOfZone[p])
+	for _, zone := range sortedZones(c.ServiceUrls) {
+		if len(c.ServiceUrls[zone]) == 0 {
+			problems = append(problems, fmt.Sprintf("zone %q has no service URLs", zone))
+		}
+		for _, serviceURL := range c.ServiceUrls[zone] {
+			u, err := url.Parse(serviceURL)
+			if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+				problems = append(problems, fmt.Sprintf("service URL %q of zone %q is not valid", serviceURL, zone))
 			}
 		}
-		return urls, nil
-	} else if c.PreferSameZone == true && c.UseDNSForServiceUrls == false {
-		urlsOfPreferredZone := c.ServiceUrls[c.DNSDiscoveryZone]
-		urlsPerm := rand.Perm(len(urlsOfPreferredZone))
+	}
+	if c.PreferSameZone {
+		if _, ok := c.ServiceUrls[c.LocalZone()]; !ok {
+			problems = append(problems, fmt.Sprintf("preferred zone %q has no service URLs", c.LocalZone()))
+		}
+	}
+
+	if c.ConnectTimeoutSeconds <= 0 {
+		problems = append(problems, fmt.Sprintf("connection timeout %v must be positive", c.ConnectTimeoutSeconds))
+	}
+	if c.ServerPort < 1 || c.ServerPort > 65535 {
+		problems = append(problems, fmt.Sprintf("server port %d is not valid", c.ServerPort))
+	}
+	if c.PollInterval < 0 {
+		problems = append(problems, fmt.Sprintf("poll interval %v must not be negative", c.PollInterval))
+	}
+	if c.WatchInterval < 0 {
+		problems = append(problems, fmt.Sprintf("watch interval %v must not be negative", c.WatchInterval))
+	}
+	if c.EndpointCoolDown < 0 || c.EndpointReshuffleInterval < 0 {
+		problems = append(problems, "endpoint cool-down and reshuffle interval must not be negative")
+	}
+	if c.RetriesCount < 0 {
+		problems = append(problems, fmt.Sprintf("retries count %d must not be negative", c.RetriesCount))
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("invalid config: %s", strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+// LocalZone returns the zone of the client: Zone when set, else the first availability zone of the region,
+// else defaultZone.
+func (c *Config) LocalZone() string {
+	if c.Zone != "" {
+		return c.Zone
+	}
+	if zones := c.AvailabilityZones[c.Region]; len(zones) > 0 {
+		return zones[0]
+	}
+	return springDefaultZone
+}
 
-		for _, p := range urlsPerm {
-			urls = append(urls, urlsOfPreferredZone[p])
+// failoverZones returns the zones of the service URLs in failover order: the local zone, then the availability
+// zones of the region following it, wrapping around, then the remaining zones in alphabetical order.
+func (c *Config) failoverZones() []string {
+	zones := make([]string, 0, len(c.ServiceUrls))
+	added := map[string]bool{}
+	add := func(zone string) {
+		if _, ok := c.ServiceUrls[zone]; ok && !added[zone] {
+			zones = append(zones, zone)
+			added[zone] = true
 		}
-		zonesPerm := rand.Perm(len(c.ServiceUrls))
-		for _, v := range zonesPerm {
-			if indMap[v] != c.DNSDiscoveryZone {
-				urlsOfZone := c.ServiceUrls[indMap[v]]
-				urlsPerm := rand.Perm(len(urlsOfZone))
-				for _, p := range urlsPerm {
-					urls = append(urls, urlsOfZone[p])
-				}
-			}
+	}
 
+	local := c.LocalZone()
+	add(local)
+	availabilityZones := c.AvailabilityZones[c.Region]
+	start := 0
+	for i, zone := range availabilityZones {
+		if zone == local {
+			start = i
+			break
 		}
-		return urls, nil
-	} else {
+	}
+	for i := range availabilityZones {
+		add(availabilityZones[(start+i)%len(availabilityZones)])
+	}
+	for _, zone := range sortedZones(c.ServiceUrls) {
+		add(zone)
+	}
+	return zones
+}
+
+func sortedZones(serviceUrls map[string][]string) []string {
+	zones := make([]string, 0, len(serviceUrls))
+	for zone := range serviceUrls {
+		zones = append(zones, zone)
+	}
+	sort.Strings(zones)
+	return zones
+}
+
+// createUrlsList creates an array of urls from the ServiceUrls map, shuffling the urls of each zone.
+// When PreferSameZone is set, the zones are in failover order, starting with the local zone.
+// Otherwise the zones are shuffled too.
+func (c *Config) createUrlsList() ([]string, error) {
+	if c.ServiceUrls == nil {
+		return nil, errors.New("Service URLs must be defined")
+	}
+	if c.UseDNSForServiceUrls {
 		return nil, fmt.Errorf("DNS discovery is not supported")
 	}
+
+	zones := sortedZones(c.ServiceUrls)
+	if c.PreferSameZone {
+		zones = c.failoverZones()
+	} else {
+		rand.Shuffle(len(zones), func(i, j int) { zones[i], zones[j] = zones[j], zones[i] })
+	}
+
+	urls := []string{}
+	for _, zone := range zones {
+		urlsOfZone := c.ServiceUrls[zone]
+		for _, p := range rand.Perm(len(urlsOfZone)) {
+			urls = append(urls, urlsOfZone[p])
+		}
+	}
+	return urls, nil
 }
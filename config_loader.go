@@ -0,0 +1,349 @@
+// Copyright 2016 IBM Corporation
+//
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+//
+//       http://www.apache.org/licenses/LICENSE-2.0
+//
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+
+//Package goEurekaClient Implements a go client that interacts with a eureka server
+package goEurekaClient
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	yaml "gopkg.in/yaml.v2"
+)
+
+const (
+	springClientPrefix = "eureka.client."
+	envClientPrefix    = "EUREKA_CLIENT_"
+	springDefaultZone  = "defaultZone"
+)
+
+// ConfigLoader loads a config from layered sources, each overriding the previous ones:
+// the defaults, a JSON or YAML file, a Spring properties file, properties, and environment variables.
+//
+// Properties use the Spring eureka.client.* names, such as eureka.client.serviceUrl.defaultZone or
+// eureka.client.eurekaServerConnectTimeoutSeconds, with relaxed binding: names are case insensitive and may
+// contain dashes. The zones of a region are listed by eureka.client.availabilityZones.<region>. Settings without
+// a Spring equivalent use the names eureka.client.zone, eureka.client.dnsDiscoveryZone,
+// eureka.client.retriesCount and eureka.client.useJson. Environment variables are the upper case property names,
+// with dots replaced by underscores, such as EUREKA_CLIENT_SERVICEURL_DEFAULTZONE. Since they cannot hold dashes,
+// the underscores of the zone or region which ends EUREKA_CLIENT_SERVICEURL_* and EUREKA_CLIENT_AVAILABILITYZONES_*
+// stand for dashes: EUREKA_CLIENT_SERVICEURL_US_EAST_1A sets the URLs of the zone us-east-1a. Like the other
+// sources, these names match the zones and regions already configured regardless of case, dashes and underscores.
+type ConfigLoader struct {
+	// File is a JSON or YAML file, decoded as YAML when its extension is .yaml or .yml. A file whose top-level
+	// key is eureka is read as Spring properties, such as an application.yml file.
+	File string
+	// PropertiesFile is a Spring properties file.
+	PropertiesFile string
+	// Properties are Spring properties.
+	Properties map[string]string
+	// Environ holds environment variables in the form key=value, typically os.Environ().
+	Environ []string
+}
+
+// Load loads the layered sources and validates the resulting config.
+func (l ConfigLoader) Load() (*Config, error) {
+	config := DefaultConfig()
+
+	if l.File != "" {
+		if err := loadConfigFile(config, l.File); err != nil {
+			return nil, err
+		}
+	}
+
+	if l.PropertiesFile != "" {
+		data, err := ioutil.ReadFile(l.PropertiesFile)
+		if err != nil {
+			return nil, fmt.Errorf("opening properties file %v", err.Error())
+		}
+		properties, err := parseProperties(data)
+		if err != nil {
+			return nil, fmt.Errorf("parsing properties file %v", err.Error())
+		}
+		if err = applyProperties(config, properties); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := applyProperties(config, l.Properties); err != nil {
+		return nil, err
+	}
+
+	if err := applyProperties(config, environProperties(l.Environ)); err != nil {
+		return nil, err
+	}
+
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+	return config, nil
+}
+
+func loadConfigFile(config *Config, fileName string) error {
+	data, err := ioutil.ReadFile(fileName)
+	if err != nil {
+		return fmt.Errorf("opening config file %v", err.Error())
+	}
+
+	var document map[string]interface{}
+	switch strings.ToLower(filepath.Ext(fileName)) {
+	case ".yaml", ".yml":
+		var raw interface{}
+		if err = yaml.Unmarshal(data, &raw); err != nil {
+			return fmt.Errorf("parsing config file %v", err.Error())
+		}
+		var ok bool
+		if document, ok = yamlToJSON(raw).(map[string]interface{}); !ok && raw != nil {
+			return fmt.Errorf("parsing config file %s: expected a mapping", fileName)
+		}
+	default:
+		if err = json.Unmarshal(data, &document); err != nil {
+			return fmt.Errorf("parsing config file %v", err.Error())
+		}
+	}
+
+	if spring, ok := document["eureka"]; ok {
+		properties := map[string]string{}
+		flattenProperties("eureka", spring, properties)
+		return applyProperties(config, properties)
+	}
+
+	data, err = json.Marshal(document)
+	if err != nil {
+		return err
+	}
+	if err = json.Unmarshal(data, config); err != nil {
+		return fmt.Errorf("parsing config file %v", err.Error())
+	}
+	return nil
+}
+
+// yamlToJSON converts the mappings decoded by yaml into JSON objects.
+func yamlToJSON(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[interface{}]interface{}:
+		object := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			object[fmt.Sprintf("%v", key)] = yamlToJSON(item)
+		}
+		return object
+	case []interface{}:
+		for i, item := range v {
+			v[i] = yamlToJSON(item)
+		}
+	}
+	return value
+}
+
+// flattenProperties converts nested mappings into dotted property names. Lists are joined with commas.
+func flattenProperties(prefix string, value interface{}, properties map[string]string) {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, item := range v {
+			flattenProperties(prefix+"."+key, item, properties)
+		}
+	case []interface{}:
+		items := make([]string, len(v))
+		for i, item := range v {
+			items[i] = fmt.Sprintf("%v", item)
+		}
+		properties[prefix] = strings.Join(items, ",")
+	case nil:
+	default:
+		properties[prefix] = fmt.Sprintf("%v", v)
+	}
+}
+
+// parseProperties parses a properties file, with key=value or key: value lines, # and ! comments,
+// and lines continued by a trailing backslash.
+func parseProperties(data []byte) (map[string]string, error) {
+	properties := map[string]string{}
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	line, number := "", 0
+	for scanner.Scan() {
+		number++
+		text := strings.TrimSpace(scanner.Text())
+		if line == "" && (text == "" || strings.HasPrefix(text, "#") || strings.HasPrefix(text, "!")) {
+			continue
+		}
+		if strings.HasSuffix(text, `\`) {
+			line += strings.TrimSuffix(text, `\`)
+			continue
+		}
+		line += text
+
+		separator := strings.IndexAny(line, "=:")
+		if separator <= 0 {
+			return nil, fmt.Errorf("line %d: %q is not a property", number, line)
+		}
+		properties[strings.TrimSpace(line[:separator])] = strings.TrimSpace(line[separator+1:])
+		line = ""
+	}
+	return properties, scanner.Err()
+}
+
+// environProperties converts the EUREKA_CLIENT_* environment variables into property names.
+func environProperties(environ []string) map[string]string {
+	properties := map[string]string{}
+	for _, variable := range environ {
+		parts := strings.SplitN(variable, "=", 2)
+		if len(parts) != 2 || !strings.HasPrefix(parts[0], envClientPrefix) {
+			continue
+		}
+		name := strings.TrimPrefix(parts[0], envClientPrefix)
+		segments := strings.SplitN(name, "_", 2)
+		if len(segments) == 2 && (segments[0] == "SERVICEURL" || segments[0] == "AVAILABILITYZONES") {
+			// The zone or region keeps its dashes, written as underscores
+			name = segments[0] + "." + strings.Replace(segments[1], "_", "-", -1)
+		} else {
+			name = strings.Replace(name, "_", ".", -1)
+		}
+		properties[springClientPrefix+strings.ToLower(name)] = parts[1]
+	}
+	return properties
+}
+
+// configuredName returns the name among the configured ones which matches name regardless of case, dashes and
+// underscores, and name itself when none matches.
+func configuredName(name string, configured []string) string {
+	for _, candidate := range configured {
+		if relaxedName(candidate) == relaxedName(name) {
+			return candidate
+		}
+	}
+	return name
+}
+
+// configuredZones returns the zones named by the config.
+func configuredZones(config *Config) []string {
+	zones := []string{springDefaultZone}
+	for zone := range config.ServiceUrls {
+		zones = append(zones, zone)
+	}
+	for _, regionZones := range config.AvailabilityZones {
+		zones = append(zones, regionZones...)
+	}
+	if config.Zone != "" {
+		zones = append(zones, config.Zone)
+	}
+	sort.Strings(zones)
+	return zones
+}
+
+// configuredRegions returns the regions named by the config.
+func configuredRegions(config *Config) []string {
+	var regions []string
+	for region := range config.AvailabilityZones {
+		regions = append(regions, region)
+	}
+	if config.Region != "" {
+		regions = append(regions, config.Region)
+	}
+	sort.Strings(regions)
+	return regions
+}
+
+// relaxedName lowers a property name and removes its dashes and underscores.
+func relaxedName(name string) string {
+	return strings.ToLower(strings.NewReplacer("-", "", "_", "").Replace(name))
+}
+
+// applyProperties sets the config fields named by eureka.client.* properties, in sorted order.
+func applyProperties(config *Config, properties map[string]string) error {
+	names := make([]string, 0, len(properties))
+	for name := range properties {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		if !strings.HasPrefix(strings.ToLower(name), springClientPrefix) {
+			continue
+		}
+		if err := applyProperty(config, name[len(springClientPrefix):], strings.TrimSpace(properties[name])); err != nil {
+			return fmt.Errorf("property %s %v", name, err)
+		}
+	}
+	return nil
+}
+
+func applyProperty(config *Config, name, value string) error {
+	parts := strings.SplitN(name, ".", 2)
+	var err error
+	switch relaxedName(parts[0]) {
+	case "serviceurl":
+		if len(parts) != 2 || parts[1] == "" {
+			return fmt.Errorf("does not name a zone")
+		}
+		zone := configuredName(parts[1], configuredZones(config))
+		if config.ServiceUrls == nil {
+			config.ServiceUrls = map[string][]string{}
+		}
+		config.ServiceUrls[zone] = splitList(value)
+	case "eurekaserverconnecttimeoutseconds":
+		config.ConnectTimeoutSeconds, err = parseSeconds(value)
+	case "usednsforfetchingserviceurls":
+		config.UseDNSForServiceUrls, err = strconv.ParseBool(value)
+	case "eurekaserverdnsname":
+		config.ServerDNSName = value
+	case "eurekaserverport":
+		config.ServerPort, err = strconv.Atoi(value)
+	case "prefersamezoneeureka":
+		config.PreferSameZone, err = strconv.ParseBool(value)
+	case "region":
+		config.Region = value
+	case "availabilityzones":
+		if len(parts) != 2 || parts[1] == "" {
+			return fmt.Errorf("does not name a region")
+		}
+		if config.AvailabilityZones == nil {
+			config.AvailabilityZones = map[string][]string{}
+		}
+		config.AvailabilityZones[configuredName(parts[1], configuredRegions(config))] = splitList(value)
+	case "zone":
+		config.Zone = value
+	case "transport":
+		if len(parts) == 2 && relaxedName(parts[1]) == "sessionedclientreconnectintervalseconds" {
+			config.EndpointReshuffleInterval, err = parseSeconds(value)
+		}
+	case "registryfetchintervalseconds":
+		config.PollInterval, err = parseSeconds(value)
+	case "fetchremoteregionsregistry":
+		config.FetchRemoteRegions = splitList(value)
+	case "dnsdiscoveryzone":
+		config.DNSDiscoveryZone = value
+	case "retriescount":
+		config.RetriesCount, err = strconv.Atoi(value)
+	case "usejson":
+		config.UseJSON, err = strconv.ParseBool(value)
+	}
+	return err
+}
+
+func splitList(value string) []string {
+	list := []string{}
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
@@ -0,0 +1,257 @@
+// Copyright 2016 IBM Corporation
+//
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+//
+//       http://www.apache.org/licenses/LICENSE-2.0
+//
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+
+//Package goEurekaClient Implements a go client that interacts with a eureka server
+package goEurekaClient
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func writeConfigFile(t *testing.T, dir, name, content string) string {
+	path := filepath.Join(dir, name)
+	if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("error = %v", err)
+	}
+	return path
+}
+
+func TestNewConfigFromFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "config")
+	if err != nil {
+		t.Fatalf("error = %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	jsonFile := writeConfigFile(t, dir, "config.json", `{
+		"connection_timeout_seconds": 5,
+		"service_urls": {"zone1": ["http://eureka1:8080/eureka/v2/"]},
+		"use_json": false
+	}`)
+	config, err := NewConfigFromFile(jsonFile)
+	if err != nil {
+		t.Fatalf("error = %v", err)
+	}
+	if config.ConnectTimeoutSeconds != 5*time.Second || config.UseJSON || config.ServerPort != 8080 ||
+		config.RetriesCount != 3 || config.ServiceUrls["zone1"][0] != "http://eureka1:8080/eureka/v2/" {
+		t.Errorf("unexpected config %+v", config)
+	}
+
+	yamlFile := writeConfigFile(t, dir, "config.yaml", `
+connection_timeout_seconds: 2.5s
+service_urls:
+  zone1: [http://eureka1:8080/eureka/v2/]
+region: us-east-1
+`)
+	if config, err = NewConfigFromFile(yamlFile); err != nil {
+		t.Fatalf("error = %v", err)
+	}
+	if config.ConnectTimeoutSeconds != 2500*time.Millisecond || !config.UseJSON || config.Region != "us-east-1" {
+		t.Errorf("unexpected config %+v", config)
+	}
+
+	springFile := writeConfigFile(t, dir, "application.yml", `
+eureka:
+  client:
+    serviceUrl:
+      defaultZone: http://eureka1:8761/eureka/,http://eureka2:8761/eureka/
+    eureka-server-connect-timeout-seconds: 7
+    fetchRemoteRegionsRegistry: [eu-west-1]
+`)
+	if config, err = NewConfigFromFile(springFile); err != nil {
+		t.Fatalf("error = %v", err)
+	}
+	if len(config.ServiceUrls["defaultZone"]) != 2 || config.ConnectTimeoutSeconds != 7*time.Second ||
+		!reflect.DeepEqual(config.FetchRemoteRegions, []string{"eu-west-1"}) {
+		t.Errorf("unexpected config %+v", config)
+	}
+
+	invalidFile := writeConfigFile(t, dir, "invalid.json", `{"server_port": 0, "connection_timeout_seconds": "soon"}`)
+	if _, err = NewConfigFromFile(invalidFile); err == nil || !strings.Contains(err.Error(), "connection_timeout_seconds") {
+		t.Errorf("error = %v, expected the timeout to be rejected", err)
+	}
+}
+
+func TestConfigLoaderLayers(t *testing.T) {
+	dir, err := ioutil.TempDir("", "config")
+	if err != nil {
+		t.Fatalf("error = %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	loader := ConfigLoader{
+		File: writeConfigFile(t, dir, "config.json", `{"service_urls": {"zone1": ["http://file:8080/eureka/v2/"]},
+			"region": "us-east-1", "retries_count": 5}`),
+		PropertiesFile: writeConfigFile(t, dir, "application.properties", `
+# Spring properties
+eureka.client.region=eu-west-1
+eureka.client.serviceUrl.zone1 = http://properties:8080/eureka/v2/, \
+    http://properties2:8080/eureka/v2/
+eureka.client.preferSameZoneEureka: true
+`),
+		Properties: map[string]string{"eureka.client.zone": "zone1", "spring.application.name": "app",
+			"eureka.client.availability-zones.eu-west-1": "zone1, defaultZone"},
+		Environ: []string{"PATH=/bin", "EUREKA_CLIENT_SERVICEURL_DEFAULTZONE=http://env:8080/eureka/v2/",
+			"EUREKA_CLIENT_EUREKASERVERPORT=8761"},
+	}
+	config, err := loader.Load()
+	if err != nil {
+		t.Fatalf("error = %v", err)
+	}
+
+	expected := &Config{
+		ConnectTimeoutSeconds: 10 * time.Second,
+		ServiceUrls: map[string][]string{
+			"zone1":       {"http://properties:8080/eureka/v2/", "http://properties2:8080/eureka/v2/"},
+			"defaultZone": {"http://env:8080/eureka/v2/"},
+		},
+		ServerPort:        8761,
+		PreferSameZone:    true,
+		RetriesCount:      5,
+		UseJSON:           true,
+		Region:            "eu-west-1",
+		Zone:              "zone1",
+		AvailabilityZones: map[string][]string{"eu-west-1": {"zone1", "defaultZone"}},
+	}
+	if !reflect.DeepEqual(config, expected) {
+		t.Errorf("config = %+v, expected %+v", config, expected)
+	}
+
+	loader.Environ = []string{"EUREKA_CLIENT_EUREKASERVERPORT=http"}
+	if _, err = loader.Load(); err == nil {
+		t.Error("an invalid port should be rejected")
+	}
+}
+
+func TestConfigLoaderEnvironZones(t *testing.T) {
+	loader := ConfigLoader{
+		Properties: map[string]string{"eureka.client.region": "us-east-1", "eureka.client.zone": "us_east_1b"},
+		Environ: []string{"EUREKA_CLIENT_SERVICEURL_US_EAST_1A=http://env1:8080/eureka/v2/",
+			"EUREKA_CLIENT_SERVICEURL_US_EAST_1B=http://env2:8080/eureka/v2/",
+			"EUREKA_CLIENT_SERVICEURL_DEFAULTZONE=http://env3:8080/eureka/v2/",
+			"EUREKA_CLIENT_AVAILABILITYZONES_US_EAST_1=us-east-1a,us_east_1b"},
+	}
+	config, err := loader.Load()
+	if err != nil {
+		t.Fatalf("error = %v", err)
+	}
+
+	expected := map[string][]string{
+		"us-east-1a":  {"http://env1:8080/eureka/v2/"},
+		"us_east_1b":  {"http://env2:8080/eureka/v2/"},
+		"defaultZone": {"http://env3:8080/eureka/v2/"},
+	}
+	if !reflect.DeepEqual(config.ServiceUrls, expected) {
+		t.Errorf("service URLs = %v, expected %v", config.ServiceUrls, expected)
+	}
+	if zones := config.AvailabilityZones["us-east-1"]; len(config.AvailabilityZones) != 1 || len(zones) != 2 {
+		t.Errorf("availability zones = %v, expected the zones of us-east-1", config.AvailabilityZones)
+	}
+}
+
+func TestConfigValidate(t *testing.T) {
+	config := DefaultConfig()
+	config.ServiceUrls = map[string][]string{"zone1": {"eureka1:8080"}, "zone2": {}}
+	config.PreferSameZone = true
+	config.Zone = "zone3"
+	config.RetriesCount = -1
+
+	err := config.Validate()
+	if err == nil {
+		t.Fatal("config should be invalid")
+	}
+	for _, problem := range []string{`"eureka1:8080"`, `zone "zone2"`, `preferred zone "zone3"`, "retries count"} {
+		if !strings.Contains(err.Error(), problem) {
+			t.Errorf("error %q should mention %s", err, problem)
+		}
+	}
+
+	config = DefaultConfig()
+	config.UseDNSForServiceUrls = true
+	config.ServerDNSName = "eureka.example.com"
+	if err = config.Validate(); err == nil || !strings.Contains(err.Error(), "DNS discovery") {
+		t.Errorf("DNS discovery should be rejected, got %v", err)
+	}
+
+	data, err := json.Marshal(DefaultConfig())
+	if err != nil || !strings.Contains(string(data), `"connection_timeout_seconds":10`) {
+		t.Errorf("timeout should be marshaled in seconds, got %s (%v)", data, err)
+	}
+}
+
+func TestCreateUrlsListZoneOrder(t *testing.T) {
+	serviceUrls := map[string][]string{
+		"us-east-1a": {"http://a1", "http://a2"},
+		"us-east-1b": {"http://b1"},
+		"us-east-1c": {"http://c1", "http://c2"},
+		"us-east-1d": {"http://d1"},
+	}
+	availabilityZones := map[string][]string{"us-east-1": {"us-east-1a", "us-east-1b", "us-east-1c"}}
+
+	tests := []struct {
+		name     string
+		config   Config
+		expected []string
+	}{
+		{"local zone", Config{Zone: "us-east-1b"},
+			[]string{"us-east-1b", "us-east-1c", "us-east-1a", "us-east-1d"}},
+		{"first availability zone", Config{},
+			[]string{"us-east-1a", "us-east-1b", "us-east-1c", "us-east-1d"}},
+		{"dns discovery zone ignored", Config{DNSDiscoveryZone: "us-east-1c"},
+			[]string{"us-east-1a", "us-east-1b", "us-east-1c", "us-east-1d"}},
+		{"zone outside the availability zones", Config{Zone: "us-east-1d"},
+			[]string{"us-east-1d", "us-east-1a", "us-east-1b", "us-east-1c"}},
+		{"other region", Config{Region: "eu-west-1", Zone: "us-east-1c"},
+			[]string{"us-east-1c", "us-east-1a", "us-east-1b", "us-east-1d"}},
+	}
+	for _, test := range tests {
+		config := test.config
+		config.ServiceUrls = serviceUrls
+		config.PreferSameZone = true
+		config.AvailabilityZones = availabilityZones
+		if config.Region == "" {
+			config.Region = "us-east-1"
+		}
+
+		urls, err := config.createUrlsList()
+		if err != nil {
+			t.Fatalf("%s: error = %v", test.name, err)
+		}
+		var zones []string
+		for _, u := range urls {
+			zone := "us-east-1" + u[len("http://"):len("http://")+1]
+			if len(zones) == 0 || zones[len(zones)-1] != zone {
+				zones = append(zones, zone)
+			}
+		}
+		if !reflect.DeepEqual(zones, test.expected) || len(urls) != 6 {
+			t.Errorf("%s: urls %v, expected the zones %v", test.name, urls, test.expected)
+		}
+	}
+
+	config := Config{ServiceUrls: serviceUrls}
+	for i := 0; i < 10; i++ {
+		urls, err := config.createUrlsList()
+		if err != nil || len(urls) != 6 {
+			t.Fatalf("urls %v (%v), expected all the urls of all the zones", urls, err)
+		}
+	}
+}
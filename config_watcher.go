@@ -0,0 +1,84 @@
+// Copyright 2016 IBM Corporation
+//
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+//
+//       http://www.apache.org/licenses/LICENSE-2.0
+//
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+
+//Package goEurekaClient Implements a go client that interacts with a eureka server
+package goEurekaClient
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"log"
+	"time"
+)
+
+const defaultConfigWatchInterval = 10 * time.Second
+
+// ConfigUpdater accepts config updates at runtime. It is implemented by Discovery, DiscoveryCache and Registrator.
+// The server list and HTTP client settings are replaced at once, so that each request uses either the previous
+// or the new settings. The regions cannot be changed.
+type ConfigUpdater interface {
+	UpdateConfig(config *Config) error
+}
+
+// WatchConfigFile checks the files of the loader every interval, default 10s, until the context is done.
+// When their contents change, the config is loaded again and passed to the updaters.
+// Configs which fail to load are logged and skipped, so that the updaters keep their current config.
+func WatchConfigFile(ctx context.Context, loader ConfigLoader, interval time.Duration, updaters ...ConfigUpdater) {
+	if interval <= 0 {
+		interval = defaultConfigWatchInterval
+	}
+
+	contents := readConfigFiles(loader)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			current := readConfigFiles(loader)
+			if bytes.Equal(current, contents) {
+				continue
+			}
+			contents = current
+
+			config, err := loader.Load()
+			if err != nil {
+				log.Printf("Failed to reload config. error: %s\n", err)
+				continue
+			}
+			for _, updater := range updaters {
+				if err = updater.UpdateConfig(config); err != nil {
+					log.Printf("Failed to update config. error: %s\n", err)
+				}
+			}
+		case <-ctx.Done():
+			log.Printf("stop chan received. stop watching config files...")
+			return
+		}
+	}
+}
+
+// readConfigFiles returns the contents of the files of the loader. Missing files have no contents.
+func readConfigFiles(loader ConfigLoader) []byte {
+	var contents []byte
+	for _, fileName := range []string{loader.File, loader.PropertiesFile} {
+		if fileName == "" {
+			continue
+		}
+		data, _ := ioutil.ReadFile(fileName)
+		contents = append(contents, data...)
+		contents = append(contents, 0)
+	}
+	return contents
+}
@@ -0,0 +1,211 @@
+// Copyright 2016 IBM Corporation
+//
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+//
+//       http://www.apache.org/licenses/LICENSE-2.0
+//
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+
+//Package goEurekaClient Implements a go client that interacts with a eureka server
+package goEurekaClient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestUpdateConfigPreservesCache(t *testing.T) {
+	first := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `{"applications":{"application":{"name":"APP1","instance":%s}}}`,
+			registryInstanceJSON("APP1", "inst1", "vip1", "UP", "us-east-1a"))
+	}))
+	defer first.Close()
+	var requests int32
+	second := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer second.Close()
+
+	conf := &Config{
+		ConnectTimeoutSeconds: 10 * time.Second,
+		ServiceUrls:           map[string][]string{"eureka": {first.URL}},
+		UseJSON:               true,
+	}
+	cache, err := NewDiscoveryCache(conf, time.Hour, nil)
+	if err != nil {
+		t.Fatalf("error = %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	cache.Run(ctx)
+
+	deadline := time.Now().Add(5 * time.Second)
+	for _, err = cache.GetInstancesByVip("vip1"); err != nil; _, err = cache.GetInstancesByVip("vip1") {
+		if time.Now().After(deadline) {
+			t.Fatalf("the cache was not filled: %v", err)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	if err = cache.UpdateConfig(&Config{ServiceUrls: map[string][]string{"eureka": {second.URL}},
+		ConnectTimeoutSeconds: time.Second, Region: "us-east-1"}); err == nil {
+		t.Error("a region change should be rejected")
+	}
+
+	updated := &Config{
+		ConnectTimeoutSeconds: time.Second,
+		ServiceUrls:           map[string][]string{"eureka": {second.URL}},
+		UseJSON:               true,
+		PollInterval:          10 * time.Millisecond,
+	}
+	if err = cache.UpdateConfig(updated); err != nil {
+		t.Fatalf("error = %v", err)
+	}
+	for atomic.LoadInt32(&requests) < 2 {
+		if time.Now().After(deadline) {
+			t.Fatal("the cache should poll the new server at the new interval")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if insts, err := cache.GetInstancesByVip("vip1"); err != nil || len(insts) != 1 {
+		t.Errorf("the cache contents should be preserved, got %v (%v)", insts, err)
+	}
+}
+
+func TestConcurrentUpdateConfigWithoutRunLoop(t *testing.T) {
+	conf := &Config{ServiceUrls: map[string][]string{"eureka": {"http://eureka1:8080/eureka/v2/"}}}
+	discovery, err := NewDiscovery(conf, nil)
+	if err != nil {
+		t.Fatalf("error = %v", err)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		var wg sync.WaitGroup
+		for i := 1; i <= 10; i++ {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				discovery.UpdateConfig(&Config{ServiceUrls: conf.ServiceUrls, PollInterval: time.Duration(i) * time.Second})
+			}(i)
+		}
+		wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("config updates should not block when no run loop receives the poll interval")
+	}
+}
+
+func TestUpdateConfigKeepsEndpointStates(t *testing.T) {
+	urls := map[string][]string{"zone1": {"http://a", "http://b"}}
+	d, err := NewDiscovery(&Config{ServiceUrls: urls}, nil)
+	if err != nil {
+		t.Fatalf("error = %v", err)
+	}
+	cl := d.(*discovery).client
+	endpoints := cl.currentSettings().endpoints
+	for _, ep := range endpoints.endpoints {
+		if ep.url == "http://a" {
+			endpoints.failed(ep, errors.New("connection refused"))
+		} else {
+			endpoints.succeeded(ep)
+		}
+	}
+	before := cl.endpointStates()
+
+	if err = d.UpdateConfig(&Config{ServiceUrls: urls, ConnectTimeoutSeconds: time.Second}); err != nil {
+		t.Fatalf("error = %v", err)
+	}
+	if states := cl.endpointStates(); !reflect.DeepEqual(states, before) {
+		t.Errorf("states = %+v, expected the states of an unchanged server list to be kept: %+v", states, before)
+	}
+
+	if err = d.UpdateConfig(&Config{ServiceUrls: map[string][]string{"zone1": {"http://a", "http://c"}}}); err != nil {
+		t.Fatalf("error = %v", err)
+	}
+	byURL := map[string]EndpointState{}
+	for _, state := range cl.endpointStates() {
+		byURL[state.URL] = state
+	}
+	if len(byURL) != 2 || byURL["http://a"].Failures != 1 || byURL["http://c"].Failures != 0 || byURL["http://c"].Current {
+		t.Errorf("unexpected states %+v, only the states of the servers still listed should be kept", byURL)
+	}
+}
+
+// configRecorder is a ConfigUpdater which records the configs it receives.
+type configRecorder struct {
+	sync.Mutex
+	configs []*Config
+}
+
+func (r *configRecorder) UpdateConfig(config *Config) error {
+	r.Lock()
+	defer r.Unlock()
+	r.configs = append(r.configs, config)
+	return nil
+}
+
+func (r *configRecorder) count() int {
+	r.Lock()
+	defer r.Unlock()
+	return len(r.configs)
+}
+
+func TestWatchConfigFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "config")
+	if err != nil {
+		t.Fatalf("error = %v", err)
+	}
+	defer os.RemoveAll(dir)
+	file := writeConfigFile(t, dir, "config.json", `{"service_urls": {"zone1": ["http://eureka1:8080/eureka/v2/"]}}`)
+
+	recorder := &configRecorder{}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go WatchConfigFile(ctx, ConfigLoader{File: file}, 10*time.Millisecond, recorder)
+
+	time.Sleep(50 * time.Millisecond)
+	if recorder.count() != 0 {
+		t.Fatal("an unchanged file should not update the config")
+	}
+
+	writeConfigFile(t, dir, "config.json", `{"service_urls": {"zone1": ["not a url"]}}`)
+	time.Sleep(50 * time.Millisecond)
+	if recorder.count() != 0 {
+		t.Fatal("an invalid config should not be applied")
+	}
+
+	writeConfigFile(t, dir, "config.json", `{"service_urls": {"zone1": ["http://eureka2:8080/eureka/v2/"]}}`)
+	deadline := time.Now().Add(5 * time.Second)
+	for recorder.count() == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("a changed file should update the config")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	recorder.Lock()
+	defer recorder.Unlock()
+	if urls := recorder.configs[0].ServiceUrls["zone1"]; len(urls) != 1 || urls[0] != "http://eureka2:8080/eureka/v2/" {
+		t.Errorf("unexpected service URLs %v", urls)
+	}
+}
@@ -0,0 +1,127 @@
+// Copyright 2016 IBM Corporation
+//
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+//
+//       http://www.apache.org/licenses/LICENSE-2.0
+//
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+
+package consulsync
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	// HealthPassing is the health status of a service instance able to serve traffic.
+	HealthPassing = "passing"
+	// HealthWarning is the health status of a degraded service instance.
+	HealthWarning = "warning"
+	// HealthCritical is the health status of a service instance unable to serve traffic.
+	HealthCritical = "critical"
+	// HealthMaintenance is the health status of a service instance in maintenance mode.
+	HealthMaintenance = "maintenance"
+)
+
+// CatalogService is a service instance registered in a Consul-compatible catalog.
+type CatalogService struct {
+	ID           string            `json:"ID"`
+	Name         string            `json:"Service"`
+	Address      string            `json:"Address"`
+	Port         int               `json:"Port"`
+	Tags         []string          `json:"Tags"`
+	Meta         map[string]string `json:"Meta"`
+	Health       string            `json:"Health"`
+	LastModified int64             `json:"LastModified"` // milliseconds since the epoch, set by the catalog
+}
+
+// Catalog is a Consul-compatible service catalog.
+type Catalog interface {
+	// Services returns all service instances of the catalog.
+	Services() ([]*CatalogService, error)
+	// Register registers or replaces a service instance.
+	Register(svc *CatalogService) error
+	// Deregister removes a service instance.
+	Deregister(id string) error
+}
+
+// FakeCatalog is an in-memory catalog, for tests and local development.
+type FakeCatalog struct {
+	sync.Mutex
+	services map[string]*CatalogService
+}
+
+// NewFakeCatalog creates an empty in-memory catalog.
+func NewFakeCatalog() *FakeCatalog {
+	return &FakeCatalog{services: map[string]*CatalogService{}}
+}
+
+// Services returns copies of the service instances, sorted by ID.
+func (c *FakeCatalog) Services() ([]*CatalogService, error) {
+	c.Lock()
+	defer c.Unlock()
+
+	services := make([]*CatalogService, 0, len(c.services))
+	for _, svc := range c.services {
+		services = append(services, copyService(svc))
+	}
+	sort.Slice(services, func(i, j int) bool { return services[i].ID < services[j].ID })
+	return services, nil
+}
+
+// Register stores a copy of the service instance. Its modification time is set to the current time,
+// unless it is already set.
+func (c *FakeCatalog) Register(svc *CatalogService) error {
+	if svc == nil || svc.ID == "" || svc.Name == "" {
+		return fmt.Errorf("service ID and name must be defined")
+	}
+
+	c.Lock()
+	defer c.Unlock()
+	stored := copyService(svc)
+	if stored.LastModified == 0 {
+		stored.LastModified = time.Now().UnixNano() / int64(time.Millisecond)
+	}
+	c.services[svc.ID] = stored
+	return nil
+}
+
+// Deregister removes a service instance.
+func (c *FakeCatalog) Deregister(id string) error {
+	c.Lock()
+	defer c.Unlock()
+	if _, ok := c.services[id]; !ok {
+		return fmt.Errorf("service %s not found", id)
+	}
+	delete(c.services, id)
+	return nil
+}
+
+// Service returns a copy of a service instance, or nil if it is not registered.
+func (c *FakeCatalog) Service(id string) *CatalogService {
+	c.Lock()
+	defer c.Unlock()
+	if svc, ok := c.services[id]; ok {
+		return copyService(svc)
+	}
+	return nil
+}
+
+func copyService(svc *CatalogService) *CatalogService {
+	copySvc := *svc
+	copySvc.Tags = append([]string(nil), svc.Tags...)
+	copySvc.Meta = make(map[string]string, len(svc.Meta))
+	for k, v := range svc.Meta {
+		copySvc.Meta[k] = v
+	}
+	return &copySvc
+}
@@ -0,0 +1,488 @@
+// Copyright 2016 IBM Corporation
+//
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+//
+//       http://www.apache.org/licenses/LICENSE-2.0
+//
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+
+// Package consulsync mirrors the eureka registry into a Consul-compatible catalog, and the catalog back into
+// the eureka registry.
+package consulsync
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	eureka "github.com/amalgam8/go-eureka-client"
+)
+
+const (
+	// SourceKey is the metadata key, set on mirrored services and instances, naming the registry they come from.
+	SourceKey = "sync-source"
+	// TimestampKey is the metadata key, set on mirrored services and instances, holding the modification time
+	// of their origin in milliseconds since the epoch.
+	TimestampKey = "sync-timestamp"
+	// AppKey is the metadata key, set on mirrored services, holding the eureka application name.
+	AppKey = "eureka-app"
+	// SyncTag is the tag of the services mirrored into the catalog.
+	SyncTag = "eureka-sync"
+
+	// SourceEureka is the source of the services mirrored from the eureka registry.
+	SourceEureka = "eureka"
+	// SourceConsul is the source of the instances mirrored from the catalog.
+	SourceConsul = "consul"
+
+	defaultInterval = 30 * time.Second
+)
+
+// ApplicationSource lists the applications of the registry. It is implemented by eureka.DiscoveryCache.
+type ApplicationSource interface {
+	GetApplications() ([]*eureka.Application, error)
+}
+
+// Config defines the way the registries are synchronized.
+type Config struct {
+	// Interval of the full synchronizations, and of the heartbeats of the instances mirrored from the catalog,
+	// default 30s. It must be shorter than the eureka lease duration.
+	Interval time.Duration
+}
+
+// Syncer synchronizes the eureka registry and a catalog in both directions.
+//
+// Each eureka instance is mirrored into a catalog service with the same ID, tagged with SyncTag, and each catalog
+// service is registered in eureka as an instance whose host name is the service ID. Mirrored copies carry their
+// source in the SourceKey metadata, and are never mirrored back, which prevents loops. A copy is never replaced
+// by an origin older than the timestamp recorded in the copy, so the last writer wins: the eureka LastDirtyTs of
+// an instance is compared with the LastModified time of a service, and the other way around. An origin with the
+// same timestamp replaces the copy when their contents differ, since a status set through the eureka status
+// override does not change the LastDirtyTs of the instance.
+type Syncer interface {
+	// Run synchronizes the registries on registry events and every interval, until the context is done.
+	Run(ctx context.Context)
+	// Sync synchronizes the registries in both directions once.
+	Sync() error
+}
+
+type syncer struct {
+	source      ApplicationSource
+	registrator eureka.Registrator
+	catalog     Catalog
+	config      Config
+	changed     chan struct{}
+	unsubFunc   func()
+
+	sync.Mutex
+	mirrored map[string]*eureka.Instance // instances registered in eureka from the catalog, by service ID
+}
+
+// NewSyncer creates a syncer reading the eureka registry from the source, and registering the catalog services
+// with the registrator. If events is not nil, the syncer subscribes to it to mirror eureka changes as soon
+// as they are fetched.
+func NewSyncer(source ApplicationSource, events eureka.EventBroadcaster, registrator eureka.Registrator,
+	catalog Catalog, config Config) (Syncer, error) {
+	if source == nil {
+		return nil, errors.New("application source must be defined")
+	}
+	if registrator == nil {
+		return nil, errors.New("registrator must be defined")
+	}
+	if catalog == nil {
+		return nil, errors.New("catalog must be defined")
+	}
+	if config.Interval <= 0 {
+		config.Interval = defaultInterval
+	}
+
+	s := &syncer{
+		source:      source,
+		registrator: registrator,
+		catalog:     catalog,
+		config:      config,
+		changed:     make(chan struct{}, 1),
+		mirrored:    map[string]*eureka.Instance{},
+	}
+
+	if events != nil {
+		notify := func(inst *eureka.Instance) {
+			// Changes of the instances mirrored from the catalog are not mirrored back
+			if inst != nil && metadataValue(inst.Metadata, SourceKey) == SourceConsul {
+				return
+			}
+			select {
+			case s.changed <- struct{}{}:
+			default:
+			}
+		}
+		s.unsubFunc = events.Subscribe(eureka.EventFuncs{
+			AddFunc:    notify,
+			UpdateFunc: func(_, newInst *eureka.Instance) { notify(newInst) },
+			DeleteFunc: notify,
+		})
+	}
+	return s, nil
+}
+
+// Run synchronizes the registries on registry events and every interval, until the context is done.
+func (s *syncer) Run(ctx context.Context) {
+	if s.unsubFunc != nil {
+		defer s.unsubFunc()
+	}
+
+	s.logError(s.Sync())
+	ticker := time.NewTicker(s.config.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-s.changed:
+			s.logError(s.syncToCatalog())
+		case <-ticker.C:
+			s.logError(s.Sync())
+		case <-ctx.Done():
+			log.Printf("stop chan received. stop running consul sync...")
+			return
+		}
+	}
+}
+
+// Sync synchronizes the registries in both directions once.
+func (s *syncer) Sync() error {
+	var problems []string
+	if err := s.syncToCatalog(); err != nil {
+		problems = append(problems, err.Error())
+	}
+	if err := s.syncToEureka(); err != nil {
+		problems = append(problems, err.Error())
+	}
+	if len(problems) > 0 {
+		return errors.New(strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+func (s *syncer) logError(err error) {
+	if err != nil {
+		log.Printf("Failed to synchronize registries. error: %s\n", err)
+	}
+}
+
+// syncToCatalog mirrors the eureka instances into the catalog, and removes the mirrored services whose
+// instance is gone.
+func (s *syncer) syncToCatalog() error {
+	apps, err := s.source.GetApplications()
+	if err != nil {
+		return fmt.Errorf("Failed to get applications. error: %s", err)
+	}
+	services, err := s.catalog.Services()
+	if err != nil {
+		return fmt.Errorf("Failed to list catalog services. error: %s", err)
+	}
+
+	existing := make(map[string]*CatalogService, len(services))
+	for _, svc := range services {
+		existing[svc.ID] = svc
+	}
+
+	var problems []string
+	desired := map[string]bool{}
+	for _, app := range apps {
+		for _, inst := range app.Instances {
+			if metadataValue(inst.Metadata, SourceKey) == SourceConsul {
+				continue
+			}
+			svc, ok := catalogService(inst)
+			if !ok {
+				continue
+			}
+			desired[svc.ID] = true
+
+			current, ok := existing[svc.ID]
+			if ok && current.Meta[SourceKey] != SourceEureka {
+				log.Printf("Catalog service %s is not mirrored from eureka, skipping instance\n", svc.ID)
+				continue
+			}
+			if ok && (timestamp(current.Meta[TimestampKey]) > timestamp(svc.Meta[TimestampKey]) || sameService(current, svc)) {
+				continue
+			}
+			if err = s.catalog.Register(svc); err != nil {
+				problems = append(problems, fmt.Sprintf("Failed to register service %s. error: %s", svc.ID, err))
+			}
+		}
+	}
+
+	for _, svc := range services {
+		if svc.Meta[SourceKey] != SourceEureka || desired[svc.ID] {
+			continue
+		}
+		if err = s.catalog.Deregister(svc.ID); err != nil {
+			problems = append(problems, fmt.Sprintf("Failed to deregister service %s. error: %s", svc.ID, err))
+		}
+	}
+
+	if len(problems) > 0 {
+		return errors.New(strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+// syncToEureka registers the catalog services in eureka, renews the leases of the instances already registered,
+// and deregisters the instances whose service is gone.
+func (s *syncer) syncToEureka() error {
+	services, err := s.catalog.Services()
+	if err != nil {
+		return fmt.Errorf("Failed to list catalog services. error: %s", err)
+	}
+	registered, err := s.registeredMirrors()
+	if err != nil {
+		return err
+	}
+
+	s.Lock()
+	defer s.Unlock()
+
+	// Mirrors registered before a restart are adopted, so that they are deregistered when their service is gone
+	for id, inst := range registered {
+		if _, ok := s.mirrored[id]; !ok {
+			s.mirrored[id] = inst
+		}
+	}
+
+	var problems []string
+	seen := map[string]bool{}
+	for _, svc := range services {
+		if svc.Meta[SourceKey] == SourceEureka || hasTag(svc.Tags, SyncTag) {
+			continue
+		}
+		seen[svc.ID] = true
+
+		current, ok := s.mirrored[svc.ID]
+		currentTs := int64(0)
+		if ok {
+			currentTs = timestamp(current.LastDirtyTs)
+			if inst, found := registered[svc.ID]; found && timestamp(inst.LastDirtyTs) > currentTs {
+				currentTs = timestamp(inst.LastDirtyTs)
+			}
+		}
+		if ok && currentTs > svc.LastModified {
+			if err = s.renew(current); err != nil {
+				problems = append(problems, err.Error())
+			}
+			continue
+		}
+
+		inst, err := eurekaInstance(svc)
+		if err != nil {
+			log.Printf("Failed to mirror catalog service %s. error: %s\n", svc.ID, err)
+			continue
+		}
+		if ok && currentTs == svc.LastModified && sameInstance(current, inst) {
+			if err = s.renew(current); err != nil {
+				problems = append(problems, err.Error())
+			}
+			continue
+		}
+		if err = s.registrator.Register(inst); err != nil {
+			problems = append(problems, fmt.Sprintf("Failed to register instance %s. error: %s", inst.HostName, err))
+			continue
+		}
+		s.mirrored[svc.ID] = inst
+	}
+
+	for id, inst := range s.mirrored {
+		if seen[id] {
+			continue
+		}
+		if err = s.registrator.Deregister(inst); err != nil {
+			problems = append(problems, fmt.Sprintf("Failed to deregister instance %s. error: %s", inst.HostName, err))
+			continue
+		}
+		delete(s.mirrored, id)
+	}
+
+	if len(problems) > 0 {
+		return errors.New(strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+// registeredMirrors returns the instances of the registry mirrored from the catalog, by service ID.
+func (s *syncer) registeredMirrors() (map[string]*eureka.Instance, error) {
+	apps, err := s.source.GetApplications()
+	if err != nil {
+		return nil, fmt.Errorf("Failed to get applications. error: %s", err)
+	}
+	mirrors := map[string]*eureka.Instance{}
+	for _, app := range apps {
+		for _, inst := range app.Instances {
+			if metadataValue(inst.Metadata, SourceKey) == SourceConsul {
+				mirrors[inst.HostName] = inst
+			}
+		}
+	}
+	return mirrors, nil
+}
+
+// renew sends a heartbeat for a mirrored instance, and registers it again when its lease expired.
+func (s *syncer) renew(inst *eureka.Instance) error {
+	if err := s.registrator.Heartbeat(inst); err != nil {
+		if err = s.registrator.Register(inst); err != nil {
+			return fmt.Errorf("Failed to register instance %s. error: %s", inst.HostName, err)
+		}
+	}
+	return nil
+}
+
+// catalogService translates an eureka instance into a catalog service. Instances without an IP address
+// are not translated.
+func catalogService(inst *eureka.Instance) (*CatalogService, bool) {
+	id := inst.ID
+	if id == "" {
+		id = inst.HostName
+	}
+	if id == "" || inst.IPAddr == "" {
+		return nil, false
+	}
+	name := inst.VIPAddr
+	if name == "" {
+		name = inst.Application
+	}
+
+	meta := map[string]string{}
+	var metadata map[string]interface{}
+	if len(inst.Metadata) > 0 && json.Unmarshal(inst.Metadata, &metadata) == nil {
+		for key, value := range metadata {
+			meta[key] = fmt.Sprintf("%v", value)
+		}
+	}
+	meta[SourceKey] = SourceEureka
+	meta[AppKey] = inst.Application
+	meta[TimestampKey] = strconv.FormatInt(timestamp(inst.LastDirtyTs), 10)
+	port, _ := inst.ServicePort()
+
+	return &CatalogService{
+		ID:      id,
+		Name:    strings.ToLower(name),
+		Address: inst.IPAddr,
+		Port:    port,
+		Tags:    []string{SyncTag},
+		Meta:    meta,
+		Health:  health(inst.EffectiveStatus()),
+	}, true
+}
+
+// eurekaInstance translates a catalog service into an eureka instance, whose host name is the service ID.
+func eurekaInstance(svc *CatalogService) (*eureka.Instance, error) {
+	builder := eureka.NewInstanceBuilder(strings.ToUpper(svc.Name)).
+		HostName(svc.ID).
+		IPAddr(svc.Address).
+		VIPAddress(svc.Name).
+		Status(status(svc.Health)).
+		Port(svc.Port)
+	for key, value := range svc.Meta {
+		builder.Metadata(key, value)
+	}
+	builder.Metadata(SourceKey, SourceConsul).
+		Metadata(TimestampKey, strconv.FormatInt(svc.LastModified, 10))
+
+	inst, err := builder.Build()
+	if err != nil {
+		return nil, err
+	}
+	inst.LastDirtyTs = strconv.FormatInt(svc.LastModified, 10)
+	return inst, nil
+}
+
+func health(status eureka.StatusType) string {
+	switch status {
+	case eureka.UP:
+		return HealthPassing
+	case eureka.STARTING:
+		return HealthWarning
+	case eureka.OUTOFSERVICE:
+		return HealthMaintenance
+	default:
+		return HealthCritical
+	}
+}
+
+func status(health string) eureka.StatusType {
+	switch health {
+	case HealthPassing, HealthWarning:
+		return eureka.UP
+	case HealthMaintenance:
+		return eureka.OUTOFSERVICE
+	default:
+		return eureka.DOWN
+	}
+}
+
+// sameInstance compares the fields of two instances which are mirrored from a catalog service.
+func sameInstance(a, b *eureka.Instance) bool {
+	portA, _ := a.ServicePort()
+	portB, _ := b.ServicePort()
+	var metadataA, metadataB map[string]interface{}
+	json.Unmarshal(a.Metadata, &metadataA)
+	json.Unmarshal(b.Metadata, &metadataB)
+	return a.HostName == b.HostName && a.IPAddr == b.IPAddr && a.VIPAddr == b.VIPAddr && portA == portB &&
+		a.EffectiveStatus() == b.EffectiveStatus() && reflect.DeepEqual(metadataA, metadataB)
+}
+
+// sameService compares two services, ignoring their modification time.
+func sameService(a, b *CatalogService) bool {
+	copyA, copyB := *a, *b
+	copyA.LastModified, copyB.LastModified = 0, 0
+	return reflect.DeepEqual(copyA, copyB)
+}
+
+func hasTag(tags []string, tag string) bool {
+	for _, t := range tags {
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
+
+func metadataValue(raw json.RawMessage, key string) string {
+	var metadata map[string]interface{}
+	if len(raw) == 0 || json.Unmarshal(raw, &metadata) != nil {
+		return ""
+	}
+	if value, ok := metadata[key]; ok {
+		return fmt.Sprintf("%v", value)
+	}
+	return ""
+}
+
+// timestamp converts a timestamp in milliseconds, as set by the client or unmarshaled from JSON, into an int64.
+func timestamp(value interface{}) int64 {
+	switch v := value.(type) {
+	case int64:
+		return v
+	case int:
+		return int64(v)
+	case float64:
+		return int64(v)
+	case json.Number:
+		ts, _ := v.Int64()
+		return ts
+	case string:
+		ts, _ := strconv.ParseInt(v, 10, 64)
+		return ts
+	}
+	return 0
+}
@@ -0,0 +1,240 @@
+// Copyright 2016 IBM Corporation
+//
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+//
+//       http://www.apache.org/licenses/LICENSE-2.0
+//
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+
+package consulsync
+
+import (
+	"errors"
+	"sync"
+	"testing"
+
+	eureka "github.com/amalgam8/go-eureka-client"
+)
+
+type fakeSource struct {
+	sync.Mutex
+	apps []*eureka.Application
+}
+
+func (f *fakeSource) GetApplications() ([]*eureka.Application, error) {
+	f.Lock()
+	defer f.Unlock()
+	return f.apps, nil
+}
+
+func (f *fakeSource) set(insts ...*eureka.Instance) {
+	f.Lock()
+	defer f.Unlock()
+	f.apps = nil
+	for _, inst := range insts {
+		f.apps = append(f.apps, &eureka.Application{Name: inst.Application, Instances: []*eureka.Instance{inst}})
+	}
+}
+
+// fakeRegistrator records the instances registered in eureka.
+type fakeRegistrator struct {
+	eureka.Registrator
+	sync.Mutex
+	instances  map[string]*eureka.Instance
+	heartbeats int
+	expired    bool
+}
+
+func (r *fakeRegistrator) Register(inst *eureka.Instance) error {
+	r.Lock()
+	defer r.Unlock()
+	r.instances[inst.HostName] = inst
+	r.expired = false
+	return nil
+}
+
+func (r *fakeRegistrator) Deregister(inst *eureka.Instance) error {
+	r.Lock()
+	defer r.Unlock()
+	delete(r.instances, inst.HostName)
+	return nil
+}
+
+func (r *fakeRegistrator) Heartbeat(inst *eureka.Instance) error {
+	r.Lock()
+	defer r.Unlock()
+	r.heartbeats++
+	if r.expired {
+		return errors.New("instance not found")
+	}
+	return nil
+}
+
+func testInstance(host, ip string, status eureka.StatusType, dirtyTs string) *eureka.Instance {
+	return &eureka.Instance{
+		ID:          host,
+		HostName:    host,
+		Application: "APP1",
+		IPAddr:      ip,
+		VIPAddr:     "app1",
+		Status:      string(status),
+		Port:        &eureka.Port{Enabled: "true", Value: float64(8080)},
+		Metadata:    []byte(`{"version":"2"}`),
+		LastDirtyTs: dirtyTs,
+	}
+}
+
+func newTestSyncer(t *testing.T) (*syncer, *fakeSource, *fakeRegistrator, *FakeCatalog) {
+	source := &fakeSource{}
+	registrator := &fakeRegistrator{instances: map[string]*eureka.Instance{}}
+	catalog := NewFakeCatalog()
+	s, err := NewSyncer(source, nil, registrator, catalog, Config{})
+	if err != nil {
+		t.Fatalf("error = %v", err)
+	}
+	return s.(*syncer), source, registrator, catalog
+}
+
+func TestSyncEurekaToCatalog(t *testing.T) {
+	s, source, _, catalog := newTestSyncer(t)
+
+	source.set(testInstance("inst1", "10.0.0.1", eureka.UP, "1000"))
+	if err := s.Sync(); err != nil {
+		t.Fatalf("error = %v", err)
+	}
+	svc := catalog.Service("inst1")
+	if svc == nil {
+		t.Fatal("instance should be mirrored into the catalog")
+	}
+	if svc.Name != "app1" || svc.Address != "10.0.0.1" || svc.Port != 8080 || svc.Health != HealthPassing ||
+		!hasTag(svc.Tags, SyncTag) || svc.Meta[SourceKey] != SourceEureka || svc.Meta["version"] != "2" ||
+		svc.Meta[TimestampKey] != "1000" {
+		t.Errorf("unexpected service %+v", svc)
+	}
+
+	// A status change with a newer dirty timestamp replaces the mirrored service
+	source.set(testInstance("inst1", "10.0.0.1", eureka.DOWN, "2000"))
+	s.Sync()
+	if svc = catalog.Service("inst1"); svc.Health != HealthCritical {
+		t.Errorf("health = %s, expected the newer instance to replace the service", svc.Health)
+	}
+
+	// A stale instance does not replace a service mirrored from a newer version
+	source.set(testInstance("inst1", "10.0.0.1", eureka.UP, "1500"))
+	s.Sync()
+	if svc = catalog.Service("inst1"); svc.Health != HealthCritical {
+		t.Errorf("health = %s, expected the last writer to win", svc.Health)
+	}
+
+	// A status override keeps the dirty timestamp, and still replaces the mirrored service
+	source.set(testInstance("inst1", "10.0.0.1", eureka.OUTOFSERVICE, "2000"))
+	s.Sync()
+	if svc = catalog.Service("inst1"); svc.Health != HealthMaintenance {
+		t.Errorf("health = %s, expected a status change with the same timestamp to be mirrored", svc.Health)
+	}
+	source.set(testInstance("inst1", "10.0.0.1", eureka.DOWN, "2000"))
+	s.Sync()
+
+	// A native catalog service with the same ID is left alone
+	catalog.Register(&CatalogService{ID: "inst2", Name: "native", Address: "10.0.0.9", Port: 80})
+	source.set(testInstance("inst1", "10.0.0.1", eureka.DOWN, "2000"), testInstance("inst2", "10.0.0.2", eureka.UP, "3000"))
+	s.syncToCatalog()
+	if svc = catalog.Service("inst2"); svc.Name != "native" {
+		t.Errorf("native service should not be replaced, got %+v", svc)
+	}
+
+	source.set()
+	s.syncToCatalog()
+	if catalog.Service("inst1") != nil {
+		t.Error("service should be removed with its instance")
+	}
+	if catalog.Service("inst2") == nil {
+		t.Error("native service should not be removed")
+	}
+}
+
+func TestSyncCatalogToEureka(t *testing.T) {
+	s, source, registrator, catalog := newTestSyncer(t)
+
+	catalog.Register(&CatalogService{ID: "web-1", Name: "web", Address: "10.0.1.1", Port: 9090,
+		Meta: map[string]string{"version": "3"}, Health: HealthPassing, LastModified: 1000})
+	if err := s.Sync(); err != nil {
+		t.Fatalf("error = %v", err)
+	}
+	inst := registrator.instances["web-1"]
+	if inst == nil {
+		t.Fatal("service should be registered in eureka")
+	}
+	if inst.Application != "WEB" || inst.VIPAddr != "web" || inst.IPAddr != "10.0.1.1" || inst.Status != string(eureka.UP) ||
+		metadataValue(inst.Metadata, SourceKey) != SourceConsul || metadataValue(inst.Metadata, "version") != "3" {
+		t.Errorf("unexpected instance %+v", inst)
+	}
+
+	// The mirrored instance, once fetched from eureka, is not mirrored back into the catalog
+	source.set(inst)
+	s.Sync()
+	if services, _ := catalog.Services(); len(services) != 1 {
+		t.Errorf("expected only the native service in the catalog, got %d services", len(services))
+	}
+
+	// An unchanged service only renews the lease, and an expired lease registers the instance again
+	registrator.instances = map[string]*eureka.Instance{}
+	registrator.expired = true
+	s.syncToEureka()
+	if registrator.heartbeats != 2 || registrator.instances["web-1"] == nil {
+		t.Errorf("heartbeats = %d, expected the expired instance to be registered again", registrator.heartbeats)
+	}
+
+	// A newer service replaces the instance, but a stale one does not
+	catalog.Register(&CatalogService{ID: "web-1", Name: "web", Address: "10.0.1.1", Port: 9090,
+		Health: HealthCritical, LastModified: 2000})
+	s.syncToEureka()
+	if inst = registrator.instances["web-1"]; inst.Status != string(eureka.DOWN) {
+		t.Errorf("status = %s, expected the newer service to replace the instance", inst.Status)
+	}
+	catalog.Register(&CatalogService{ID: "web-1", Name: "web", Address: "10.0.1.1", Port: 9090,
+		Health: HealthPassing, LastModified: 1500})
+	s.syncToEureka()
+	if inst = registrator.instances["web-1"]; inst.Status != string(eureka.DOWN) {
+		t.Errorf("status = %s, expected the last writer to win", inst.Status)
+	}
+	catalog.Register(&CatalogService{ID: "web-1", Name: "web", Address: "10.0.1.1", Port: 9090,
+		Health: HealthMaintenance, LastModified: 2000})
+	s.syncToEureka()
+	if inst = registrator.instances["web-1"]; inst.Status != string(eureka.OUTOFSERVICE) {
+		t.Errorf("status = %s, expected a service changed with the same timestamp to replace the instance", inst.Status)
+	}
+
+	catalog.Deregister("web-1")
+	s.syncToEureka()
+	if len(registrator.instances) != 0 {
+		t.Error("instance should be deregistered with its service")
+	}
+}
+
+func TestSyncOnEvents(t *testing.T) {
+	events := eureka.NewEventBroadcaster()
+	s, err := NewSyncer(&fakeSource{}, events, &fakeRegistrator{}, NewFakeCatalog(), Config{})
+	if err != nil {
+		t.Fatalf("error = %v", err)
+	}
+	changed := s.(*syncer).changed
+
+	mirrored := testInstance("web-1", "10.0.1.1", eureka.UP, "1000")
+	mirrored.Metadata = []byte(`{"sync-source":"consul"}`)
+	events.OnAdd(mirrored)
+	if len(changed) != 0 {
+		t.Error("events of instances mirrored from the catalog should be ignored")
+	}
+
+	events.OnAdd(testInstance("inst1", "10.0.0.1", eureka.UP, "1000"))
+	if len(changed) != 1 {
+		t.Error("events of eureka instances should trigger a synchronization")
+	}
+}
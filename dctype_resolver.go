@@ -19,6 +19,7 @@ import (
 	"errors"
 	"fmt"
 	"strings"
+	"sync"
 )
 
 // UniqueIdentifier indicates the unique ID of the datacenter information
@@ -26,8 +27,71 @@ type UniqueIdentifier interface {
 	GetID(dcinfo *DatacenterInfo) string
 }
 
+// DatacenterInfoProvider provides the datacenter information of the host the process runs on.
+type DatacenterInfoProvider interface {
+	DatacenterInfo() (*DatacenterInfo, error)
+}
+
+// UnknownDatacenterPolicy defines how the ID of instances of unregistered datacenters is resolved.
+type UnknownDatacenterPolicy int
+
+const (
+	// RejectUnknownDatacenter fails resolving the ID of instances of unregistered datacenters.
+	RejectUnknownDatacenter UnknownDatacenterPolicy = iota
+	// HostNameForUnknownDatacenter identifies instances of unregistered datacenters by their host name.
+	HostNameForUnknownDatacenter
+)
+
 var amzInfo amazonInfo
 var slInfo softlayerInfo
+var gcpInfo gcpIdentifier
+var azInfo azureIdentifier
+var k8sInfo kubernetesIdentifier
+
+var datacentersLock sync.RWMutex
+var datacenterPolicy = RejectUnknownDatacenter
+var datacenters = map[string]UniqueIdentifier{
+	"myown":      hostNameIdentifier{},
+	"netflix":    hostNameIdentifier{},
+	"amazon":     &amzInfo,
+	"softlayer":  &slInfo,
+	"gcp":        &gcpInfo,
+	"azure":      &azInfo,
+	"kubernetes": &k8sInfo,
+}
+
+// hostNameIdentifier identifies instances by their host name.
+type hostNameIdentifier struct{}
+
+// Returns no unique identifier, thus the host name is used
+func (hostNameIdentifier) GetID(dcinfo *DatacenterInfo) string {
+	return ""
+}
+
+// RegisterDatacenter registers the identifier used to resolve the ID of instances of the named datacenter.
+// Datacenter names are case insensitive, and registering an existing name replaces its identifier.
+// When the identifier returns an empty ID, the host name of the instance is used.
+func RegisterDatacenter(name string, identifier UniqueIdentifier) error {
+	if name == "" {
+		return errors.New("datacenter name must be defined")
+	}
+	if identifier == nil {
+		return fmt.Errorf("identifier of datacenter %s must be defined", name)
+	}
+
+	datacentersLock.Lock()
+	defer datacentersLock.Unlock()
+	datacenters[strings.ToLower(name)] = identifier
+	return nil
+}
+
+// SetUnknownDatacenterPolicy sets how the ID of instances of unregistered datacenters is resolved.
+// The default policy is RejectUnknownDatacenter.
+func SetUnknownDatacenterPolicy(policy UnknownDatacenterPolicy) {
+	datacentersLock.Lock()
+	defer datacentersLock.Unlock()
+	datacenterPolicy = policy
+}
 
 func resolveInstanceID(inst *Instance) (string, error) {
 	var id string
@@ -42,21 +106,21 @@ func resolveInstanceID(inst *Instance) (string, error) {
 		return id, nil
 	}
 
-	name := strings.ToLower(inst.Datacenter.Name)
+	datacentersLock.RLock()
+	identifier, ok := datacenters[strings.ToLower(inst.Datacenter.Name)]
+	policy := datacenterPolicy
+	datacentersLock.RUnlock()
 
-	switch name {
-	case "myown", "netflix":
-	case "amazon":
-		if uid := amzInfo.GetID(inst.Datacenter); uid != "" {
-			id = uid
-		}
-	case "softlayer":
-		if uid := slInfo.GetID(inst.Datacenter); uid != "" {
-			id = uid
+	if !ok {
+		if policy == HostNameForUnknownDatacenter {
+			return id, nil
 		}
-	default:
 		return "", fmt.Errorf("unknown datacenter name [%s]", inst.Datacenter.Name)
 	}
 
+	if uid := identifier.GetID(inst.Datacenter); uid != "" {
+		id = uid
+	}
+
 	return id, nil
 }
@@ -0,0 +1,85 @@
+// Copyright 2016 IBM Corporation
+//
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+//
+//       http://www.apache.org/licenses/LICENSE-2.0
+//
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+
+//Package goEurekaClient Implements a go client that interacts with a eureka server
+package goEurekaClient
+
+import (
+	"testing"
+)
+
+type rackIdentifier struct{}
+
+func (rackIdentifier) GetID(dcinfo *DatacenterInfo) string {
+	return metadataString(dcinfo, "rack") + "-" + metadataString(dcinfo, "slot")
+}
+
+func TestResolveInstanceIDBuiltinDatacenters(t *testing.T) {
+	cases := []struct {
+		dcinfo *DatacenterInfo
+		id     string
+	}{
+		{nil, "host1"},
+		{&DatacenterInfo{Name: "MyOwn"}, "host1"},
+		{&DatacenterInfo{Name: "Amazon", Metadata: DatacenterMetadata{amazonInstanceID: "i-1234"}}, "i-1234"},
+		{&DatacenterInfo{Name: "Amazon"}, "host1"},
+		{&DatacenterInfo{Name: "SoftLayer", Metadata: DatacenterMetadata{softlayerInstanceID: float64(42)}}, "42"},
+		{&DatacenterInfo{Name: "GCP", Metadata: DatacenterMetadata{gcpInstanceID: "987"}}, "987"},
+		{&DatacenterInfo{Name: "Azure", Metadata: DatacenterMetadata{azureVMID: "vm-1"}}, "vm-1"},
+		{&DatacenterInfo{Name: "Kubernetes", Metadata: DatacenterMetadata{kubernetesPodUID: "uid-1"}}, "uid-1"},
+	}
+
+	for _, c := range cases {
+		id, err := resolveInstanceID(&Instance{HostName: "host1", Datacenter: c.dcinfo})
+		if err != nil {
+			t.Errorf("Failed to resolve instance ID of %v. error: %v", c.dcinfo, err)
+		} else if id != c.id {
+			t.Errorf("instance ID of %v should be %s, got %s", c.dcinfo, c.id, id)
+		}
+	}
+}
+
+func TestRegisterDatacenter(t *testing.T) {
+	inst := &Instance{HostName: "host1", Datacenter: &DatacenterInfo{Name: "OnPrem",
+		Metadata: DatacenterMetadata{"rack": "r1", "slot": "s2"}}}
+
+	if _, err := resolveInstanceID(inst); err == nil {
+		t.Error("unknown datacenter should be rejected by default")
+	}
+
+	SetUnknownDatacenterPolicy(HostNameForUnknownDatacenter)
+	defer SetUnknownDatacenterPolicy(RejectUnknownDatacenter)
+	if id, err := resolveInstanceID(inst); err != nil || id != "host1" {
+		t.Errorf("unknown datacenter should fall back to the host name, got %s (%v)", id, err)
+	}
+
+	if err := RegisterDatacenter("onprem", rackIdentifier{}); err != nil {
+		t.Fatalf("error = %v", err)
+	}
+	defer func() {
+		datacentersLock.Lock()
+		delete(datacenters, "onprem")
+		datacentersLock.Unlock()
+	}()
+	if id, err := resolveInstanceID(inst); err != nil || id != "r1-s2" {
+		t.Errorf("instance ID should be resolved by the registered identifier, got %s (%v)", id, err)
+	}
+
+	if err := RegisterDatacenter("", rackIdentifier{}); err == nil {
+		t.Error("empty datacenter name should be rejected")
+	}
+	if err := RegisterDatacenter("onprem", nil); err == nil {
+		t.Error("nil identifier should be rejected")
+	}
+}
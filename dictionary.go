@@ -26,6 +26,7 @@ type Dictionary interface {
 	getApplications() []*Application
 	GetInstancesByVip(vipAddress string) []*Instance
 	GetInstancesBySecVip(svipAddress string) []*Instance
+	GetInstancesByGroup(groupName string) []*Instance
 	isEmpty() bool
 	copyDictionary() dictionary
 }
@@ -34,11 +35,12 @@ type dictionary struct {
 	appNameIndex instanceMap
 	vipIndex     instanceMap
 	svipIndex    instanceMap
+	groupIndex   instanceMap
 }
 
 func newDictionary() dictionary {
 	return dictionary{appNameIndex: instanceMap{}, vipIndex: instanceMap{},
-		svipIndex: instanceMap{}}
+		svipIndex: instanceMap{}, groupIndex: instanceMap{}}
 }
 
 func (d *dictionary) Delete(inst *Instance, id string, app *Application) {
@@ -60,6 +62,12 @@ func (d *dictionary) Delete(inst *Instance, id string, app *Application) {
 			delete(d.appNameIndex, app.Name)
 		}
 	}
+	if groupInsts, ok := d.groupIndex[inst.GroupName]; ok {
+		delete(groupInsts, id)
+		if len(groupInsts) == 0 {
+			delete(d.groupIndex, inst.GroupName)
+		}
+	}
 }
 
 func (d *dictionary) Add(inst *Instance, id string, app *Application) {
@@ -93,6 +101,13 @@ func (d *dictionary) Add(inst *Instance, id string, app *Application) {
 		}
 	}
 
+	if inst.GroupName != "" {
+		if d.groupIndex[inst.GroupName] == nil {
+			d.groupIndex[inst.GroupName] = map[string]*Instance{}
+		}
+		d.groupIndex[inst.GroupName][inst.ID] = inst
+	}
+
 }
 
 func (d *dictionary) Update(inst *Instance, id string, app *Application) {
@@ -124,6 +139,13 @@ func (d *dictionary) Update(inst *Instance, id string, app *Application) {
 			d.appNameIndex[app.Name][inst.ID] = inst
 		}
 	}
+
+	if inst.GroupName != "" {
+		if d.groupIndex[inst.GroupName] == nil {
+			d.groupIndex[inst.GroupName] = map[string]*Instance{}
+		}
+		d.groupIndex[inst.GroupName][inst.ID] = inst
+	}
 }
 
 func (d *dictionary) getApplication(appName string) *Application {
@@ -183,8 +205,43 @@ func (d *dictionary) GetInstancesBySecVip(svipAddress string) []*Instance {
 	return instancesArray
 }
 
+func (d *dictionary) GetInstancesByGroup(groupName string) []*Instance {
+	instancesMap, ok := d.groupIndex[groupName]
+	if !ok {
+		return nil
+	}
+	var instancesArray []*Instance
+	for _, v := range instancesMap {
+		dc := v.deepCopy()
+		instancesArray = append(instancesArray, &dc)
+	}
+	return instancesArray
+}
+
+// getApplicationsByVip returns the applications backing the given vipAddress,
+// each holding only its instances which are registered with that vip.
+func (d *dictionary) getApplicationsByVip(vipAddress string) []*Application {
+	instancesMap, ok := d.vipIndex[vipAddress]
+	if !ok {
+		return nil
+	}
+	appsMap := map[string]*Application{}
+	var applicationsArray []*Application
+	for _, v := range instancesMap {
+		app, ok := appsMap[v.Application]
+		if !ok {
+			app = &Application{Name: v.Application}
+			appsMap[v.Application] = app
+			applicationsArray = append(applicationsArray, app)
+		}
+		dc := v.deepCopy()
+		app.Instances = append(app.Instances, &dc)
+	}
+	return applicationsArray
+}
+
 func (d *dictionary) isEmpty() bool {
-	if len(d.appNameIndex) == 0 && len(d.svipIndex) == 0 && len(d.vipIndex) == 0 {
+	if len(d.appNameIndex) == 0 && len(d.svipIndex) == 0 && len(d.vipIndex) == 0 && len(d.groupIndex) == 0 {
 		return true
 	}
 	return false
@@ -192,7 +249,8 @@ func (d *dictionary) isEmpty() bool {
 }
 
 func (d *dictionary) copyDictionary() *dictionary {
-	dict := dictionary{appNameIndex: map[string]map[string]*Instance{}, svipIndex: map[string]map[string]*Instance{}, vipIndex: map[string]map[string]*Instance{}}
+	dict := dictionary{appNameIndex: map[string]map[string]*Instance{}, svipIndex: map[string]map[string]*Instance{},
+		vipIndex: map[string]map[string]*Instance{}, groupIndex: map[string]map[string]*Instance{}}
 	for appIndex, insts := range d.appNameIndex {
 		copyInsts := map[string]*Instance{}
 		for instName, inst := range insts {
@@ -216,6 +274,37 @@ func (d *dictionary) copyDictionary() *dictionary {
 		}
 		dict.svipIndex[svipIndex] = copyInsts
 	}
+
+	for groupIndex, insts := range d.groupIndex {
+		copyInsts := map[string]*Instance{}
+		for instName, inst := range insts {
+			copyInsts[instName] = inst
+		}
+		dict.groupIndex[groupIndex] = copyInsts
+	}
 	return &dict
 
 }
+
+func (d *dictionary) getInstancesByVipInRegions(vipAddress string, regions []string) []*Instance {
+	return instancesInRegions(d.vipIndex[vipAddress], regions)
+}
+
+func (d *dictionary) getInstancesBySecVipInRegions(svipAddress string, regions []string) []*Instance {
+	return instancesInRegions(d.svipIndex[svipAddress], regions)
+}
+
+// instancesInRegions returns copies of the instances which belong to one of the given regions.
+func instancesInRegions(instancesMap map[string]*Instance, regions []string) []*Instance {
+	var instancesArray []*Instance
+	for _, v := range instancesMap {
+		for _, region := range regions {
+			if v.Region == region {
+				dc := v.deepCopy()
+				instancesArray = append(instancesArray, &dc)
+				break
+			}
+		}
+	}
+	return instancesArray
+}
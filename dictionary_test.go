@@ -291,3 +291,62 @@ func TestCopyDictionary(t *testing.T) {
 		t.Errorf("should shallow copy the instance... ")
 	}
 }
+
+func TestGetInstancesByGroup(t *testing.T) {
+	dict := newDictionary()
+	app1 := &Application{Name: "APP1"}
+	app2 := &Application{Name: "APP2"}
+	dict.Add(&Instance{ID: "inst1", Application: "APP1", GroupName: "GROUP1", VIPAddr: "vip1"}, "inst1", app1)
+	dict.Add(&Instance{ID: "inst2", Application: "APP2", GroupName: "GROUP1", VIPAddr: "vip1"}, "inst2", app2)
+	dict.Add(&Instance{ID: "inst3", Application: "APP2", GroupName: "GROUP2", VIPAddr: "vip2"}, "inst3", app2)
+
+	if insts := dict.GetInstancesByGroup("GROUP3"); insts != nil {
+		t.Errorf("should return nil")
+	}
+	if insts := dict.GetInstancesByGroup("GROUP1"); len(insts) != 2 {
+		t.Errorf("should contain 2 instances, %d", len(insts))
+	}
+
+	dict.Delete(dict.groupIndex["GROUP2"]["inst3"], "inst3", app2)
+	if _, ok := dict.groupIndex["GROUP2"]; ok {
+		t.Error("GROUP2 wasn't deleted from groupIndex")
+	}
+
+	copyDict := dict.copyDictionary()
+	if len(copyDict.groupIndex["GROUP1"]) != 2 {
+		t.Errorf("Length of copy dict group index = %d", len(copyDict.groupIndex["GROUP1"]))
+	}
+}
+
+func TestGetApplicationsByVip(t *testing.T) {
+	dict := newDictionary()
+	app1 := &Application{Name: "APP1"}
+	app2 := &Application{Name: "APP2"}
+	dict.Add(&Instance{ID: "inst1", Application: "APP1", VIPAddr: "vip1"}, "inst1", app1)
+	dict.Add(&Instance{ID: "inst2", Application: "APP1", VIPAddr: "vip1"}, "inst2", app1)
+	dict.Add(&Instance{ID: "inst3", Application: "APP2", VIPAddr: "vip1"}, "inst3", app2)
+	dict.Add(&Instance{ID: "inst4", Application: "APP2", VIPAddr: "vip2"}, "inst4", app2)
+
+	if apps := dict.getApplicationsByVip("vip3"); apps != nil {
+		t.Errorf("should return nil")
+	}
+
+	apps := dict.getApplicationsByVip("vip1")
+	if len(apps) != 2 {
+		t.Fatalf("Unexpected number of apps, %d", len(apps))
+	}
+	for _, app := range apps {
+		switch app.Name {
+		case "APP1":
+			if len(app.Instances) != 2 {
+				t.Errorf("app1 should contain 2 instances, %d", len(app.Instances))
+			}
+		case "APP2":
+			if len(app.Instances) != 1 {
+				t.Errorf("app2 should contain 1 instance, %d", len(app.Instances))
+			}
+		default:
+			t.Errorf("Unexpected appName %s", app.Name)
+		}
+	}
+}
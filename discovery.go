@@ -18,6 +18,7 @@ package goEurekaClient
 import (
 	"fmt"
 	"log"
+	"net/http"
 )
 
 // Discovery defines the discovery interface and actions :
@@ -27,6 +28,12 @@ type Discovery interface {
 	GetInstance(appID, id string) (*Instance, error)
 	GetInstancesByVip(vipAddress string) ([]*Instance, error)
 	GetInstancesBySecVip(secVipAddress string) ([]*Instance, error)
+	GetInstancesByGroup(groupName string) ([]*Instance, error)
+	GetApplicationsByVip(vipAddress string) ([]*Application, error)
+	GetApplicationInstancesByStatus(appName string, statuses ...StatusType) ([]*Instance, error)
+	GetInstancesByVipAndStatus(vipAddress string, statuses ...StatusType) ([]*Instance, error)
+	GetInstancesBySecVipAndStatus(secVipAddress string, statuses ...StatusType) ([]*Instance, error)
+	ConfigUpdater
 }
 
 type discovery struct {
@@ -45,6 +52,11 @@ func NewDiscovery(config *Config, handler InstanceEventHandler) (Discovery, erro
 	return newDiscovery, nil
 }
 
+// UpdateConfig replaces the server list and HTTP client settings used by the following requests.
+func (r *discovery) UpdateConfig(config *Config) error {
+	return r.client.updateConfig(config)
+}
+
 // GetApplication returns an application instance from the registry with the appName specified as argument.
 // If more the one application instance with the same name exists, it will return the first one found.
 func (r *discovery) GetApplication(appName string) (*Application, error) {
@@ -103,3 +115,78 @@ func (r *discovery) GetInstancesBySecVip(secVipAddress string) ([]*Instance, err
 	return insts, nil
 
 }
+
+// GetInstancesByGroup returns from the registry all the instances which belong to the given application group.
+// The server does not index instances by group, thus the whole registry is fetched and filtered.
+// Like the discovery cache, it returns a not found error when the group has no instances.
+func (r *discovery) GetInstancesByGroup(groupName string) ([]*Instance, error) {
+	apps, e := r.client.fetchApps("apps/")
+	if e != nil {
+		return nil, e
+	}
+
+	var insts []*Instance
+	if apps != nil {
+		for _, app := range apps.Application {
+			for _, inst := range app.Instances {
+				if inst.GroupName == groupName {
+					insts = append(insts, inst)
+				}
+			}
+		}
+	}
+	if insts == nil {
+		return nil, notFoundErrorf("group %s not found", groupName)
+	}
+	return insts, nil
+}
+
+// GetApplicationsByVip returns from the registry the applications backing the given vipAddress.
+// Like the discovery cache, it returns a not found error when the vipAddress has no applications.
+func (r *discovery) GetApplicationsByVip(vipAddress string) ([]*Application, error) {
+	apps, e := r.client.fetchApps("vips/" + vipAddress)
+	if statusErr, ok := e.(*statusError); ok && statusErr.code == http.StatusNotFound {
+		apps, e = nil, nil
+	}
+	if e != nil {
+		return nil, e
+	}
+	if apps == nil || len(apps.Application) == 0 {
+		return nil, notFoundErrorf("vipAddress  %s not found", vipAddress)
+	}
+
+	return apps.Application, nil
+}
+
+// GetApplicationInstancesByStatus returns from the registry the instances of the application whose effective
+// status is one of the given statuses. If no status is given, only UP instances are returned.
+func (r *discovery) GetApplicationInstancesByStatus(appName string, statuses ...StatusType) ([]*Instance, error) {
+	app, e := r.GetApplication(appName)
+	if e != nil {
+		return nil, e
+	}
+
+	return filterByStatus(app.Instances, statuses), nil
+}
+
+// GetInstancesByVipAndStatus returns from the registry the instances with the given vipAddress whose effective
+// status is one of the given statuses. If no status is given, only UP instances are returned.
+func (r *discovery) GetInstancesByVipAndStatus(vipAddress string, statuses ...StatusType) ([]*Instance, error) {
+	insts, e := r.GetInstancesByVip(vipAddress)
+	if e != nil {
+		return nil, e
+	}
+
+	return filterByStatus(insts, statuses), nil
+}
+
+// GetInstancesBySecVipAndStatus returns from the registry the instances with the given secured vip address whose
+// effective status is one of the given statuses. If no status is given, only UP instances are returned.
+func (r *discovery) GetInstancesBySecVipAndStatus(secVipAddress string, statuses ...StatusType) ([]*Instance, error) {
+	insts, e := r.GetInstancesBySecVip(secVipAddress)
+	if e != nil {
+		return nil, e
+	}
+
+	return filterByStatus(insts, statuses), nil
+}
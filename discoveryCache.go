@@ -25,17 +25,51 @@ import (
 type DiscoveryCache interface {
 	Discovery
 	Run(stopCh context.Context)
+	GetInstancesByVipInRegions(vipAddress string, regions ...string) ([]*Instance, error)
+	GetInstancesBySecVipInRegions(secVipAddress string, regions ...string) ([]*Instance, error)
+	SelectInstancesByVip(vipAddress string) ([]*Instance, error)
+	SelectInstancesBySecVip(secVipAddress string) ([]*Instance, error)
+}
+
+const defaultPollInterval = 30 * time.Second
+
+// notFoundError reports a lookup of a name which the registry does not hold.
+type notFoundError struct {
+	message string
+}
+
+func (e *notFoundError) Error() string {
+	return e.message
+}
+
+func notFoundErrorf(format string, args ...interface{}) error {
+	return &notFoundError{message: fmt.Sprintf(format, args...)}
+}
+
+// IsNotFound returns whether the error reports a lookup of an application, instance, group or VIP address
+// which the registry does not hold.
+func IsNotFound(err error) bool {
+	_, ok := err.(*notFoundError)
+	return ok
 }
 
 type discoveryCache struct {
 	client       *client
 	pollInterval time.Duration
+	targets      []RefreshTarget // refreshed instead of the whole registry when set
 }
 
 // NewDiscoveryCache creates a new client used for instances discovery with internal cache.
-// pollInterval defines the polling interval.
+// pollInterval defines the polling interval. When it is not positive, the poll interval of the config is used,
+// and 30s when neither is set.
 // handler is used to get notification on instances. nil indicates that no notifications are needed.
 func NewDiscoveryCache(config *Config, pollInterval time.Duration, handler InstanceEventHandler) (DiscoveryCache, error) {
+	if pollInterval <= 0 && config != nil {
+		pollInterval = config.PollInterval
+	}
+	if pollInterval <= 0 {
+		pollInterval = defaultPollInterval
+	}
 
 	discoveryCacheClient, err := newClient(config, handler)
 	if err != nil {
@@ -51,48 +85,186 @@ func NewDiscoveryCache(config *Config, pollInterval time.Duration, handler Insta
 
 // Run start running the cache.
 func (d *discoveryCache) Run(stopCh context.Context) {
+	if len(d.targets) > 0 {
+		go d.client.runTargets(d.targets, stopCh)
+		return
+	}
 	go d.client.run(d.pollInterval, stopCh)
 }
 
+// Filled returns whether the registry was fetched at least once. Until then, every lookup reports a not found error.
+func (d *discoveryCache) Filled() bool {
+	d.client.Lock()
+	defer d.client.Unlock()
+	return d.client.filled
+}
+
+// UpdateConfig replaces the server list, the HTTP client settings and, when the config sets it, the poll interval.
+// The cache contents are preserved. A targeted cache keeps the intervals of its targets: the poll interval
+// of the config only provides their default when the cache is created.
+func (d *discoveryCache) UpdateConfig(config *Config) error {
+	return d.client.updateConfig(config)
+}
+
 // GetApplication returns an application instance from the cache with the appName specified as argument.
 func (d *discoveryCache) GetApplication(appName string) (*Application, error) {
-
+	d.client.Lock()
 	app := d.client.dictionary.getApplication(appName)
+	d.client.Unlock()
 	if app == nil {
-		return nil, fmt.Errorf("Application Name %s not found", appName)
+		return nil, notFoundErrorf("Application Name %s not found", appName)
 	}
 	return app, nil
 }
 
 // GetApplications retrieves all applications from the cache and returns them inside an array.
 func (d *discoveryCache) GetApplications() ([]*Application, error) {
+	d.client.Lock()
+	defer d.client.Unlock()
 	return d.client.dictionary.getApplications(), nil
 }
 
 // GetInstance returns from the cache an instance object with the specified appId and id given as arguments.
 // appId - string representing application name. id - id  string of instance
 func (d *discoveryCache) GetInstance(appID, id string) (*Instance, error) {
-	if val, ok := d.client.dictionary.appNameIndex[appID][id]; ok {
+	d.client.Lock()
+	val, ok := d.client.dictionary.appNameIndex[appID][id]
+	d.client.Unlock()
+	if ok {
 		return val, nil
 	}
-	return nil, fmt.Errorf("Instance %s not found under application %s", id, appID)
+	return nil, notFoundErrorf("Instance %s not found under application %s", id, appID)
 
 }
 
 // GetInstancesByVip returns from the cache all the instances with the given vipAddress.
 func (d *discoveryCache) GetInstancesByVip(vipAddress string) ([]*Instance, error) {
+	d.client.Lock()
 	instances := d.client.dictionary.GetInstancesByVip(vipAddress)
+	d.client.Unlock()
 	if instances == nil {
-		return nil, fmt.Errorf("vipAddress  %s not found", vipAddress)
+		return nil, notFoundErrorf("vipAddress  %s not found", vipAddress)
 	}
 	return instances, nil
 }
 
 // GetInstancesBySecVip return from the cache all the instances with the given secured vip address.
 func (d *discoveryCache) GetInstancesBySecVip(secVipAddress string) ([]*Instance, error) {
+	d.client.Lock()
 	instances := d.client.dictionary.GetInstancesBySecVip(secVipAddress)
+	d.client.Unlock()
+	if instances == nil {
+		return nil, notFoundErrorf("vipAddress  %s not found", secVipAddress)
+	}
+	return instances, nil
+}
+
+// GetInstancesByGroup returns from the cache all the instances which belong to the given application group.
+func (d *discoveryCache) GetInstancesByGroup(groupName string) ([]*Instance, error) {
+	d.client.Lock()
+	instances := d.client.dictionary.GetInstancesByGroup(groupName)
+	d.client.Unlock()
+	if instances == nil {
+		return nil, notFoundErrorf("group %s not found", groupName)
+	}
+	return instances, nil
+}
+
+// GetApplicationsByVip returns from the cache the applications backing the given vipAddress.
+// Each application holds only its instances which are registered with the vipAddress.
+func (d *discoveryCache) GetApplicationsByVip(vipAddress string) ([]*Application, error) {
+	d.client.Lock()
+	apps := d.client.dictionary.getApplicationsByVip(vipAddress)
+	d.client.Unlock()
+	if apps == nil {
+		return nil, notFoundErrorf("vipAddress  %s not found", vipAddress)
+	}
+	return apps, nil
+}
+
+// GetInstancesByVipInRegions returns from the cache the instances with the given vipAddress which belong to one
+// of the given regions. If no region is given, only instances of the local region are returned.
+func (d *discoveryCache) GetInstancesByVipInRegions(vipAddress string, regions ...string) ([]*Instance, error) {
+	if len(regions) == 0 {
+		regions = []string{d.client.region}
+	}
+
+	d.client.Lock()
+	instances := d.client.dictionary.getInstancesByVipInRegions(vipAddress, regions)
+	d.client.Unlock()
 	if instances == nil {
-		return nil, fmt.Errorf("vipAddress  %s not found", secVipAddress)
+		return nil, notFoundErrorf("vipAddress  %s not found in regions %v", vipAddress, regions)
 	}
 	return instances, nil
 }
+
+// GetInstancesBySecVipInRegions returns from the cache the instances with the given secured vip address which belong
+// to one of the given regions. If no region is given, only instances of the local region are returned.
+func (d *discoveryCache) GetInstancesBySecVipInRegions(secVipAddress string, regions ...string) ([]*Instance, error) {
+	if len(regions) == 0 {
+		regions = []string{d.client.region}
+	}
+
+	d.client.Lock()
+	instances := d.client.dictionary.getInstancesBySecVipInRegions(secVipAddress, regions)
+	d.client.Unlock()
+	if instances == nil {
+		return nil, notFoundErrorf("vipAddress  %s not found in regions %v", secVipAddress, regions)
+	}
+	return instances, nil
+}
+
+// SelectInstancesByVip returns from the cache the UP instances with the given vipAddress in the local region.
+// If the local region has no UP instances, the remote regions are tried in the configured order.
+func (d *discoveryCache) SelectInstancesByVip(vipAddress string) ([]*Instance, error) {
+	return d.selectInstances(vipAddress, d.GetInstancesByVipInRegions)
+}
+
+// SelectInstancesBySecVip returns from the cache the UP instances with the given secured vip address in the local
+// region. If the local region has no UP instances, the remote regions are tried in the configured order.
+func (d *discoveryCache) SelectInstancesBySecVip(secVipAddress string) ([]*Instance, error) {
+	return d.selectInstances(secVipAddress, d.GetInstancesBySecVipInRegions)
+}
+
+func (d *discoveryCache) selectInstances(address string, lookup func(string, ...string) ([]*Instance, error)) ([]*Instance, error) {
+	for _, region := range append([]string{d.client.region}, d.client.remoteRegions...) {
+		instances, _ := lookup(address, region)
+		if upInstances := filterByStatus(instances, nil); len(upInstances) > 0 {
+			return upInstances, nil
+		}
+	}
+	return nil, fmt.Errorf("no UP instances found for %s", address)
+}
+
+// GetApplicationInstancesByStatus returns from the cache the instances of the application whose effective
+// status is one of the given statuses. If no status is given, only UP instances are returned.
+func (d *discoveryCache) GetApplicationInstancesByStatus(appName string, statuses ...StatusType) ([]*Instance, error) {
+	app, e := d.GetApplication(appName)
+	if e != nil {
+		return nil, e
+	}
+
+	return filterByStatus(app.Instances, statuses), nil
+}
+
+// GetInstancesByVipAndStatus returns from the cache the instances with the given vipAddress whose effective
+// status is one of the given statuses. If no status is given, only UP instances are returned.
+func (d *discoveryCache) GetInstancesByVipAndStatus(vipAddress string, statuses ...StatusType) ([]*Instance, error) {
+	instances, e := d.GetInstancesByVip(vipAddress)
+	if e != nil {
+		return nil, e
+	}
+
+	return filterByStatus(instances, statuses), nil
+}
+
+// GetInstancesBySecVipAndStatus returns from the cache the instances with the given secured vip address whose
+// effective status is one of the given statuses. If no status is given, only UP instances are returned.
+func (d *discoveryCache) GetInstancesBySecVipAndStatus(secVipAddress string, statuses ...StatusType) ([]*Instance, error) {
+	instances, e := d.GetInstancesBySecVip(secVipAddress)
+	if e != nil {
+		return nil, e
+	}
+
+	return filterByStatus(instances, statuses), nil
+}
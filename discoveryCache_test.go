@@ -15,6 +15,15 @@
 //Package goEurekaClient Implements a go client that interacts with a eureka server
 package goEurekaClient
 
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
 /*
 import (
 	"testing"
@@ -97,3 +106,149 @@ func TestNewDiscoveryCache(t *testing.T){
 	log.Printf("inst id  = %s", vipInst.ID)
 }
 */
+
+// registryInstanceJSON returns the JSON representation of an instance as marshaled by the eureka server.
+func registryInstanceJSON(app, host, vip, status, zone string) string {
+	return fmt.Sprintf(`{"instanceId":"%[2]s","hostName":"%[2]s","app":"%[1]s","ipAddr":"10.0.0.1","vipAddress":"%[3]s",
+		"status":"%[4]s","port":{"@enabled":"true","$":8080},"securePort":{"@enabled":"false","$":443},
+		"dataCenterInfo":{"@class":"com.netflix.appinfo.AmazonInfo","name":"Amazon",
+		"metadata":{"instance-id":"%[2]s","availability-zone":"%[5]s"}},"leaseInfo":{"renewalIntervalInSecs":30}}`,
+		app, host, vip, status, zone)
+}
+
+func TestSelectInstancesByVipFailsOverToRemoteRegion(t *testing.T) {
+	var query string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query = r.URL.Query().Get("regions")
+		fmt.Fprintf(w, `{"applications":{"application":[{"name":"APP1","instance":[%s,%s,%s]}]}}`,
+			registryInstanceJSON("APP1", "local1", "vip1", "DOWN", "us-east-1a"),
+			registryInstanceJSON("APP1", "remote1", "vip1", "UP", "eu-west-1b"),
+			registryInstanceJSON("APP1", "remote2", "vip1", "UP", "eu-west-1c"))
+	}))
+	defer server.Close()
+
+	conf := &Config{
+		ConnectTimeoutSeconds: 10 * time.Second,
+		ServiceUrls:           map[string][]string{"eureka": {server.URL}},
+		UseJSON:               true,
+		Region:                "us-east-1",
+		FetchRemoteRegions:    []string{"eu-west-1"},
+	}
+	cache, err := NewDiscoveryCache(conf, time.Minute, nil)
+	if err != nil {
+		t.Fatalf("error = %v", err)
+	}
+	cache.(*discoveryCache).client.refresh(nil)
+
+	if query != "eu-west-1" {
+		t.Errorf("remote regions should be requested, got %q", query)
+	}
+
+	local, err := cache.GetInstancesByVipInRegions("vip1")
+	if err != nil || len(local) != 1 || local[0].Region != "us-east-1" {
+		t.Errorf("expected a single local instance, got %v (%v)", local, err)
+	}
+
+	remote, err := cache.GetInstancesByVipInRegions("vip1", "eu-west-1")
+	if err != nil || len(remote) != 2 {
+		t.Errorf("expected 2 remote instances, got %v (%v)", remote, err)
+	}
+
+	selected, err := cache.SelectInstancesByVip("vip1")
+	if err != nil {
+		t.Fatalf("error = %v", err)
+	}
+	if len(selected) != 2 {
+		t.Fatalf("expected to fail over to the 2 remote instances, got %d", len(selected))
+	}
+	for _, inst := range selected {
+		if !strings.HasPrefix(inst.HostName, "remote") {
+			t.Errorf("unexpected instance selected: %s", inst.HostName)
+		}
+	}
+}
+
+func TestGetInstancesByVipAndStatusRespectsOverride(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		overridden := strings.Replace(registryInstanceJSON("APP1", "inst2", "vip1", "UP", "us-east-1a"),
+			`"status":"UP"`, `"status":"UP","overriddenstatus":"OUT_OF_SERVICE"`, 1)
+		fmt.Fprintf(w, `{"applications":{"application":{"name":"APP1","instance":[%s,%s,%s]}}}`,
+			registryInstanceJSON("APP1", "inst1", "vip1", "UP", "us-east-1a"), overridden,
+			registryInstanceJSON("APP1", "inst3", "vip1", "DOWN", "us-east-1a"))
+	}))
+	defer server.Close()
+
+	conf := &Config{
+		ConnectTimeoutSeconds: 10 * time.Second,
+		ServiceUrls:           map[string][]string{"eureka": {server.URL}},
+		UseJSON:               true,
+	}
+	cache, err := NewDiscoveryCache(conf, time.Minute, nil)
+	if err != nil {
+		t.Fatalf("error = %v", err)
+	}
+	cache.(*discoveryCache).client.refresh(nil)
+
+	up, err := cache.GetInstancesByVipAndStatus("vip1")
+	if err != nil {
+		t.Fatalf("error = %v", err)
+	}
+	if len(up) != 1 || up[0].HostName != "inst1" {
+		t.Errorf("only inst1 should be UP, got %v", up)
+	}
+
+	all, _ := cache.GetInstancesByVipAndStatus("vip1", UP, DOWN, OUTOFSERVICE)
+	if len(all) != 3 {
+		t.Errorf("should contain 3 instances, %d", len(all))
+	}
+
+	oos, _ := cache.GetApplicationInstancesByStatus("APP1", OUTOFSERVICE)
+	if len(oos) != 1 || oos[0].HostName != "inst2" {
+		t.Errorf("only inst2 should be OUT_OF_SERVICE, got %v", oos)
+	}
+}
+
+// eventRecorder is an InstanceEventHandler which records the events it receives.
+type eventRecorder struct {
+	events []string
+}
+
+func (e *eventRecorder) OnAdd(inst *Instance) {
+	e.events = append(e.events, "added "+inst.HostName)
+}
+
+func (e *eventRecorder) OnUpdate(oldInst, newInst *Instance) {
+	e.events = append(e.events, "modified "+newInst.HostName)
+}
+
+func (e *eventRecorder) OnDelete(inst *Instance) {
+	e.events = append(e.events, "deleted "+inst.HostName)
+}
+
+func TestRefreshNotifiesFullFetchChanges(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasPrefix(r.URL.Path, "/apps/delta") {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		fmt.Fprintf(w, `{"applications":{"application":{"name":"APP1","instance":%s}}}`,
+			registryInstanceJSON("APP1", "inst1", "vip1", "UP", "us-east-1a"))
+	}))
+	defer server.Close()
+
+	conf := &Config{
+		ConnectTimeoutSeconds: 10 * time.Second,
+		ServiceUrls:           map[string][]string{"eureka": {server.URL}},
+		UseJSON:               true,
+	}
+	recorder := &eventRecorder{}
+	cache, err := NewDiscoveryCache(conf, time.Minute, recorder)
+	if err != nil {
+		t.Fatalf("error = %v", err)
+	}
+	cache.(*discoveryCache).client.refresh(recorder)
+
+	if len(recorder.events) != 1 || recorder.events[0] != "added inst1" {
+		t.Errorf("events = %v, expected the instance to be added", recorder.events)
+	}
+}
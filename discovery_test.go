@@ -15,6 +15,87 @@
 //Package goEurekaClient Implements a go client that interacts with a eureka server
 package goEurekaClient
 
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDiscoveryGetInstancesByGroup(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		grouped := func(app, host, group string) string {
+			return strings.Replace(registryInstanceJSON(app, host, "vip1", "UP", "zone1"),
+				`"app":`, fmt.Sprintf(`"appGroupName":"%s","app":`, group), 1)
+		}
+		fmt.Fprintf(w, `{"applications":{"application":[{"name":"APP1","instance":%s},{"name":"APP2","instance":[%s,%s]}]}}`,
+			grouped("APP1", "inst1", "GROUP1"), grouped("APP2", "inst2", "GROUP1"), grouped("APP2", "inst3", "GROUP2"))
+	}))
+	defer server.Close()
+
+	conf := &Config{
+		ConnectTimeoutSeconds: 10 * time.Second,
+		ServiceUrls:           map[string][]string{"eureka": {server.URL}},
+		UseJSON:               true,
+	}
+	discovery, e := NewDiscovery(conf, nil)
+	if e != nil {
+		t.Fatalf("error = %v", e)
+	}
+
+	insts, e := discovery.GetInstancesByGroup("GROUP1")
+	if e != nil {
+		t.Fatalf("Failed to get instances by group. error : %v", e)
+	}
+	if len(insts) != 2 {
+		t.Errorf("num of instances should be 2. instead : %d", len(insts))
+	}
+
+	apps, e := discovery.GetApplicationsByVip("vip1")
+	if e != nil {
+		t.Fatalf("Failed to get applications by vip. error : %v", e)
+	}
+	if len(apps) != 2 || len(apps[1].Instances) != 2 {
+		t.Errorf("unexpected applications %v", apps)
+	}
+}
+
+func TestDiscoveryUnknownGroupAndVip(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasPrefix(r.URL.Path, "/vips/") {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		fmt.Fprint(w, `{"applications":null}`)
+	}))
+	defer server.Close()
+
+	conf := &Config{
+		ConnectTimeoutSeconds: 10 * time.Second,
+		ServiceUrls:           map[string][]string{"eureka": {server.URL}},
+		UseJSON:               true,
+	}
+	discovery, e := NewDiscovery(conf, nil)
+	if e != nil {
+		t.Fatalf("error = %v", e)
+	}
+	cache, e := NewDiscoveryCache(conf, time.Minute, nil)
+	if e != nil {
+		t.Fatalf("error = %v", e)
+	}
+
+	for name, lookups := range map[string]Discovery{"discovery": discovery, "cache": cache} {
+		if insts, e := lookups.GetInstancesByGroup("GROUP1"); !IsNotFound(e) || insts != nil {
+			t.Errorf("%s: unknown group should not be found, got %v (%v)", name, insts, e)
+		}
+		if apps, e := lookups.GetApplicationsByVip("vip1"); !IsNotFound(e) || apps != nil {
+			t.Errorf("%s: unknown vip should not be found, got %v (%v)", name, apps, e)
+		}
+	}
+}
+
 /*
 import (
 	"testing"
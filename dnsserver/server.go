@@ -0,0 +1,302 @@
+// Copyright 2016 IBM Corporation
+//
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+//
+//       http://www.apache.org/licenses/LICENSE-2.0
+//
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+
+// Package dnsserver implements a DNS frontend answering vip address queries from the eureka registry.
+package dnsserver
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	eureka "github.com/amalgam8/go-eureka-client"
+	"github.com/miekg/dns"
+)
+
+const (
+	// DefaultDomain is the domain under which vip addresses are served.
+	DefaultDomain = "eureka.local."
+	// WeightMetadataKey is the instance metadata key holding the SRV weight of the instance.
+	WeightMetadataKey = "weight"
+
+	defaultPollInterval = 30 * time.Second
+	defaultWeight       = 1
+)
+
+// InstanceSource looks up the instances of a vip address. It is implemented by eureka.DiscoveryCache.
+// A vip address whose lookup error satisfies eureka.IsNotFound is answered as a name which does not exist.
+// When the source also has a Filled() bool method, as the discovery cache does, queries are answered
+// with a server failure until it reports being filled.
+type InstanceSource interface {
+	GetInstancesByVip(vipAddress string) ([]*eureka.Instance, error)
+}
+
+// filler is implemented by sources which are filled asynchronously.
+type filler interface {
+	Filled() bool
+}
+
+// Config defines the way the DNS server answers queries.
+type Config struct {
+	Domain       string        // default eureka.local.
+	PollInterval time.Duration // poll interval of the instance source, used as the TTL of answers, default 30s
+}
+
+// Server answers A, AAAA and SRV queries for <vip>.<domain> names with the UP instances of the vip address.
+// SRV queries may also use the _<service>._<proto>.<vip>.<domain> form.
+type Server interface {
+	dns.Handler
+	// Serve answers queries received on the connection until the context is done.
+	Serve(ctx context.Context, conn net.PacketConn) error
+	// ListenAndServe answers UDP and TCP queries received on the address until the context is done.
+	ListenAndServe(ctx context.Context, addr string) error
+}
+
+type server struct {
+	source InstanceSource
+	domain string
+	ttl    uint32
+}
+
+// NewServer creates a DNS server answering queries from the instance source.
+func NewServer(source InstanceSource, config Config) (Server, error) {
+	if source == nil {
+		return nil, errors.New("instance source must be defined")
+	}
+	if config.Domain == "" {
+		config.Domain = DefaultDomain
+	}
+	if config.PollInterval <= 0 {
+		config.PollInterval = defaultPollInterval
+	}
+
+	ttl := (config.PollInterval + time.Second - 1) / time.Second
+	return &server{
+		source: source,
+		domain: dns.CanonicalName(config.Domain),
+		ttl:    uint32(ttl),
+	}, nil
+}
+
+// ListenAndServe answers UDP and TCP queries received on the address until the context is done.
+// The TCP listener lets clients retry the queries whose UDP answers were truncated.
+func (s *server) ListenAndServe(ctx context.Context, addr string) error {
+	conn, err := net.ListenPacket("udp", addr)
+	if err != nil {
+		return err
+	}
+	listener, err := net.Listen("tcp", conn.LocalAddr().String())
+	if err != nil {
+		conn.Close()
+		return err
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	errs := make(chan error, 2)
+	for _, dnsServer := range []*dns.Server{{PacketConn: conn, Handler: s}, {Listener: listener, Handler: s}} {
+		go func(dnsServer *dns.Server) {
+			errs <- s.serve(ctx, dnsServer)
+		}(dnsServer)
+	}
+
+	// Stop both servers when either fails
+	err = <-errs
+	cancel()
+	if tcpErr := <-errs; err == nil {
+		err = tcpErr
+	}
+	return err
+}
+
+// Serve answers queries received on the connection until the context is done.
+func (s *server) Serve(ctx context.Context, conn net.PacketConn) error {
+	return s.serve(ctx, &dns.Server{PacketConn: conn, Handler: s})
+}
+
+func (s *server) serve(ctx context.Context, dnsServer *dns.Server) error {
+	go func() {
+		<-ctx.Done()
+		dnsServer.Shutdown()
+	}()
+
+	err := dnsServer.ActivateAndServe()
+	if ctx.Err() != nil {
+		return nil
+	}
+	return err
+}
+
+// ServeDNS answers a single query. UDP answers exceeding the buffer size of the client are truncated.
+func (s *server) ServeDNS(w dns.ResponseWriter, req *dns.Msg) {
+	resp := new(dns.Msg)
+	resp.SetReply(req)
+	resp.Authoritative = true
+	defer writeMsg(w, req, resp)
+
+	if len(req.Question) != 1 {
+		resp.Rcode = dns.RcodeFormatError
+		return
+	}
+
+	question := req.Question[0]
+	vip, ok := s.vipAddress(question.Name)
+	if !ok {
+		resp.Rcode = dns.RcodeRefused
+		return
+	}
+
+	if f, ok := s.source.(filler); ok && !f.Filled() {
+		resp.Rcode = dns.RcodeServerFailure
+		return
+	}
+	insts, err := s.source.GetInstancesByVip(vip)
+	if eureka.IsNotFound(err) {
+		resp.Rcode = dns.RcodeNameError
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to get instances of vip %s. error: %s\n", vip, err)
+		resp.Rcode = dns.RcodeServerFailure
+		return
+	}
+	insts = upInstances(insts)
+	if len(insts) == 0 {
+		resp.Rcode = dns.RcodeNameError
+		return
+	}
+
+	switch question.Qtype {
+	case dns.TypeA, dns.TypeAAAA:
+		resp.Answer = s.addressRecords(question.Name, question.Qtype, insts)
+	case dns.TypeSRV:
+		resp.Answer, resp.Extra = s.serviceRecords(question.Name, insts)
+	}
+}
+
+// writeMsg writes the response, truncated to the UDP buffer size of the client, 512 bytes unless its query
+// advertises another size. The TC bit of truncated answers asks the client to retry over TCP.
+func writeMsg(w dns.ResponseWriter, req, resp *dns.Msg) {
+	if _, ok := w.RemoteAddr().(*net.UDPAddr); ok {
+		size := dns.MinMsgSize
+		if opt := req.IsEdns0(); opt != nil {
+			size = int(opt.UDPSize())
+		}
+		resp.Truncate(size)
+	}
+	if err := w.WriteMsg(resp); err != nil {
+		log.Printf("Failed to write DNS answer. error: %s\n", err)
+	}
+}
+
+// vipAddress extracts the vip address from a query name, ignoring the service and protocol labels of SRV names.
+// The domain is matched case-insensitively, while the vip address keeps the case of the query.
+func (s *server) vipAddress(name string) (string, bool) {
+	name = dns.Fqdn(name)
+	suffix := "." + s.domain
+	if len(name) <= len(suffix) || !strings.EqualFold(name[len(name)-len(suffix):], suffix) {
+		return "", false
+	}
+	vip := name[:len(name)-len(suffix)]
+
+	labels := strings.SplitN(vip, ".", 3)
+	if len(labels) == 3 && strings.HasPrefix(labels[0], "_") && strings.HasPrefix(labels[1], "_") {
+		vip = labels[2]
+	}
+	return vip, vip != ""
+}
+
+func (s *server) addressRecords(name string, qtype uint16, insts []*eureka.Instance) []dns.RR {
+	var records []dns.RR
+	for _, inst := range insts {
+		if rr := s.addressRecord(name, inst, qtype); rr != nil {
+			records = append(records, rr)
+		}
+	}
+	return records
+}
+
+// addressRecord returns the A or AAAA record of the instance, or nil if its address is of the other family.
+func (s *server) addressRecord(name string, inst *eureka.Instance, qtype uint16) dns.RR {
+	ip := net.ParseIP(inst.IPAddr)
+	if ip == nil {
+		return nil
+	}
+	if ip4 := ip.To4(); ip4 != nil {
+		if qtype != dns.TypeA {
+			return nil
+		}
+		return &dns.A{Hdr: s.header(name, dns.TypeA), A: ip4}
+	}
+	if qtype != dns.TypeAAAA {
+		return nil
+	}
+	return &dns.AAAA{Hdr: s.header(name, dns.TypeAAAA), AAAA: ip}
+}
+
+// serviceRecords returns the SRV records of the instances, and the address records of their targets.
+func (s *server) serviceRecords(name string, insts []*eureka.Instance) ([]dns.RR, []dns.RR) {
+	var answers, extras []dns.RR
+	for _, inst := range insts {
+		port, ok := inst.ServicePort()
+		if !ok || inst.HostName == "" {
+			continue
+		}
+		target := dns.Fqdn(inst.HostName)
+		answers = append(answers, &dns.SRV{
+			Hdr:    s.header(name, dns.TypeSRV),
+			Weight: instanceWeight(inst),
+			Port:   uint16(port),
+			Target: target,
+		})
+		for _, qtype := range []uint16{dns.TypeA, dns.TypeAAAA} {
+			if rr := s.addressRecord(target, inst, qtype); rr != nil {
+				extras = append(extras, rr)
+			}
+		}
+	}
+	return answers, extras
+}
+
+func (s *server) header(name string, rrtype uint16) dns.RR_Header {
+	return dns.RR_Header{Name: name, Rrtype: rrtype, Class: dns.ClassINET, Ttl: s.ttl}
+}
+
+func upInstances(insts []*eureka.Instance) []*eureka.Instance {
+	var up []*eureka.Instance
+	for _, inst := range insts {
+		if inst.EffectiveStatus() == eureka.UP {
+			up = append(up, inst)
+		}
+	}
+	return up
+}
+
+// instanceWeight returns the SRV weight set in the instance metadata, or the default weight.
+func instanceWeight(inst *eureka.Instance) uint16 {
+	var metadata map[string]interface{}
+	if len(inst.Metadata) == 0 || json.Unmarshal(inst.Metadata, &metadata) != nil {
+		return defaultWeight
+	}
+	if weight, err := strconv.ParseUint(fmt.Sprintf("%v", metadata[WeightMetadataKey]), 10, 16); err == nil {
+		return uint16(weight)
+	}
+	return defaultWeight
+}
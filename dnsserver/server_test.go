@@ -0,0 +1,230 @@
+// Copyright 2016 IBM Corporation
+//
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+//
+//       http://www.apache.org/licenses/LICENSE-2.0
+//
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+
+package dnsserver
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"testing"
+	"time"
+
+	eureka "github.com/amalgam8/go-eureka-client"
+	"github.com/miekg/dns"
+)
+
+type fakeSource map[string][]*eureka.Instance
+
+func (f fakeSource) GetInstancesByVip(vipAddress string) ([]*eureka.Instance, error) {
+	return f[vipAddress], nil
+}
+
+func testInstance(host, ip string, status eureka.StatusType, port int, metadata map[string]string) *eureka.Instance {
+	inst := &eureka.Instance{
+		HostName: host,
+		IPAddr:   ip,
+		Status:   string(status),
+		Port:     &eureka.Port{Enabled: "true", Value: float64(port)},
+	}
+	if metadata != nil {
+		inst.Metadata, _ = json.Marshal(metadata)
+	}
+	return inst
+}
+
+// startServer serves the source on a local UDP port, and returns a resolver querying it.
+func startServer(t *testing.T, source InstanceSource) (*net.Resolver, string, func()) {
+	srv, err := NewServer(source, Config{PollInterval: 15 * time.Second})
+	if err != nil {
+		t.Fatalf("error = %v", err)
+	}
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("error = %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	go srv.Serve(ctx, conn)
+
+	addr := conn.LocalAddr().String()
+	resolver := &net.Resolver{
+		PreferGo: true,
+		Dial: func(ctx context.Context, network, address string) (net.Conn, error) {
+			var dialer net.Dialer
+			return dialer.DialContext(ctx, "udp", addr)
+		},
+	}
+	return resolver, addr, cancel
+}
+
+func TestResolveVip(t *testing.T) {
+	source := fakeSource{
+		"vip1": {
+			testInstance("inst1.example.com", "10.0.0.1", eureka.UP, 8080, map[string]string{WeightMetadataKey: "5"}),
+			testInstance("inst2.example.com", "fd00::2", eureka.UP, 8081, nil),
+			testInstance("inst3.example.com", "10.0.0.3", eureka.DOWN, 8082, nil),
+		},
+		"vip2": {testInstance("inst4.example.com", "10.0.0.4", eureka.OUTOFSERVICE, 8080, nil)},
+	}
+	resolver, addr, stop := startServer(t, source)
+	defer stop()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	addrs, err := resolver.LookupHost(ctx, "vip1.eureka.local.")
+	if err != nil {
+		t.Fatalf("error = %v", err)
+	}
+	sort.Strings(addrs)
+	if len(addrs) != 2 || addrs[0] != "10.0.0.1" || addrs[1] != "fd00::2" {
+		t.Errorf("addresses = %v, expected the UP instances only", addrs)
+	}
+
+	_, srvs, err := resolver.LookupSRV(ctx, "http", "tcp", "vip1.eureka.local.")
+	if err != nil {
+		t.Fatalf("error = %v", err)
+	}
+	ports := map[string]*net.SRV{}
+	for _, srv := range srvs {
+		ports[srv.Target] = srv
+	}
+	if len(srvs) != 2 || ports["inst1.example.com."].Port != 8080 || ports["inst1.example.com."].Weight != 5 ||
+		ports["inst2.example.com."].Port != 8081 || ports["inst2.example.com."].Weight != defaultWeight {
+		t.Errorf("unexpected SRV records %v", srvs)
+	}
+
+	if _, err = resolver.LookupHost(ctx, "vip2.eureka.local."); err == nil {
+		t.Error("a vip without UP instances should not resolve")
+	}
+
+	msg := new(dns.Msg)
+	msg.SetQuestion("vip1.eureka.local.", dns.TypeA)
+	resp, err := dns.Exchange(msg, addr)
+	if err != nil {
+		t.Fatalf("error = %v", err)
+	}
+	if len(resp.Answer) != 1 || resp.Answer[0].Header().Ttl != 15 {
+		t.Errorf("unexpected answer %v, expected a TTL tied to the poll interval", resp.Answer)
+	}
+
+	msg.SetQuestion("vip1.example.com.", dns.TypeA)
+	if resp, err = dns.Exchange(msg, addr); err != nil || resp.Rcode != dns.RcodeRefused {
+		t.Errorf("names outside the domain should be refused, got %v (%v)", resp, err)
+	}
+}
+
+type failingSource struct{}
+
+func (failingSource) GetInstancesByVip(vipAddress string) ([]*eureka.Instance, error) {
+	return nil, errors.New("registry unavailable")
+}
+
+func TestResolveUnknownVip(t *testing.T) {
+	registry := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"applications":{"application":{"name":"APP1","instance":{"instanceId":"inst1","hostName":"inst1",
+			"app":"APP1","ipAddr":"10.0.0.1","vipAddress":"vip1","status":"UP","port":{"@enabled":"true","$":8080},
+			"dataCenterInfo":{"name":"MyOwn"}}}}}`))
+	}))
+	defer registry.Close()
+	cache, err := eureka.NewDiscoveryCache(&eureka.Config{
+		ServiceUrls: map[string][]string{"eureka": {registry.URL}},
+		UseJSON:     true,
+	}, time.Minute, nil)
+	if err != nil {
+		t.Fatalf("error = %v", err)
+	}
+	_, addr, stop := startServer(t, cache)
+	defer stop()
+
+	msg := new(dns.Msg)
+	msg.SetQuestion("unknown.eureka.local.", dns.TypeA)
+	resp, err := dns.Exchange(msg, addr)
+	if err != nil || resp.Rcode != dns.RcodeServerFailure {
+		t.Errorf("a cache which was never filled should be a server failure, got %v (%v)", resp, err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	cache.Run(ctx)
+	deadline := time.Now().Add(5 * time.Second)
+	for !cache.(filler).Filled() {
+		if time.Now().After(deadline) {
+			t.Fatal("the cache was not filled")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	resp, err = dns.Exchange(msg, addr)
+	if err != nil || resp.Rcode != dns.RcodeNameError {
+		t.Errorf("a vip unknown to the cache should not exist, got %v (%v)", resp, err)
+	}
+
+	_, addr, stop = startServer(t, failingSource{})
+	defer stop()
+	resp, err = dns.Exchange(msg, addr)
+	if err != nil || resp.Rcode != dns.RcodeServerFailure {
+		t.Errorf("a failing source should be a server failure, got %v (%v)", resp, err)
+	}
+}
+
+func TestTruncateLargeAnswers(t *testing.T) {
+	var insts []*eureka.Instance
+	for i := 0; i < 100; i++ {
+		insts = append(insts, testInstance(fmt.Sprintf("inst%d.example.com", i), fmt.Sprintf("10.0.0.%d", i+1), eureka.UP, 8080, nil))
+	}
+	srv, err := NewServer(fakeSource{"vip1": insts}, Config{})
+	if err != nil {
+		t.Fatalf("error = %v", err)
+	}
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("error = %v", err)
+	}
+	addr := conn.LocalAddr().String()
+	conn.Close()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go srv.ListenAndServe(ctx, addr)
+
+	msg := new(dns.Msg)
+	msg.SetQuestion("vip1.eureka.local.", dns.TypeA)
+	var resp *dns.Msg
+	deadline := time.Now().Add(5 * time.Second)
+	for resp, err = dns.Exchange(msg, addr); err != nil; resp, err = dns.Exchange(msg, addr) {
+		if time.Now().After(deadline) {
+			t.Fatalf("error = %v", err)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	resp.Compress = true
+	if !resp.Truncated || len(resp.Answer) == 0 || len(resp.Answer) == len(insts) || resp.Len() > dns.MinMsgSize {
+		t.Errorf("expected an answer truncated to 512 bytes, got %d records in %d bytes", len(resp.Answer), resp.Len())
+	}
+
+	msg.SetEdns0(4096, false)
+	if resp, err = dns.Exchange(msg, addr); err != nil || resp.Truncated || len(resp.Answer) != len(insts) {
+		t.Errorf("expected the whole answer within the advertised buffer size, got %v (%v)", resp, err)
+	}
+
+	tcp := &dns.Client{Net: "tcp"}
+	msg = new(dns.Msg)
+	msg.SetQuestion("vip1.eureka.local.", dns.TypeA)
+	if resp, _, err = tcp.Exchange(msg, addr); err != nil || resp.Truncated || len(resp.Answer) != len(insts) {
+		t.Errorf("expected the whole answer over TCP, got %v (%v)", resp, err)
+	}
+}
@@ -0,0 +1,163 @@
+// Copyright 2016 IBM Corporation
+//
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+//
+//       http://www.apache.org/licenses/LICENSE-2.0
+//
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+
+//Package goEurekaClient Implements a go client that interacts with a eureka server
+package goEurekaClient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"time"
+)
+
+const defaultDrainMargin = 5 * time.Second
+
+// DrainPhase is a step of an instance drain.
+type DrainPhase string
+
+// Drain phases, run in this order by default.
+const (
+	// DrainOutOfService marks the instance OUT_OF_SERVICE, so that consumers stop selecting it.
+	DrainOutOfService DrainPhase = "out-of-service"
+	// DrainWait keeps the instance alive for the drain delay, until the caches of the consumers drop it.
+	DrainWait DrainPhase = "wait"
+	// DrainDeregister removes the instance from the registry.
+	DrainDeregister DrainPhase = "deregister"
+)
+
+// DrainHooks are called around each drain phase. nil hooks are skipped.
+type DrainHooks struct {
+	Before func(phase DrainPhase)
+	After  func(phase DrainPhase, err error)
+}
+
+// DrainConfig defines the phases of a drain.
+type DrainConfig struct {
+	Phases []DrainPhase // default out-of-service, wait and deregister
+	// Delay is the time the instance stays OUT_OF_SERVICE before it is deregistered, default the poll
+	// interval of the client config, or 30s, plus the margin.
+	Delay             time.Duration
+	Margin            time.Duration // default 5s
+	HeartbeatInterval time.Duration // heartbeats sent during the delay, default the lease renewal interval, or 30s
+	Hooks             DrainHooks
+}
+
+// drainInstance drains an instance through the registrator. When the context is done during the delay,
+// the instance is deregistered right away.
+func drainInstance(ctx context.Context, registrator Registrator, inst *Instance, config DrainConfig,
+	pollInterval time.Duration) error {
+	if inst == nil {
+		return errors.New("instance must be defined")
+	}
+	if len(config.Phases) == 0 {
+		config.Phases = []DrainPhase{DrainOutOfService, DrainWait, DrainDeregister}
+	}
+	if config.Delay <= 0 {
+		if config.Margin <= 0 {
+			config.Margin = defaultDrainMargin
+		}
+		if pollInterval <= 0 {
+			pollInterval = defaultPollInterval
+		}
+		config.Delay = pollInterval + config.Margin
+	}
+	if config.HeartbeatInterval <= 0 {
+		config.HeartbeatInterval = defaultHeartbeatInterval
+		if inst.Lease != nil && inst.Lease.RenewalInt > 0 {
+			config.HeartbeatInterval = time.Duration(inst.Lease.RenewalInt) * time.Second
+		}
+	}
+	for _, phase := range config.Phases {
+		if phase != DrainOutOfService && phase != DrainWait && phase != DrainDeregister {
+			return fmt.Errorf("drain phase %q is not valid", phase)
+		}
+	}
+
+	// An instance registered again while draining stays out of service
+	drained := *inst
+	drained.Status = string(OUTOFSERVICE)
+
+	for _, phase := range config.Phases {
+		if config.Hooks.Before != nil {
+			config.Hooks.Before(phase)
+		}
+
+		var err error
+		switch phase {
+		case DrainOutOfService:
+			if err = registrator.SetStatus(inst, OUTOFSERVICE); err != nil {
+				log.Printf("Failed to set status %v. error: %s\n", OUTOFSERVICE, err)
+			}
+		case DrainWait:
+			waitDraining(ctx, registrator, &drained, config)
+		case DrainDeregister:
+			err = registrator.Deregister(inst)
+		}
+
+		if config.Hooks.After != nil {
+			config.Hooks.After(phase, err)
+		}
+		if phase == DrainDeregister && err != nil {
+			return fmt.Errorf("Failed to deregister instance. error: %s", err)
+		}
+	}
+	return nil
+}
+
+// waitDraining renews the lease of the instance until the drain delay elapses or the context is done.
+func waitDraining(ctx context.Context, registrator Registrator, inst *Instance, config DrainConfig) {
+	delay := time.NewTimer(config.Delay)
+	defer delay.Stop()
+	heartbeats := time.NewTicker(config.HeartbeatInterval)
+	defer heartbeats.Stop()
+
+	for {
+		select {
+		case <-heartbeats.C:
+			if err := registrator.Heartbeat(inst); err != nil {
+				log.Printf("Failed to send heartbeat, registering again. error: %s\n", err)
+				if err = registrator.Register(inst); err != nil {
+					log.Printf("Failed to register instance. error: %s\n", err)
+				}
+			}
+		case <-delay.C:
+			return
+		case <-ctx.Done():
+			log.Printf("stop chan received. stop waiting for the drain delay...")
+			return
+		}
+	}
+}
+
+// DrainOnSignal waits until one of the signals is received, and then drains the instance through the registrator.
+// The default signals are SIGTERM and SIGINT. When the context is done before a signal is received,
+// the instance is not drained and the context error is returned. When the context is done during the drain
+// delay, the instance is deregistered right away.
+func DrainOnSignal(ctx context.Context, registrator Registrator, inst *Instance, config DrainConfig,
+	signals ...os.Signal) error {
+	if registrator == nil {
+		return errors.New("registrator must be defined")
+	}
+
+	signaled, cancel := ContextWithSignals(ctx, signals...)
+	defer cancel()
+	<-signaled.Done()
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	return registrator.Drain(ctx, inst, config)
+}
@@ -0,0 +1,171 @@
+// Copyright 2016 IBM Corporation
+//
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+//
+//       http://www.apache.org/licenses/LICENSE-2.0
+//
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+
+//Package goEurekaClient Implements a go client that interacts with a eureka server
+package goEurekaClient
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/signal"
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRegistratorDrain(t *testing.T) {
+	var lock sync.Mutex
+	var requests []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lock.Lock()
+		requests = append(requests, r.Method+" "+r.URL.Path)
+		lock.Unlock()
+	}))
+	defer server.Close()
+
+	conf := &Config{
+		ConnectTimeoutSeconds: 10 * time.Second,
+		ServiceUrls:           map[string][]string{"eureka": {server.URL}},
+		UseJSON:               true,
+		PollInterval:          30 * time.Millisecond,
+	}
+	reg, err := NewRegistrator(conf, nil)
+	if err != nil {
+		t.Fatalf("error = %v", err)
+	}
+
+	var phases []string
+	config := DrainConfig{
+		Margin:            20 * time.Millisecond,
+		HeartbeatInterval: 20 * time.Millisecond,
+		Hooks: DrainHooks{
+			Before: func(phase DrainPhase) { phases = append(phases, "before "+string(phase)) },
+			After: func(phase DrainPhase, err error) {
+				if err != nil {
+					t.Errorf("phase %s failed: %v", phase, err)
+				}
+				phases = append(phases, "after "+string(phase))
+			},
+		},
+	}
+	start := time.Now()
+	if err = reg.Drain(context.Background(), &Instance{Application: "APP1", HostName: "inst1"}, config); err != nil {
+		t.Fatalf("error = %v", err)
+	}
+	if elapsed := time.Since(start); elapsed < 50*time.Millisecond {
+		t.Errorf("drain took %v, expected at least the poll interval plus the margin", elapsed)
+	}
+
+	expected := []string{"before out-of-service", "after out-of-service", "before wait", "after wait",
+		"before deregister", "after deregister"}
+	if !reflect.DeepEqual(phases, expected) {
+		t.Errorf("phases %v, expected %v", phases, expected)
+	}
+	lock.Lock()
+	defer lock.Unlock()
+	if len(requests) < 3 || requests[0] != "PUT /apps/APP1/inst1/status" || requests[1] != "PUT /apps/APP1/inst1" ||
+		requests[len(requests)-1] != "DELETE /apps/APP1/inst1" {
+		t.Errorf("unexpected requests %v", requests)
+	}
+}
+
+func TestDrainPhases(t *testing.T) {
+	recorder := &callRecorder{}
+	inst := &Instance{Application: "APP1", HostName: "inst1"}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	start := time.Now()
+	if err := drainInstance(ctx, recorder, inst, DrainConfig{Delay: time.Minute}, 0); err != nil {
+		t.Fatalf("error = %v", err)
+	}
+	if time.Since(start) > 5*time.Second {
+		t.Error("a done context should cut the drain delay short")
+	}
+
+	config := DrainConfig{Phases: []DrainPhase{DrainDeregister}}
+	if err := drainInstance(context.Background(), recorder, inst, config, 0); err != nil {
+		t.Fatalf("error = %v", err)
+	}
+	if calls := recorder.recorded(); !reflect.DeepEqual(calls, []string{"status OUT_OF_SERVICE", "deregister", "deregister"}) {
+		t.Errorf("unexpected calls %v", calls)
+	}
+
+	config.Phases = []DrainPhase{"shutdown"}
+	if err := drainInstance(context.Background(), recorder, inst, config, 0); err == nil {
+		t.Error("an invalid phase should be rejected")
+	}
+}
+
+// drainRecorder is a Registrator which counts the drains it receives.
+type drainRecorder struct {
+	Registrator
+	drains int32
+}
+
+func (r *drainRecorder) Drain(ctx context.Context, inst *Instance, config DrainConfig) error {
+	atomic.AddInt32(&r.drains, 1)
+	return nil
+}
+
+func TestDrainOnSignal(t *testing.T) {
+	recorder := &drainRecorder{}
+	inst := &Instance{Application: "APP1", HostName: "inst1"}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := DrainOnSignal(ctx, recorder, inst, DrainConfig{}); err != context.Canceled {
+		t.Errorf("error = %v, expected the context error", err)
+	}
+	if atomic.LoadInt32(&recorder.drains) != 0 {
+		t.Error("the instance should not be drained without a signal")
+	}
+
+	// Keep the signal from interrupting the test process
+	ch := make(chan os.Signal, 1)
+	signal.Notify(ch, os.Interrupt)
+	defer signal.Stop(ch)
+
+	done := make(chan error, 1)
+	go func() {
+		done <- DrainOnSignal(context.Background(), recorder, inst, DrainConfig{}, os.Interrupt)
+	}()
+
+	process, err := os.FindProcess(os.Getpid())
+	if err != nil {
+		t.Fatalf("error = %v", err)
+	}
+	ticker := time.NewTicker(10 * time.Millisecond)
+	defer ticker.Stop()
+	timeout := time.After(5 * time.Second)
+	for {
+		select {
+		case err = <-done:
+			if drains := atomic.LoadInt32(&recorder.drains); err != nil || drains != 1 {
+				t.Errorf("expected a single drain, got %d (%v)", drains, err)
+			}
+			return
+		case <-ticker.C:
+			if err = process.Signal(os.Interrupt); err != nil {
+				t.Skipf("signals are not supported: %v", err)
+			}
+		case <-timeout:
+			t.Fatal("the instance should be drained on the signal")
+		}
+	}
+}
@@ -0,0 +1,231 @@
+// Copyright 2016 IBM Corporation
+//
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+//
+//       http://www.apache.org/licenses/LICENSE-2.0
+//
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+
+//Package goEurekaClient Implements a go client that interacts with a eureka server
+package goEurekaClient
+
+import (
+	"math/rand"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	defaultEndpointCoolDown  = 30 * time.Second
+	defaultReshuffleInterval = 20 * time.Minute
+)
+
+// EndpointState describes the state of an eureka server endpoint.
+type EndpointState struct {
+	URL              string    `json:"url"`
+	Zone             string    `json:"zone"`
+	Current          bool      `json:"current"` // the last working endpoint, tried first
+	Available        bool      `json:"available"`
+	UnavailableUntil time.Time `json:"unavailableUntil"`
+	Failures         int       `json:"failures"` // consecutive failures
+	LastError        string    `json:"lastError,omitempty"`
+}
+
+type endpoint struct {
+	url              string
+	zone             string
+	unavailableUntil time.Time
+	failures         int
+	lastError        string
+}
+
+// endpointManager orders the eureka server endpoints for requests. Like the RetryableEurekaHttpClient of the
+// Java client, it sticks to the last working endpoint, skips the endpoints which failed during a cool-down,
+// and re-randomizes the endpoints within each zone every reshuffle interval, keeping the zone failover order.
+type endpointManager struct {
+	sync.Mutex
+	endpoints         []*endpoint
+	current           *endpoint
+	attempts          int
+	coolDown          time.Duration
+	reshuffleInterval time.Duration
+	shuffledAt        time.Time
+	now               func() time.Time
+}
+
+// newEndpointManager creates a manager of the given endpoint URLs, ordered as created from the config.
+func newEndpointManager(config *Config, urls []string) *endpointManager {
+	zones := map[string]string{}
+	for zone, zoneURLs := range config.ServiceUrls {
+		for _, zoneURL := range zoneURLs {
+			zones[strings.TrimRight(zoneURL, "/")] = zone
+		}
+	}
+
+	m := &endpointManager{
+		attempts:          config.RetriesCount + 1,
+		coolDown:          config.EndpointCoolDown,
+		reshuffleInterval: config.EndpointReshuffleInterval,
+		now:               time.Now,
+	}
+	if m.attempts < 1 {
+		m.attempts = 1
+	}
+	if m.coolDown <= 0 {
+		m.coolDown = defaultEndpointCoolDown
+	}
+	if m.reshuffleInterval <= 0 {
+		m.reshuffleInterval = defaultReshuffleInterval
+	}
+	for _, u := range urls {
+		m.endpoints = append(m.endpoints, &endpoint{url: u, zone: zones[u]})
+	}
+	m.shuffledAt = m.now()
+	return m
+}
+
+// candidates returns the endpoints a request should try, in order: the current endpoint, then the available
+// endpoints, limited to the number of attempts. When no endpoint is available, the endpoints whose cool-down
+// ends first are tried.
+func (m *endpointManager) candidates() []*endpoint {
+	m.Lock()
+	defer m.Unlock()
+
+	now := m.now()
+	if now.Sub(m.shuffledAt) >= m.reshuffleInterval {
+		m.reshuffle()
+		m.shuffledAt = now
+	}
+
+	var candidates []*endpoint
+	if m.current != nil {
+		candidates = append(candidates, m.current)
+	}
+	for _, ep := range m.endpoints {
+		if ep != m.current && !now.Before(ep.unavailableUntil) {
+			candidates = append(candidates, ep)
+		}
+	}
+	if len(candidates) == 0 {
+		candidates = append(candidates, m.endpoints...)
+		sort.SliceStable(candidates, func(i, j int) bool {
+			return candidates[i].unavailableUntil.Before(candidates[j].unavailableUntil)
+		})
+	}
+	if len(candidates) > m.attempts {
+		candidates = candidates[:m.attempts]
+	}
+	return candidates
+}
+
+// reshuffle randomizes the endpoints within each zone, keeping the order of the zones, and forgets the
+// current endpoint so that load spreads over the servers.
+func (m *endpointManager) reshuffle() {
+	var zones []string
+	groups := map[string][]*endpoint{}
+	for _, ep := range m.endpoints {
+		if _, ok := groups[ep.zone]; !ok {
+			zones = append(zones, ep.zone)
+		}
+		groups[ep.zone] = append(groups[ep.zone], ep)
+	}
+
+	endpoints := make([]*endpoint, 0, len(m.endpoints))
+	for _, zone := range zones {
+		group := groups[zone]
+		rand.Shuffle(len(group), func(i, j int) { group[i], group[j] = group[j], group[i] })
+		endpoints = append(endpoints, group...)
+	}
+	m.endpoints = endpoints
+	m.current = nil
+}
+
+// succeeded makes the endpoint the current one.
+func (m *endpointManager) succeeded(ep *endpoint) {
+	m.Lock()
+	defer m.Unlock()
+	m.current = ep
+	ep.failures = 0
+	ep.unavailableUntil = time.Time{}
+	ep.lastError = ""
+}
+
+// failed marks the endpoint unavailable for the cool-down.
+func (m *endpointManager) failed(ep *endpoint, err error) {
+	m.Lock()
+	defer m.Unlock()
+	if m.current == ep {
+		m.current = nil
+	}
+	ep.failures++
+	ep.unavailableUntil = m.now().Add(m.coolDown)
+	ep.lastError = err.Error()
+}
+
+// keepStates takes over the states of the endpoints of previous which are still served, so that a config update
+// does not retry the failed servers. When the server list is unchanged, its order and reshuffle time are kept too.
+func (m *endpointManager) keepStates(previous *endpointManager) {
+	previous.Lock()
+	defer previous.Unlock()
+
+	byURL := map[string]*endpoint{}
+	order := map[string]int{}
+	for i, ep := range previous.endpoints {
+		byURL[ep.url] = ep
+		order[ep.url] = i
+	}
+	unchanged := len(m.endpoints) == len(previous.endpoints)
+	for _, ep := range m.endpoints {
+		old, ok := byURL[ep.url]
+		if !ok {
+			unchanged = false
+			continue
+		}
+		if old.zone != ep.zone {
+			unchanged = false
+		}
+		ep.unavailableUntil = old.unavailableUntil
+		ep.failures = old.failures
+		ep.lastError = old.lastError
+		if previous.current == old {
+			m.current = ep
+		}
+	}
+	if unchanged {
+		sort.SliceStable(m.endpoints, func(i, j int) bool {
+			return order[m.endpoints[i].url] < order[m.endpoints[j].url]
+		})
+		m.shuffledAt = previous.shuffledAt
+	}
+}
+
+// states returns the states of the endpoints, in their current order.
+func (m *endpointManager) states() []EndpointState {
+	m.Lock()
+	defer m.Unlock()
+
+	now := m.now()
+	states := make([]EndpointState, len(m.endpoints))
+	for i, ep := range m.endpoints {
+		states[i] = EndpointState{
+			URL:       ep.url,
+			Zone:      ep.zone,
+			Current:   ep == m.current,
+			Available: !now.Before(ep.unavailableUntil),
+			Failures:  ep.failures,
+			LastError: ep.lastError,
+		}
+		if !states[i].Available {
+			states[i].UnavailableUntil = ep.unavailableUntil
+		}
+	}
+	return states
+}
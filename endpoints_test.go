@@ -0,0 +1,145 @@
+// Copyright 2016 IBM Corporation
+//
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+//
+//       http://www.apache.org/licenses/LICENSE-2.0
+//
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+
+//Package goEurekaClient Implements a go client that interacts with a eureka server
+package goEurekaClient
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func candidateURLs(m *endpointManager) []string {
+	var urls []string
+	for _, ep := range m.candidates() {
+		urls = append(urls, ep.url)
+	}
+	return urls
+}
+
+func TestEndpointManagerStickyAndCoolDown(t *testing.T) {
+	config := &Config{
+		ServiceUrls:      map[string][]string{"zone1": {"http://a", "http://b"}, "zone2": {"http://c"}},
+		RetriesCount:     1,
+		EndpointCoolDown: 10 * time.Second,
+	}
+	m := newEndpointManager(config, []string{"http://a", "http://b", "http://c"})
+	now := time.Unix(1000, 0)
+	m.now = func() time.Time { return now }
+	m.shuffledAt = now
+
+	if urls := candidateURLs(m); len(urls) != 2 || urls[0] != "http://a" || urls[1] != "http://b" {
+		t.Fatalf("candidates = %v, expected the first 2 endpoints", urls)
+	}
+
+	eps := m.candidates()
+	m.failed(eps[0], errors.New("connection refused"))
+	m.succeeded(eps[1])
+	if urls := candidateURLs(m); urls[0] != "http://b" || urls[1] != "http://c" {
+		t.Errorf("candidates = %v, expected the working endpoint first and the failed one skipped", urls)
+	}
+	states := m.states()
+	if states[0].Available || states[0].Failures != 1 || states[0].LastError != "connection refused" || !states[1].Current {
+		t.Errorf("unexpected states %+v", states)
+	}
+
+	now = now.Add(10 * time.Second)
+	if urls := candidateURLs(m); urls[0] != "http://b" || urls[1] != "http://a" {
+		t.Errorf("candidates = %v, expected the failed endpoint back after the cool-down", urls)
+	}
+
+	for _, ep := range m.endpoints {
+		m.failed(ep, errors.New("down"))
+		now = now.Add(time.Second)
+	}
+	if urls := candidateURLs(m); len(urls) != 2 || urls[0] != "http://a" {
+		t.Errorf("candidates = %v, expected the endpoints whose cool-down ends first", urls)
+	}
+}
+
+func TestEndpointManagerReshuffleKeepsZoneOrder(t *testing.T) {
+	config := &Config{
+		ServiceUrls: map[string][]string{"zone1": {"http://a", "http://b"}, "zone2": {"http://c", "http://d"},
+			"zone3": {"http://e"}},
+		PreferSameZone:            true,
+		Zone:                      "zone2",
+		EndpointReshuffleInterval: time.Minute,
+	}
+	m := newEndpointManager(config, []string{"http://c", "http://d", "http://e", "http://a", "http://b"})
+	now := time.Unix(1000, 0)
+	m.now = func() time.Time { return now }
+	m.shuffledAt = now
+	m.succeeded(m.endpoints[1])
+
+	for i := 0; i < 10; i++ {
+		now = now.Add(time.Minute)
+		m.candidates()
+		states := m.states()
+		var zones []string
+		for _, state := range states {
+			zones = append(zones, state.Zone)
+		}
+		if strings.Join(zones, ",") != "zone2,zone2,zone3,zone1,zone1" {
+			t.Fatalf("the zone order should be kept, got %v", zones)
+		}
+		for _, state := range states {
+			if state.Current {
+				t.Fatal("a reshuffle should forget the current endpoint")
+			}
+		}
+	}
+}
+
+func TestRequestsSkipFailedServer(t *testing.T) {
+	var failing, working int32
+	failingServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&failing, 1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer failingServer.Close()
+	workingServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&working, 1)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer workingServer.Close()
+
+	conf := DefaultConfig()
+	conf.ServiceUrls = map[string][]string{"zone1": {failingServer.URL}}
+	cl, err := newClient(conf, nil)
+	if err != nil {
+		t.Fatalf("error = %v", err)
+	}
+	// Keep a deterministic order, with the failing server first
+	cl.currentSettings().endpoints = newEndpointManager(conf, []string{failingServer.URL, workingServer.URL})
+
+	inst := &Instance{Application: "APP1", HostName: "inst1"}
+	for i := 0; i < 3; i++ {
+		if err = cl.heartbeat(inst); err != nil {
+			t.Fatalf("error = %v", err)
+		}
+	}
+	if failing != 1 || working != 3 {
+		t.Errorf("requests: failing server %d, working server %d, expected the failing server to be tried once",
+			failing, working)
+	}
+	states := cl.endpointStates()
+	if states[0].Available || !states[1].Current {
+		t.Errorf("unexpected states %+v", states)
+	}
+}
@@ -0,0 +1,125 @@
+// Copyright 2016 IBM Corporation
+//
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+//
+//       http://www.apache.org/licenses/LICENSE-2.0
+//
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+
+//Package goEurekaClient Implements a go client that interacts with a eureka server
+package goEurekaClient
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// EurekaClient owns a single connection to the eureka servers, shared by its discovery, cache and registrator
+// views: they use the same HTTP client, server list and config, and the cache is the only registry copy.
+type EurekaClient interface {
+	ConfigUpdater
+	// Discovery returns the view querying the servers directly.
+	Discovery() Discovery
+	// Cache returns the view querying the registry cache. Its polling is controlled by Start and Close,
+	// instead of its Run method.
+	Cache() DiscoveryCache
+	// Registrator returns the view registering instances.
+	Registrator() Registrator
+	// Start starts polling the registry into the cache, until the context is done or the client is closed.
+	Start(ctx context.Context) error
+	// Close stops polling the registry. The views may still be used for direct requests.
+	Close() error
+	// Endpoints returns the states of the eureka server endpoints. Requests try the current one first.
+	Endpoints() []EndpointState
+}
+
+type eurekaClient struct {
+	client      *client
+	discovery   *discovery
+	cache       *discoveryCache
+	registrator *registrator
+
+	sync.Mutex
+	started bool
+	closed  bool
+	cancel  context.CancelFunc
+}
+
+// NewEurekaClient creates a client whose cache polls the registry every pollInterval.
+// When pollInterval is not positive, the poll interval of the config is used, and 30s when neither is set.
+// handler is notified of the cache changes. nil indicates that no notifications are needed.
+func NewEurekaClient(config *Config, pollInterval time.Duration, handler InstanceEventHandler) (EurekaClient, error) {
+	cache, err := NewDiscoveryCache(config, pollInterval, handler)
+	if err != nil {
+		return nil, err
+	}
+	sharedClient := cache.(*discoveryCache).client
+
+	return &eurekaClient{
+		client:      sharedClient,
+		discovery:   &discovery{client: sharedClient},
+		cache:       cache.(*discoveryCache),
+		registrator: &registrator{client: sharedClient},
+	}, nil
+}
+
+// Discovery returns the view querying the servers directly.
+func (e *eurekaClient) Discovery() Discovery {
+	return e.discovery
+}
+
+// Cache returns the view querying the registry cache.
+func (e *eurekaClient) Cache() DiscoveryCache {
+	return e.cache
+}
+
+// Registrator returns the view registering instances.
+func (e *eurekaClient) Registrator() Registrator {
+	return e.registrator
+}
+
+// UpdateConfig updates the config shared by all the views.
+func (e *eurekaClient) UpdateConfig(config *Config) error {
+	return e.client.updateConfig(config)
+}
+
+// Start starts polling the registry into the cache, until the context is done or the client is closed.
+func (e *eurekaClient) Start(ctx context.Context) error {
+	e.Lock()
+	defer e.Unlock()
+	if e.closed {
+		return errors.New("client is closed")
+	}
+	if e.started {
+		return errors.New("client is already started")
+	}
+
+	ctx, e.cancel = context.WithCancel(ctx)
+	e.started = true
+	e.cache.Run(ctx)
+	return nil
+}
+
+// Close stops polling the registry. Closing a client more than once has no effect.
+func (e *eurekaClient) Close() error {
+	e.Lock()
+	defer e.Unlock()
+	if e.cancel != nil {
+		e.cancel()
+	}
+	e.closed = true
+	return nil
+}
+
+// Endpoints returns the states of the eureka server endpoints. Requests try the current one first.
+func (e *eurekaClient) Endpoints() []EndpointState {
+	return e.client.endpointStates()
+}
@@ -0,0 +1,95 @@
+// Copyright 2016 IBM Corporation
+//
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+//
+//       http://www.apache.org/licenses/LICENSE-2.0
+//
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+
+//Package goEurekaClient Implements a go client that interacts with a eureka server
+package goEurekaClient
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestEurekaClientSharesOneClient(t *testing.T) {
+	var fetches int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == "POST":
+			w.WriteHeader(http.StatusNoContent)
+		case strings.HasPrefix(r.URL.Path, "/apps/delta"):
+			w.WriteHeader(http.StatusNotFound)
+		case strings.HasPrefix(r.URL.Path, "/apps"):
+			atomic.AddInt32(&fetches, 1)
+			fmt.Fprintf(w, `{"applications":{"application":{"name":"APP1","instance":%s}}}`,
+				registryInstanceJSON("APP1", "inst1", "vip1", "UP", "us-east-1a"))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	conf := &Config{
+		ConnectTimeoutSeconds: 10 * time.Second,
+		ServiceUrls:           map[string][]string{"eureka": {server.URL}},
+		UseJSON:               true,
+	}
+	shared, err := NewEurekaClient(conf, 10*time.Millisecond, nil)
+	if err != nil {
+		t.Fatalf("error = %v", err)
+	}
+	e := shared.(*eurekaClient)
+	if e.discovery.client != e.cache.client || e.registrator.client != e.cache.client {
+		t.Fatal("the views should share a single client")
+	}
+
+	if err = shared.Registrator().Register(&Instance{Application: "APP1", HostName: "inst1", IPAddr: "10.0.0.1",
+		Datacenter: &DatacenterInfo{Name: "MyOwn"}, Port: &Port{Enabled: "true", Value: 8080}}); err != nil {
+		t.Errorf("error = %v", err)
+	}
+	if apps, err := shared.Discovery().GetApplications(); err != nil || len(apps) != 1 {
+		t.Errorf("expected an application, got %v (%v)", apps, err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if err = shared.Start(ctx); err != nil {
+		t.Fatalf("error = %v", err)
+	}
+	if err = shared.Start(ctx); err == nil {
+		t.Error("a started client should not be started again")
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for _, err = shared.Cache().GetInstancesByVip("vip1"); err != nil; _, err = shared.Cache().GetInstancesByVip("vip1") {
+		if time.Now().After(deadline) {
+			t.Fatalf("the cache was not filled: %v", err)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	shared.Close()
+	time.Sleep(50 * time.Millisecond)
+	count := atomic.LoadInt32(&fetches)
+	time.Sleep(50 * time.Millisecond)
+	if atomic.LoadInt32(&fetches) != count {
+		t.Error("a closed client should stop polling")
+	}
+	if err = shared.Start(ctx); err == nil {
+		t.Error("a closed client should not be started")
+	}
+}
@@ -0,0 +1,115 @@
+// Copyright 2016 IBM Corporation
+//
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+//
+//       http://www.apache.org/licenses/LICENSE-2.0
+//
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+
+//Package goEurekaClient Implements a go client that interacts with a eureka server
+package goEurekaClient
+
+import (
+	"sync"
+)
+
+// EventBroadcaster is an InstanceEventHandler which forwards the events it receives to all subscribed handlers.
+// It allows several components to consume the events of a single DiscoveryCache.
+type EventBroadcaster interface {
+	InstanceEventHandler
+	// Subscribe adds a handler, and returns a function removing it.
+	Subscribe(handler InstanceEventHandler) (unsubscribe func())
+}
+
+type eventBroadcaster struct {
+	sync.RWMutex
+	handlers map[int]InstanceEventHandler
+	nextID   int
+}
+
+// NewEventBroadcaster creates an event broadcaster without subscribers.
+func NewEventBroadcaster() EventBroadcaster {
+	return &eventBroadcaster{handlers: map[int]InstanceEventHandler{}}
+}
+
+// Subscribe adds a handler, and returns a function removing it.
+func (b *eventBroadcaster) Subscribe(handler InstanceEventHandler) func() {
+	b.Lock()
+	defer b.Unlock()
+
+	id := b.nextID
+	b.nextID++
+	b.handlers[id] = handler
+	return func() {
+		b.Lock()
+		defer b.Unlock()
+		delete(b.handlers, id)
+	}
+}
+
+// OnAdd forwards the event to all subscribed handlers.
+func (b *eventBroadcaster) OnAdd(inst *Instance) {
+	for _, handler := range b.subscribers() {
+		handler.OnAdd(inst)
+	}
+}
+
+// OnUpdate forwards the event to all subscribed handlers.
+func (b *eventBroadcaster) OnUpdate(oldInst, newInst *Instance) {
+	for _, handler := range b.subscribers() {
+		handler.OnUpdate(oldInst, newInst)
+	}
+}
+
+// OnDelete forwards the event to all subscribed handlers.
+func (b *eventBroadcaster) OnDelete(inst *Instance) {
+	for _, handler := range b.subscribers() {
+		handler.OnDelete(inst)
+	}
+}
+
+// subscribers returns the current handlers, so that handlers may unsubscribe while an event is delivered.
+func (b *eventBroadcaster) subscribers() []InstanceEventHandler {
+	b.RLock()
+	defer b.RUnlock()
+
+	handlers := make([]InstanceEventHandler, 0, len(b.handlers))
+	for _, handler := range b.handlers {
+		handlers = append(handlers, handler)
+	}
+	return handlers
+}
+
+// EventFuncs adapts functions to the InstanceEventHandler interface. Nil functions are ignored.
+type EventFuncs struct {
+	AddFunc    func(inst *Instance)
+	UpdateFunc func(oldInst, newInst *Instance)
+	DeleteFunc func(inst *Instance)
+}
+
+// OnAdd calls AddFunc if it is defined.
+func (f EventFuncs) OnAdd(inst *Instance) {
+	if f.AddFunc != nil {
+		f.AddFunc(inst)
+	}
+}
+
+// OnUpdate calls UpdateFunc if it is defined.
+func (f EventFuncs) OnUpdate(oldInst, newInst *Instance) {
+	if f.UpdateFunc != nil {
+		f.UpdateFunc(oldInst, newInst)
+	}
+}
+
+// OnDelete calls DeleteFunc if it is defined.
+func (f EventFuncs) OnDelete(inst *Instance) {
+	if f.DeleteFunc != nil {
+		f.DeleteFunc(inst)
+	}
+}
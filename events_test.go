@@ -0,0 +1,48 @@
+// Copyright 2016 IBM Corporation
+//
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+//
+//       http://www.apache.org/licenses/LICENSE-2.0
+//
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+
+//Package goEurekaClient Implements a go client that interacts with a eureka server
+package goEurekaClient
+
+import (
+	"testing"
+)
+
+func TestEventBroadcaster(t *testing.T) {
+	broadcaster := NewEventBroadcaster()
+	first, second := &eventRecorder{}, &eventRecorder{}
+	broadcaster.Subscribe(first)
+	unsubscribe := broadcaster.Subscribe(second)
+
+	inst := &Instance{HostName: "inst1"}
+	broadcaster.OnAdd(inst)
+	unsubscribe()
+	broadcaster.OnUpdate(inst, inst)
+	broadcaster.OnDelete(inst)
+
+	if len(first.events) != 3 {
+		t.Errorf("first subscriber events = %v, expected 3 events", first.events)
+	}
+	if len(second.events) != 1 || second.events[0] != "added inst1" {
+		t.Errorf("second subscriber events = %v, expected a single event before unsubscribing", second.events)
+	}
+
+	var deleted string
+	broadcaster.Subscribe(EventFuncs{DeleteFunc: func(inst *Instance) { deleted = inst.HostName }})
+	broadcaster.OnAdd(inst)
+	broadcaster.OnDelete(inst)
+	if deleted != "inst1" {
+		t.Errorf("delete function should be called, got %q", deleted)
+	}
+}
@@ -0,0 +1,103 @@
+// Copyright 2016 IBM Corporation
+//
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+//
+//       http://www.apache.org/licenses/LICENSE-2.0
+//
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+
+//Package goEurekaClient Implements a go client that interacts with a eureka server
+package goEurekaClient
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	// DefaultGCPMetadataURL is the base URL of the GCE metadata server
+	DefaultGCPMetadataURL = "http://metadata.google.internal"
+
+	gcpDatacenterName = "GCP"
+	gcpInstanceID     = "instance-id"
+)
+
+// gcpMetadataPaths maps the datacenter metadata keys to their path in the metadata server.
+var gcpMetadataPaths = []struct {
+	key      string
+	path     string
+	optional bool
+}{
+	{gcpInstanceID, "instance/id", false},
+	{"instance-name", "instance/name", false},
+	{availabilityZoneKey, "instance/zone", false},
+	{"machine-type", "instance/machine-type", false},
+	{"hostname", "instance/hostname", false},
+	{"local-ipv4", "instance/network-interfaces/0/ip", false},
+	{"public-ipv4", "instance/network-interfaces/0/access-configs/0/external-ip", true},
+	{"project-id", "project/project-id", false},
+}
+
+// GCP datacenter information
+type gcpIdentifier struct{}
+
+// Returns the unique identifier of this datacenter info
+func (gcp *gcpIdentifier) GetID(dcinfo *DatacenterInfo) string {
+	return metadataString(dcinfo, gcpInstanceID)
+}
+
+type gcpMetadataProvider struct {
+	baseURL    string
+	httpClient *http.Client
+}
+
+// NewGCPMetadataProvider creates a provider which reads the datacenter information from the GCE metadata server.
+// An empty baseURL means DefaultGCPMetadataURL.
+func NewGCPMetadataProvider(baseURL string, timeout time.Duration) DatacenterInfoProvider {
+	if baseURL == "" {
+		baseURL = DefaultGCPMetadataURL
+	}
+	return &gcpMetadataProvider{
+		baseURL:    strings.TrimSuffix(baseURL, "/"),
+		httpClient: &http.Client{Timeout: timeout},
+	}
+}
+
+// DatacenterInfo returns the GCP datacenter information of the running VM.
+func (p *gcpMetadataProvider) DatacenterInfo() (*DatacenterInfo, error) {
+	headers := map[string]string{"Metadata-Flavor": "Google"}
+	metadata := DatacenterMetadata{}
+	for _, entry := range gcpMetadataPaths {
+		body, found, err := getMetadata(p.httpClient, "GET", p.baseURL+"/computeMetadata/v1/"+entry.path, headers)
+		if err != nil {
+			return nil, fmt.Errorf("Failed to read metadata %s. error: %s", entry.path, err)
+		}
+		if !found {
+			if !entry.optional {
+				return nil, fmt.Errorf("metadata %s not found", entry.path)
+			}
+			continue
+		}
+
+		value := strings.TrimSpace(string(body))
+		// Zones and machine types are reported as resource paths, e.g. projects/123/zones/us-central1-a
+		if i := strings.LastIndex(value, "/"); i >= 0 && (entry.key == availabilityZoneKey || entry.key == "machine-type") {
+			value = value[i+1:]
+		}
+		metadata[entry.key] = value
+	}
+
+	return &DatacenterInfo{
+		Class:    customDatacenterClass,
+		Name:     gcpDatacenterName,
+		Metadata: metadata,
+	}, nil
+}
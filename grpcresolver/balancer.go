@@ -0,0 +1,128 @@
+// Copyright 2016 IBM Corporation
+//
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+//
+//       http://www.apache.org/licenses/LICENSE-2.0
+//
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+
+package grpcresolver
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+	"sync/atomic"
+
+	"google.golang.org/grpc/balancer"
+	"google.golang.org/grpc/balancer/base"
+	"google.golang.org/grpc/serviceconfig"
+)
+
+// ZoneAwareBalancerName is the name of the zone-aware balancer, registered by this package.
+const ZoneAwareBalancerName = "eureka_zone_aware"
+
+func init() {
+	balancer.Register(zoneAwareBuilder{})
+}
+
+// zoneAwareServiceConfig returns a service config selecting the zone-aware balancer for the given zone.
+func zoneAwareServiceConfig(zone string) string {
+	config, _ := json.Marshal(map[string]interface{}{
+		"loadBalancingConfig": []interface{}{
+			map[string]interface{}{ZoneAwareBalancerName: zoneConfig{Zone: zone}},
+		},
+	})
+	return string(config)
+}
+
+// zoneConfig is the load balancing config of the zone-aware balancer.
+type zoneConfig struct {
+	serviceconfig.LoadBalancingConfig `json:"-"`
+	Zone                              string `json:"zone"`
+}
+
+// zoneAwareBuilder builds balancers which round robin over the ready addresses of the configured zone,
+// and over all ready addresses when none of the zone is ready.
+type zoneAwareBuilder struct{}
+
+func (zoneAwareBuilder) Name() string {
+	return ZoneAwareBalancerName
+}
+
+func (zoneAwareBuilder) Build(cc balancer.ClientConn, opts balancer.BuildOptions) balancer.Balancer {
+	pickers := &zonePickerBuilder{}
+	return &zoneAwareBalancer{
+		Balancer: base.NewBalancerBuilder(ZoneAwareBalancerName, pickers, base.Config{}).Build(cc, opts),
+		pickers:  pickers,
+	}
+}
+
+func (zoneAwareBuilder) ParseConfig(raw json.RawMessage) (serviceconfig.LoadBalancingConfig, error) {
+	config := &zoneConfig{}
+	if err := json.Unmarshal(raw, config); err != nil {
+		return nil, fmt.Errorf("parsing zone-aware balancer config %v", err)
+	}
+	return config, nil
+}
+
+// zoneAwareBalancer passes the zone of its config to the picker builder.
+type zoneAwareBalancer struct {
+	balancer.Balancer
+	pickers *zonePickerBuilder
+}
+
+func (b *zoneAwareBalancer) UpdateClientConnState(state balancer.ClientConnState) error {
+	if config, ok := state.BalancerConfig.(*zoneConfig); ok {
+		b.pickers.setZone(config.Zone)
+	}
+	return b.Balancer.UpdateClientConnState(state)
+}
+
+type zonePickerBuilder struct {
+	sync.Mutex
+	zone string
+}
+
+func (p *zonePickerBuilder) setZone(zone string) {
+	p.Lock()
+	defer p.Unlock()
+	p.zone = zone
+}
+
+func (p *zonePickerBuilder) Build(info base.PickerBuildInfo) balancer.Picker {
+	p.Lock()
+	zone := p.zone
+	p.Unlock()
+
+	var local, all []balancer.SubConn
+	for sc, scInfo := range info.ReadySCs {
+		all = append(all, sc)
+		if instInfo, ok := InstanceInfoFromAddress(scInfo.Address); ok && zone != "" && instInfo.Zone == zone {
+			local = append(local, sc)
+		}
+	}
+	if len(all) == 0 {
+		return base.NewErrPicker(balancer.ErrNoSubConnAvailable)
+	}
+	if len(local) > 0 {
+		return &roundRobinPicker{subConns: local}
+	}
+	return &roundRobinPicker{subConns: all}
+}
+
+type roundRobinPicker struct {
+	subConns []balancer.SubConn
+	next     uint32
+}
+
+func (p *roundRobinPicker) Pick(balancer.PickInfo) (balancer.PickResult, error) {
+	n := atomic.AddUint32(&p.next, 1)
+	return balancer.PickResult{SubConn: p.subConns[int(n)%len(p.subConns)]}, nil
+}
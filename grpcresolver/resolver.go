@@ -0,0 +1,235 @@
+// Copyright 2016 IBM Corporation
+//
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+//
+//       http://www.apache.org/licenses/LICENSE-2.0
+//
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+
+// Package grpcresolver resolves gRPC targets of the form eureka:///<vip> from the eureka registry.
+package grpcresolver
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	eureka "github.com/amalgam8/go-eureka-client"
+	"google.golang.org/grpc/attributes"
+	"google.golang.org/grpc/resolver"
+)
+
+const (
+	// Scheme is the gRPC target scheme resolved by the builder.
+	Scheme = "eureka"
+)
+
+// InstanceSource looks up the instances of vip addresses by effective status. It is implemented by
+// eureka.DiscoveryCache.
+type InstanceSource interface {
+	GetInstancesByVipAndStatus(vipAddress string, statuses ...eureka.StatusType) ([]*eureka.Instance, error)
+	GetInstancesBySecVipAndStatus(secVipAddress string, statuses ...eureka.StatusType) ([]*eureka.Instance, error)
+}
+
+// Config defines the way targets are resolved.
+type Config struct {
+	// Statuses are the effective statuses of the resolved instances, default UP.
+	Statuses []eureka.StatusType
+	// Zone is the zone of the client. When set, the resolved service config selects the zone-aware balancer,
+	// which prefers the instances of this zone.
+	Zone string
+}
+
+// InstanceInfo holds the instance information attached to resolved addresses.
+type InstanceInfo struct {
+	ID       string
+	Zone     string
+	Status   eureka.StatusType
+	Metadata map[string]string
+}
+
+// Equal compares the instance information with another attribute value.
+func (i InstanceInfo) Equal(o interface{}) bool {
+	other, ok := o.(InstanceInfo)
+	return ok && reflect.DeepEqual(i, other)
+}
+
+type instanceInfoKey struct{}
+
+// InstanceInfoFromAddress returns the instance information of an address resolved by the builder.
+func InstanceInfoFromAddress(addr resolver.Address) (InstanceInfo, bool) {
+	info, ok := addr.Attributes.Value(instanceInfoKey{}).(InstanceInfo)
+	return info, ok
+}
+
+type builder struct {
+	source InstanceSource
+	events eureka.EventBroadcaster
+	config Config
+}
+
+// NewBuilder creates a resolver builder for targets of the form eureka:///<vip>, or eureka:///<vip>?secure=true
+// to resolve a secure vip address. If events is not nil, resolvers subscribe to it to push address updates as
+// soon as the instances of their vip address change.
+func NewBuilder(source InstanceSource, events eureka.EventBroadcaster, config Config) resolver.Builder {
+	if len(config.Statuses) == 0 {
+		config.Statuses = []eureka.StatusType{eureka.UP}
+	}
+	return &builder{source: source, events: events, config: config}
+}
+
+// Scheme returns the eureka scheme.
+func (b *builder) Scheme() string {
+	return Scheme
+}
+
+// Build creates a resolver for the vip address of the target, and pushes its current addresses.
+func (b *builder) Build(target resolver.Target, cc resolver.ClientConn, opts resolver.BuildOptions) (resolver.Resolver, error) {
+	vip := strings.TrimPrefix(target.URL.Path, "/")
+	if vip == "" {
+		vip = target.URL.Opaque
+	}
+	if vip == "" {
+		return nil, fmt.Errorf("target %s has no vip address", target.URL.String())
+	}
+	secure, _ := strconv.ParseBool(target.URL.Query().Get("secure"))
+
+	r := &vipResolver{builder: b, cc: cc, vip: vip, secure: secure}
+	if b.events != nil {
+		r.unsubscribe = b.events.Subscribe(eureka.EventFuncs{
+			AddFunc:    r.onEvent,
+			UpdateFunc: func(oldInst, newInst *eureka.Instance) { r.onEvent(oldInst); r.onEvent(newInst) },
+			DeleteFunc: r.onEvent,
+		})
+	}
+	r.resolve()
+	return r, nil
+}
+
+type vipResolver struct {
+	sync.Mutex
+	builder     *builder
+	cc          resolver.ClientConn
+	vip         string
+	secure      bool
+	unsubscribe func()
+	closed      bool
+}
+
+// ResolveNow pushes the current addresses of the vip address.
+func (r *vipResolver) ResolveNow(resolver.ResolveNowOptions) {
+	r.resolve()
+}
+
+// Close stops the resolver from pushing updates.
+func (r *vipResolver) Close() {
+	r.Lock()
+	defer r.Unlock()
+	r.closed = true
+	if r.unsubscribe != nil {
+		r.unsubscribe()
+	}
+}
+
+func (r *vipResolver) onEvent(inst *eureka.Instance) {
+	if inst == nil {
+		return
+	}
+	if (!r.secure && inst.VIPAddr == r.vip) || (r.secure && inst.SecVIPAddr == r.vip) {
+		r.resolve()
+	}
+}
+
+// resolve pushes the current addresses of the vip address. The lock is released before calling the ClientConn,
+// which may call back into the resolver.
+func (r *vipResolver) resolve() {
+	r.Lock()
+	if r.closed {
+		r.Unlock()
+		return
+	}
+	state, err := r.state()
+	r.Unlock()
+	if err != nil {
+		r.cc.ReportError(err)
+		return
+	}
+
+	if r.builder.config.Zone != "" {
+		state.ServiceConfig = r.cc.ParseServiceConfig(zoneAwareServiceConfig(r.builder.config.Zone))
+	}
+	if err = r.cc.UpdateState(state); err != nil {
+		r.cc.ReportError(err)
+	}
+}
+
+// state returns the sorted addresses of the instances of the vip address having one of the configured statuses.
+func (r *vipResolver) state() (resolver.State, error) {
+	lookup := r.builder.source.GetInstancesByVipAndStatus
+	if r.secure {
+		lookup = r.builder.source.GetInstancesBySecVipAndStatus
+	}
+	insts, err := lookup(r.vip, r.builder.config.Statuses...)
+	if err != nil {
+		return resolver.State{}, err
+	}
+
+	state := resolver.State{}
+	for _, inst := range insts {
+		addr, ok := r.address(inst)
+		if !ok {
+			continue
+		}
+		state.Addresses = append(state.Addresses, addr)
+		state.Endpoints = append(state.Endpoints, resolver.Endpoint{Addresses: []resolver.Address{addr}})
+	}
+	sort.Slice(state.Addresses, func(i, j int) bool { return state.Addresses[i].Addr < state.Addresses[j].Addr })
+	sort.Slice(state.Endpoints, func(i, j int) bool {
+		return state.Endpoints[i].Addresses[0].Addr < state.Endpoints[j].Addresses[0].Addr
+	})
+	return state, nil
+}
+
+// address returns the address of the instance, on its secure port when resolving a secure vip address.
+func (r *vipResolver) address(inst *eureka.Instance) (resolver.Address, bool) {
+	port := inst.Port
+	if r.secure {
+		port = inst.SecPort
+	}
+	p, ok := port.Number()
+	if !ok || inst.IPAddr == "" {
+		return resolver.Address{}, false
+	}
+
+	info := InstanceInfo{
+		ID:       inst.ID,
+		Status:   inst.EffectiveStatus(),
+		Zone:     inst.AvailabilityZone(),
+		Metadata: map[string]string{},
+	}
+	if len(inst.Metadata) > 0 {
+		var metadata map[string]interface{}
+		if json.Unmarshal(inst.Metadata, &metadata) == nil {
+			for key, value := range metadata {
+				info.Metadata[key] = fmt.Sprintf("%v", value)
+			}
+		}
+	}
+
+	return resolver.Address{
+		Addr:       net.JoinHostPort(inst.IPAddr, strconv.Itoa(p)),
+		ServerName: inst.HostName,
+		Attributes: attributes.New(instanceInfoKey{}, info),
+	}, true
+}
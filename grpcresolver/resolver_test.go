@@ -0,0 +1,261 @@
+// Copyright 2016 IBM Corporation
+//
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+//
+//       http://www.apache.org/licenses/LICENSE-2.0
+//
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+
+package grpcresolver
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	eureka "github.com/amalgam8/go-eureka-client"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/resolver"
+	"google.golang.org/grpc/serviceconfig"
+)
+
+type fakeSource struct {
+	sync.Mutex
+	insts []*eureka.Instance
+}
+
+func (f *fakeSource) GetInstancesByVipAndStatus(vipAddress string, statuses ...eureka.StatusType) ([]*eureka.Instance, error) {
+	f.Lock()
+	defer f.Unlock()
+	var insts []*eureka.Instance
+	found := false
+	for _, inst := range f.insts {
+		if inst.VIPAddr != vipAddress {
+			continue
+		}
+		found = true
+		for _, status := range statuses {
+			if inst.EffectiveStatus() == status {
+				insts = append(insts, inst)
+				break
+			}
+		}
+	}
+	if !found {
+		return nil, fmt.Errorf("vipAddress %s not found", vipAddress)
+	}
+	return insts, nil
+}
+
+func (f *fakeSource) GetInstancesBySecVipAndStatus(secVipAddress string, statuses ...eureka.StatusType) ([]*eureka.Instance, error) {
+	return nil, fmt.Errorf("vipAddress %s not found", secVipAddress)
+}
+
+func (f *fakeSource) set(insts ...*eureka.Instance) {
+	f.Lock()
+	defer f.Unlock()
+	f.insts = insts
+}
+
+func testInstance(id, addr, zone string, status eureka.StatusType) *eureka.Instance {
+	host, port, _ := net.SplitHostPort(addr)
+	p, _ := strconv.Atoi(port)
+	return &eureka.Instance{
+		ID:       id,
+		HostName: id,
+		IPAddr:   host,
+		VIPAddr:  "vip1",
+		Status:   string(status),
+		Port:     &eureka.Port{Enabled: "true", Value: float64(p)},
+		Metadata: []byte(`{"version":"2"}`),
+		Datacenter: &eureka.DatacenterInfo{Name: "Amazon",
+			Metadata: eureka.DatacenterMetadata{"availability-zone": zone}},
+	}
+}
+
+// startHealthServer starts a gRPC server whose "zone" service reports the given status.
+func startHealthServer(t *testing.T, status healthpb.HealthCheckResponse_ServingStatus) (string, func()) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("error = %v", err)
+	}
+	server := grpc.NewServer()
+	healthServer := health.NewServer()
+	healthServer.SetServingStatus("zone", status)
+	healthpb.RegisterHealthServer(server, healthServer)
+	go server.Serve(listener)
+	return listener.Addr().String(), server.Stop
+}
+
+func TestZoneAwareResolution(t *testing.T) {
+	local, stopLocal := startHealthServer(t, healthpb.HealthCheckResponse_SERVING)
+	defer stopLocal()
+	remote, stopRemote := startHealthServer(t, healthpb.HealthCheckResponse_NOT_SERVING)
+	defer stopRemote()
+
+	source := &fakeSource{}
+	source.set(testInstance("local", local, "us-east-1a", eureka.UP),
+		testInstance("remote", remote, "us-east-1b", eureka.UP))
+	builder := NewBuilder(source, nil, Config{Zone: "us-east-1a"})
+
+	conn, err := grpc.NewClient("eureka:///vip1", grpc.WithResolvers(builder),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatalf("error = %v", err)
+	}
+	defer conn.Close()
+
+	client := healthpb.NewHealthClient(conn)
+	check := func() healthpb.HealthCheckResponse_ServingStatus {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		resp, err := client.Check(ctx, &healthpb.HealthCheckRequest{Service: "zone"}, grpc.WaitForReady(true))
+		if err != nil {
+			t.Fatalf("error = %v", err)
+		}
+		return resp.Status
+	}
+
+	// Requests fall back to the remote zone until the connection to the local zone is ready
+	deadline := time.Now().Add(5 * time.Second)
+	for check() != healthpb.HealthCheckResponse_SERVING {
+		if time.Now().After(deadline) {
+			t.Fatal("no request was served by the instance of the local zone")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	for i := 0; i < 10; i++ {
+		if check() != healthpb.HealthCheckResponse_SERVING {
+			t.Fatalf("request %d was not served by the instance of the local zone", i)
+		}
+	}
+}
+
+// testClientConn records the states pushed by a resolver.
+type testClientConn struct {
+	resolver.ClientConn
+	sync.Mutex
+	states []resolver.State
+}
+
+func (cc *testClientConn) UpdateState(state resolver.State) error {
+	cc.Lock()
+	defer cc.Unlock()
+	cc.states = append(cc.states, state)
+	return nil
+}
+
+func (cc *testClientConn) ReportError(error) {}
+
+func (cc *testClientConn) ParseServiceConfig(string) *serviceconfig.ParseResult {
+	return nil
+}
+
+func (cc *testClientConn) last() resolver.State {
+	cc.Lock()
+	defer cc.Unlock()
+	return cc.states[len(cc.states)-1]
+}
+
+func TestResolverPushesUpdatesOnEvents(t *testing.T) {
+	source := &fakeSource{}
+	first := testInstance("inst1", "10.0.0.1:8080", "us-east-1a", eureka.UP)
+	source.set(first, testInstance("inst2", "10.0.0.2:8080", "us-east-1a", eureka.DOWN))
+	events := eureka.NewEventBroadcaster()
+	builder := NewBuilder(source, events, Config{})
+
+	cc := &testClientConn{}
+	target := resolver.Target{}
+	target.URL.Path = "/vip1"
+	r, err := builder.Build(target, cc, resolver.BuildOptions{})
+	if err != nil {
+		t.Fatalf("error = %v", err)
+	}
+	defer r.Close()
+
+	state := cc.last()
+	if len(state.Addresses) != 1 || state.Addresses[0].Addr != "10.0.0.1:8080" {
+		t.Fatalf("addresses = %v, expected the UP instance only", state.Addresses)
+	}
+	info, ok := InstanceInfoFromAddress(state.Addresses[0])
+	if !ok || info.Zone != "us-east-1a" || info.Status != eureka.UP || info.Metadata["version"] != "2" {
+		t.Errorf("unexpected instance info %+v", info)
+	}
+
+	second := testInstance("inst2", "10.0.0.2:8080", "us-east-1a", eureka.UP)
+	source.set(first, second)
+	events.OnUpdate(second, second)
+	if state = cc.last(); len(state.Addresses) != 2 {
+		t.Errorf("addresses = %v, expected an update after the event", state.Addresses)
+	}
+
+	other := testInstance("inst3", "10.0.0.3:8080", "us-east-1a", eureka.UP)
+	other.VIPAddr = "vip2"
+	count := len(cc.states)
+	events.OnAdd(other)
+	if len(cc.states) != count {
+		t.Error("events of other vip addresses should not push updates")
+	}
+}
+
+// reentrantClientConn resolves again from its first state update, as a channel may do when its balancer
+// asks for a resolution.
+type reentrantClientConn struct {
+	testClientConn
+	resolver resolver.Resolver
+}
+
+func (cc *reentrantClientConn) UpdateState(state resolver.State) error {
+	cc.testClientConn.UpdateState(state)
+	cc.Lock()
+	r := cc.resolver
+	cc.resolver = nil
+	cc.Unlock()
+	if r != nil {
+		r.ResolveNow(resolver.ResolveNowOptions{})
+	}
+	return nil
+}
+
+func TestResolverReleasesLockBeforeUpdatingState(t *testing.T) {
+	source := &fakeSource{}
+	source.set(testInstance("inst1", "10.0.0.1:8080", "us-east-1a", eureka.UP))
+	cc := &reentrantClientConn{}
+	target := resolver.Target{}
+	target.URL.Path = "/vip1"
+	r, err := NewBuilder(source, nil, Config{}).Build(target, cc, resolver.BuildOptions{})
+	if err != nil {
+		t.Fatalf("error = %v", err)
+	}
+	defer r.Close()
+
+	cc.Lock()
+	cc.resolver = r
+	cc.Unlock()
+	done := make(chan struct{})
+	go func() {
+		r.ResolveNow(resolver.ResolveNowOptions{})
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("resolving from a state update should not deadlock")
+	}
+	if len(cc.states) != 3 {
+		t.Errorf("expected 3 state updates, got %d", len(cc.states))
+	}
+}
@@ -0,0 +1,352 @@
+// Copyright 2016 IBM Corporation
+//
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+//
+//       http://www.apache.org/licenses/LICENSE-2.0
+//
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+
+//Package goEurekaClient Implements a go client that interacts with a eureka server
+package goEurekaClient
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+)
+
+const (
+	defaultHealthCheckInterval = 10 * time.Second
+	defaultHealthCheckTimeout  = 5 * time.Second
+	defaultFailureThreshold    = 3
+	defaultSuccessThreshold    = 2
+)
+
+// HealthCheck probes the health of a local component.
+// A nil error means the component is healthy.
+type HealthCheck interface {
+	Check(ctx context.Context) error
+}
+
+// HealthCheckFunc adapts an ordinary function to the HealthCheck interface.
+type HealthCheckFunc func(ctx context.Context) error
+
+// Check calls f(ctx).
+func (f HealthCheckFunc) Check(ctx context.Context) error {
+	return f(ctx)
+}
+
+type httpHealthCheck struct {
+	url        string
+	httpClient *http.Client
+}
+
+// NewHTTPHealthCheck creates a health check which sends a GET request to the given url.
+// The component is healthy if the response status code is 2xx.
+func NewHTTPHealthCheck(url string) HealthCheck {
+	return &httpHealthCheck{url: url, httpClient: &http.Client{}}
+}
+
+// Check sends a GET request to the health check url.
+func (h *httpHealthCheck) Check(ctx context.Context) error {
+	req, err := http.NewRequest("GET", h.url, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := h.httpClient.Do(req.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("bad response for health check request. response is %v", resp.Status)
+	}
+	return nil
+}
+
+type tcpHealthCheck struct {
+	address string
+}
+
+// NewTCPHealthCheck creates a health check which dials the given address.
+// The component is healthy if the connection is established.
+func NewTCPHealthCheck(address string) HealthCheck {
+	return &tcpHealthCheck{address: address}
+}
+
+// Check dials the address and closes the connection.
+func (h *tcpHealthCheck) Check(ctx context.Context) error {
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", h.address)
+	if err != nil {
+		return err
+	}
+	return conn.Close()
+}
+
+// HealthCheckDefinition defines a named health check and the status reported while it fails.
+type HealthCheckDefinition struct {
+	Name          string
+	Check         HealthCheck
+	FailureStatus StatusType // DOWN or OUTOFSERVICE, default DOWN
+}
+
+// HealthReporterConfig defines the way health checks are run and aggregated.
+type HealthReporterConfig struct {
+	Interval         time.Duration // default 10s
+	Timeout          time.Duration // default 5s, per check
+	FailureThreshold int           // consecutive failures before a healthy check is considered failing, default 3
+	SuccessThreshold int           // consecutive successes before a failing check is considered healthy, default 2
+}
+
+// HealthReporter periodically runs health checks and reports the aggregated status of an instance
+// to the registry. It can also serve the status page and health check URLs of the instance.
+type HealthReporter interface {
+	Run(ctx context.Context)
+	Status() StatusType
+	Handler() http.Handler
+}
+
+type checkState struct {
+	definition HealthCheckDefinition
+	evaluated  bool
+	healthy    bool
+	successes  int
+	failures   int
+	lastError  error
+}
+
+type healthReporter struct {
+	sync.Mutex
+	registrator Registrator
+	instance    *Instance
+	config      HealthReporterConfig
+	checks      []*checkState
+	status      StatusType
+	reported    StatusType
+}
+
+// NewHealthReporter creates a reporter which updates the status of inst through the registrator,
+// according to the given health checks.
+// DOWN takes precedence over OUT_OF_SERVICE when several checks fail.
+func NewHealthReporter(registrator Registrator, inst *Instance, config HealthReporterConfig,
+	checks ...HealthCheckDefinition) (HealthReporter, error) {
+	if registrator == nil {
+		return nil, errors.New("registrator must be defined")
+	}
+	if inst == nil {
+		return nil, errors.New("instance must be defined")
+	}
+	if len(checks) == 0 {
+		return nil, errors.New("at least one health check must be defined")
+	}
+
+	if config.Interval <= 0 {
+		config.Interval = defaultHealthCheckInterval
+	}
+	if config.Timeout <= 0 {
+		config.Timeout = defaultHealthCheckTimeout
+	}
+	if config.FailureThreshold <= 0 {
+		config.FailureThreshold = defaultFailureThreshold
+	}
+	if config.SuccessThreshold <= 0 {
+		config.SuccessThreshold = defaultSuccessThreshold
+	}
+
+	states := make([]*checkState, len(checks))
+	for i, check := range checks {
+		if check.Check == nil {
+			return nil, fmt.Errorf("health check %q is not defined", check.Name)
+		}
+		if check.FailureStatus == "" {
+			check.FailureStatus = DOWN
+		}
+		if check.FailureStatus != DOWN && check.FailureStatus != OUTOFSERVICE {
+			return nil, fmt.Errorf("health check %q has invalid failure status %v", check.Name, check.FailureStatus)
+		}
+		states[i] = &checkState{definition: check}
+	}
+
+	return &healthReporter{
+		registrator: registrator,
+		instance:    inst,
+		config:      config,
+		checks:      states,
+		status:      STARTING,
+	}, nil
+}
+
+// Run runs the health checks until the context is done.
+func (h *healthReporter) Run(ctx context.Context) {
+	h.evaluate(ctx)
+
+	ticker := time.NewTicker(h.config.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			h.evaluate(ctx)
+		case <-ctx.Done():
+			log.Printf("stop chan received. stop running health checks...")
+			return
+		}
+	}
+}
+
+// Status returns the current aggregated status.
+func (h *healthReporter) Status() StatusType {
+	h.Lock()
+	defer h.Unlock()
+	return h.status
+}
+
+// evaluate runs all health checks once, and reports the aggregated status if it changed.
+func (h *healthReporter) evaluate(ctx context.Context) {
+	results := make([]error, len(h.checks))
+	var wg sync.WaitGroup
+	for i, state := range h.checks {
+		wg.Add(1)
+		go func(i int, check HealthCheck) {
+			defer wg.Done()
+			checkCtx, cancel := context.WithTimeout(ctx, h.config.Timeout)
+			defer cancel()
+			results[i] = check.Check(checkCtx)
+		}(i, state.definition.Check)
+	}
+	wg.Wait()
+
+	h.Lock()
+	status := UP
+	for i, state := range h.checks {
+		state.record(results[i], h.config)
+		if !state.healthy {
+			if state.definition.FailureStatus == DOWN || status == UP {
+				status = state.definition.FailureStatus
+			}
+		}
+	}
+	h.status = status
+	reported := h.reported
+	h.Unlock()
+
+	if status == reported {
+		return
+	}
+	if err := h.registrator.SetStatus(h.instance, status); err != nil {
+		log.Printf("Failed to report status %v. error: %s\n", status, err)
+		return
+	}
+
+	h.Lock()
+	h.reported = status
+	h.Unlock()
+}
+
+// record applies the result of a check, changing its health only after crossing the thresholds.
+// The first result is applied immediately.
+func (s *checkState) record(err error, config HealthReporterConfig) {
+	s.lastError = err
+	if err == nil {
+		s.successes++
+		s.failures = 0
+	} else {
+		s.failures++
+		s.successes = 0
+	}
+
+	switch {
+	case !s.evaluated:
+		s.evaluated = true
+		s.healthy = err == nil
+	case s.healthy && s.failures >= config.FailureThreshold:
+		s.healthy = false
+	case !s.healthy && s.successes >= config.SuccessThreshold:
+		s.healthy = true
+	}
+}
+
+type checkReport struct {
+	Status StatusType `json:"status"`
+	Error  string     `json:"error,omitempty"`
+}
+
+type healthReport struct {
+	Status StatusType             `json:"status"`
+	Checks map[string]checkReport `json:"checks"`
+}
+
+func (h *healthReporter) report() healthReport {
+	h.Lock()
+	defer h.Unlock()
+
+	report := healthReport{Status: h.status, Checks: map[string]checkReport{}}
+	for _, state := range h.checks {
+		check := checkReport{Status: UP}
+		if !state.evaluated {
+			check.Status = STARTING
+		} else if !state.healthy {
+			check.Status = state.definition.FailureStatus
+		}
+		if state.lastError != nil {
+			check.Error = state.lastError.Error()
+		}
+		report.Checks[state.definition.Name] = check
+	}
+	return report
+}
+
+// Handler returns an HTTP handler serving the paths of the instance status page and health check URLs.
+// The health check responds with 503 unless the instance is UP, while the status page always responds with 200.
+// Serving the handler is optional: an application which serves these URLs itself does not mount it, and may
+// probe them with NewHTTPHealthCheck. The checks must not probe the URLs served by the handler, since its
+// health check would never respond with 200 before the instance is UP.
+func (h *healthReporter) Handler() http.Handler {
+	mux := http.NewServeMux()
+	if path := urlPath(h.instance.HealthCheck); path != "" {
+		mux.HandleFunc(path, func(w http.ResponseWriter, r *http.Request) {
+			report := h.report()
+			code := http.StatusOK
+			if report.Status != UP {
+				code = http.StatusServiceUnavailable
+			}
+			writeHealthReport(w, code, report)
+		})
+	}
+	if path := urlPath(h.instance.StatusPage); path != "" && path != urlPath(h.instance.HealthCheck) {
+		mux.HandleFunc(path, func(w http.ResponseWriter, r *http.Request) {
+			writeHealthReport(w, http.StatusOK, h.report())
+		})
+	}
+	return mux
+}
+
+func writeHealthReport(w http.ResponseWriter, code int, report healthReport) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(report)
+}
+
+func urlPath(rawURL string) string {
+	if rawURL == "" {
+		return ""
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Path == "" {
+		return ""
+	}
+	return u.Path
+}
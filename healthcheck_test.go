@@ -0,0 +1,190 @@
+// Copyright 2016 IBM Corporation
+//
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+//
+//       http://www.apache.org/licenses/LICENSE-2.0
+//
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+
+//Package goEurekaClient Implements a go client that interacts with a eureka server
+package goEurekaClient
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+)
+
+// statusRecorder is a Registrator which records the status changes it is asked to perform.
+type statusRecorder struct {
+	Registrator
+	sync.Mutex
+	statuses []StatusType
+}
+
+func (r *statusRecorder) SetStatus(inst *Instance, status StatusType) error {
+	r.Lock()
+	defer r.Unlock()
+	r.statuses = append(r.statuses, status)
+	return nil
+}
+
+func (r *statusRecorder) last() StatusType {
+	r.Lock()
+	defer r.Unlock()
+	if len(r.statuses) == 0 {
+		return ""
+	}
+	return r.statuses[len(r.statuses)-1]
+}
+
+func TestHealthReporterHysteresis(t *testing.T) {
+	var failing bool
+	check := HealthCheckFunc(func(ctx context.Context) error {
+		if failing {
+			return errors.New("failing")
+		}
+		return nil
+	})
+
+	recorder := &statusRecorder{}
+	inst := &Instance{Application: "APP1", HostName: "inst1"}
+	reporter, err := NewHealthReporter(recorder, inst, HealthReporterConfig{FailureThreshold: 2, SuccessThreshold: 2},
+		HealthCheckDefinition{Name: "func", Check: check})
+	if err != nil {
+		t.Fatalf("error = %v", err)
+	}
+	h := reporter.(*healthReporter)
+
+	h.evaluate(context.Background())
+	if recorder.last() != UP || len(recorder.statuses) != 1 {
+		t.Fatalf("first evaluation should report UP, got %v", recorder.statuses)
+	}
+
+	failing = true
+	h.evaluate(context.Background())
+	if h.Status() != UP {
+		t.Errorf("a single failure should not change the status, got %v", h.Status())
+	}
+	h.evaluate(context.Background())
+	if h.Status() != DOWN || recorder.last() != DOWN {
+		t.Errorf("status should be DOWN after crossing the failure threshold, got %v", h.Status())
+	}
+
+	failing = false
+	h.evaluate(context.Background())
+	if h.Status() != DOWN {
+		t.Errorf("a single success should not change the status, got %v", h.Status())
+	}
+	h.evaluate(context.Background())
+	if h.Status() != UP || recorder.last() != UP {
+		t.Errorf("status should be UP after crossing the success threshold, got %v", h.Status())
+	}
+	if len(recorder.statuses) != 3 {
+		t.Errorf("status should be reported only on changes, got %v", recorder.statuses)
+	}
+}
+
+func TestHealthReporterAggregation(t *testing.T) {
+	maintenance := HealthCheckFunc(func(ctx context.Context) error { return errors.New("maintenance") })
+	broken := HealthCheckFunc(func(ctx context.Context) error { return errors.New("broken") })
+	healthy := HealthCheckFunc(func(ctx context.Context) error { return nil })
+
+	recorder := &statusRecorder{}
+	inst := &Instance{Application: "APP1", HostName: "inst1"}
+	reporter, _ := NewHealthReporter(recorder, inst, HealthReporterConfig{},
+		HealthCheckDefinition{Name: "healthy", Check: healthy},
+		HealthCheckDefinition{Name: "maintenance", Check: maintenance, FailureStatus: OUTOFSERVICE})
+	reporter.(*healthReporter).evaluate(context.Background())
+	if reporter.Status() != OUTOFSERVICE {
+		t.Errorf("status should be OUT_OF_SERVICE, got %v", reporter.Status())
+	}
+
+	reporter, _ = NewHealthReporter(recorder, inst, HealthReporterConfig{},
+		HealthCheckDefinition{Name: "maintenance", Check: maintenance, FailureStatus: OUTOFSERVICE},
+		HealthCheckDefinition{Name: "broken", Check: broken})
+	reporter.(*healthReporter).evaluate(context.Background())
+	if reporter.Status() != DOWN {
+		t.Errorf("DOWN should take precedence, got %v", reporter.Status())
+	}
+}
+
+func TestHealthChecks(t *testing.T) {
+	code := http.StatusOK
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(code)
+	}))
+	defer server.Close()
+
+	httpCheck := NewHTTPHealthCheck(server.URL + "/health")
+	err := httpCheck.Check(context.Background())
+	if err != nil {
+		t.Errorf("http check should pass, error = %v", err)
+	}
+	code = http.StatusInternalServerError
+	if err = httpCheck.Check(context.Background()); err == nil {
+		t.Error("http check should fail on 500")
+	}
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("error = %v", err)
+	}
+	address := listener.Addr().String()
+	tcpCheck := NewTCPHealthCheck(address)
+	if err = tcpCheck.Check(context.Background()); err != nil {
+		t.Errorf("tcp check should pass, error = %v", err)
+	}
+	listener.Close()
+	if err = tcpCheck.Check(context.Background()); err == nil {
+		t.Error("tcp check should fail on a closed port")
+	}
+}
+
+func TestHealthReporterHandler(t *testing.T) {
+	var failing bool
+	check := HealthCheckFunc(func(ctx context.Context) error {
+		if failing {
+			return errors.New("failing")
+		}
+		return nil
+	})
+	inst := &Instance{
+		Application: "APP1",
+		HostName:    "inst1",
+		StatusPage:  "http://inst1:8080/info",
+		HealthCheck: "http://inst1:8080/health",
+	}
+	reporter, _ := NewHealthReporter(&statusRecorder{}, inst, HealthReporterConfig{FailureThreshold: 1},
+		HealthCheckDefinition{Name: "func", Check: check})
+	reporter.(*healthReporter).evaluate(context.Background())
+
+	server := httptest.NewServer(reporter.Handler())
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + "/health")
+	if err != nil || resp.StatusCode != http.StatusOK {
+		t.Errorf("health check should respond with 200, got %v (%v)", resp, err)
+	}
+
+	failing = true
+	reporter.(*healthReporter).evaluate(context.Background())
+	resp, err = http.Get(server.URL + "/health")
+	if err != nil || resp.StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("health check should respond with 503, got %v (%v)", resp, err)
+	}
+	resp, err = http.Get(server.URL + "/info")
+	if err != nil || resp.StatusCode != http.StatusOK {
+		t.Errorf("status page should respond with 200, got %v (%v)", resp, err)
+	}
+}
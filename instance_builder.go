@@ -0,0 +1,311 @@
+// Copyright 2016 IBM Corporation
+//
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+//
+//       http://www.apache.org/licenses/LICENSE-2.0
+//
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+
+//Package goEurekaClient Implements a go client that interacts with a eureka server
+package goEurekaClient
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	defaultDatacenterClass = "com.netflix.appinfo.InstanceInfo$DefaultDataCenterInfo"
+	defaultDatacenterName  = "MyOwn"
+	defaultRenewalInterval = 30 * time.Second
+	defaultLeaseDuration   = 90 * time.Second
+	defaultPort            = 80
+	defaultSecurePort      = 443
+
+	// DefaultHomePageURL is the default home page URL template
+	DefaultHomePageURL = "{scheme}://{hostName}:{activePort}/"
+	// DefaultStatusPageURL is the default status page URL template
+	DefaultStatusPageURL = "{scheme}://{hostName}:{activePort}/info"
+	// DefaultHealthCheckURL is the default health check URL template
+	DefaultHealthCheckURL = "{scheme}://{hostName}:{activePort}/health"
+)
+
+// InstanceBuilder builds instances ready for registration.
+// The host name and IP address are detected when not set explicitly.
+// URL templates may refer to {hostName}, {ipAddr}, {port}, {securePort}, {app},
+// and to {scheme} and {activePort}, which refer to the non-secure port unless only the secure port is enabled.
+type InstanceBuilder struct {
+	inst                Instance
+	port                int
+	securePort          int
+	homePageTemplate    string
+	statusPageTemplate  string
+	healthCheckTemplate string
+	renewalInterval     time.Duration
+	leaseDuration       time.Duration
+	metadata            map[string]string
+}
+
+// NewInstanceBuilder creates a builder for an instance of the given application.
+func NewInstanceBuilder(appName string) *InstanceBuilder {
+	return &InstanceBuilder{
+		inst:                Instance{Application: appName, Status: string(UP)},
+		homePageTemplate:    DefaultHomePageURL,
+		statusPageTemplate:  DefaultStatusPageURL,
+		healthCheckTemplate: DefaultHealthCheckURL,
+		renewalInterval:     defaultRenewalInterval,
+		leaseDuration:       defaultLeaseDuration,
+		metadata:            map[string]string{},
+	}
+}
+
+// HostName sets the host name of the instance, instead of the detected one.
+func (b *InstanceBuilder) HostName(hostName string) *InstanceBuilder {
+	b.inst.HostName = hostName
+	return b
+}
+
+// IPAddr sets the IP address of the instance, instead of the detected one.
+func (b *InstanceBuilder) IPAddr(ipAddr string) *InstanceBuilder {
+	b.inst.IPAddr = ipAddr
+	return b
+}
+
+// VIPAddress sets the vip address of the instance.
+func (b *InstanceBuilder) VIPAddress(vipAddress string) *InstanceBuilder {
+	b.inst.VIPAddr = vipAddress
+	return b
+}
+
+// SecureVIPAddress sets the secured vip address of the instance.
+func (b *InstanceBuilder) SecureVIPAddress(secVipAddress string) *InstanceBuilder {
+	b.inst.SecVIPAddr = secVipAddress
+	return b
+}
+
+// GroupName sets the application group of the instance.
+func (b *InstanceBuilder) GroupName(groupName string) *InstanceBuilder {
+	b.inst.GroupName = groupName
+	return b
+}
+
+// Status sets the initial status of the instance. The default is UP.
+func (b *InstanceBuilder) Status(status StatusType) *InstanceBuilder {
+	b.inst.Status = string(status)
+	return b
+}
+
+// Port enables the non-secure port of the instance.
+func (b *InstanceBuilder) Port(port int) *InstanceBuilder {
+	b.port = port
+	return b
+}
+
+// SecurePort enables the secure port of the instance.
+func (b *InstanceBuilder) SecurePort(port int) *InstanceBuilder {
+	b.securePort = port
+	return b
+}
+
+// HomePageURL sets the home page URL template.
+func (b *InstanceBuilder) HomePageURL(template string) *InstanceBuilder {
+	b.homePageTemplate = template
+	return b
+}
+
+// StatusPageURL sets the status page URL template.
+func (b *InstanceBuilder) StatusPageURL(template string) *InstanceBuilder {
+	b.statusPageTemplate = template
+	return b
+}
+
+// HealthCheckURL sets the health check URL template.
+func (b *InstanceBuilder) HealthCheckURL(template string) *InstanceBuilder {
+	b.healthCheckTemplate = template
+	return b
+}
+
+// Datacenter sets the datacenter information of the instance. The default is MyOwn.
+func (b *InstanceBuilder) Datacenter(dcinfo *DatacenterInfo) *InstanceBuilder {
+	b.inst.Datacenter = dcinfo
+	return b
+}
+
+// Lease sets the lease renewal interval and duration of the instance. The defaults are 30s and 90s.
+func (b *InstanceBuilder) Lease(renewalInterval, duration time.Duration) *InstanceBuilder {
+	b.renewalInterval = renewalInterval
+	b.leaseDuration = duration
+	return b
+}
+
+// Metadata sets a metadata key of the instance.
+func (b *InstanceBuilder) Metadata(key, value string) *InstanceBuilder {
+	b.metadata[key] = value
+	return b
+}
+
+// Build creates the instance, filling in the detected and default values, and validates it.
+func (b *InstanceBuilder) Build() (*Instance, error) {
+	inst := b.inst
+
+	if inst.HostName == "" {
+		hostName, err := os.Hostname()
+		if err != nil {
+			return nil, fmt.Errorf("detecting host name: %v", err)
+		}
+		inst.HostName = hostName
+	}
+	if inst.IPAddr == "" {
+		ip, err := preferredIP()
+		if err != nil {
+			return nil, fmt.Errorf("detecting IP address: %v", err)
+		}
+		inst.IPAddr = ip
+	}
+
+	inst.Port = &Port{Enabled: "false", Value: defaultPort}
+	if b.port != 0 {
+		inst.Port = &Port{Enabled: "true", Value: b.port}
+	}
+	inst.SecPort = &Port{Enabled: "false", Value: defaultSecurePort}
+	if b.securePort != 0 {
+		inst.SecPort = &Port{Enabled: "true", Value: b.securePort}
+	}
+
+	if inst.Datacenter == nil {
+		inst.Datacenter = &DatacenterInfo{Class: defaultDatacenterClass, Name: defaultDatacenterName}
+	}
+	inst.Lease = &LeaseInfo{
+		RenewalInt:  uint32(b.renewalInterval / time.Second),
+		DurationInt: uint32(b.leaseDuration / time.Second),
+	}
+
+	if len(b.metadata) > 0 {
+		metadata, err := json.Marshal(b.metadata)
+		if err != nil {
+			return nil, err
+		}
+		inst.Metadata = metadata
+	}
+
+	scheme, activePort := "http", b.port
+	if b.port == 0 && b.securePort != 0 {
+		scheme, activePort = "https", b.securePort
+	}
+	replacer := strings.NewReplacer(
+		"{scheme}", scheme,
+		"{hostName}", inst.HostName,
+		"{ipAddr}", inst.IPAddr,
+		"{app}", strings.ToLower(inst.Application),
+		"{activePort}", strconv.Itoa(activePort),
+		"{port}", strconv.Itoa(b.port),
+		"{securePort}", strconv.Itoa(b.securePort))
+	inst.HomePage = replacer.Replace(b.homePageTemplate)
+	inst.StatusPage = replacer.Replace(b.statusPageTemplate)
+	inst.HealthCheck = replacer.Replace(b.healthCheckTemplate)
+
+	if err := inst.Validate(); err != nil {
+		return nil, err
+	}
+
+	id, err := resolveInstanceID(&inst)
+	if err != nil {
+		return nil, err
+	}
+	inst.ID = id
+
+	return &inst, nil
+}
+
+// Validate verifies that the fields required for registration are set.
+func (ir *Instance) Validate() error {
+	var problems []string
+	if ir.Application == "" {
+		problems = append(problems, "application name must be defined")
+	}
+	if ir.HostName == "" {
+		problems = append(problems, "host name must be defined")
+	}
+	if ir.IPAddr == "" {
+		problems = append(problems, "IP address must be defined")
+	} else if net.ParseIP(ir.IPAddr) == nil {
+		problems = append(problems, fmt.Sprintf("IP address %q is not valid", ir.IPAddr))
+	}
+	if ir.Status != "" && validateStatus(StatusType(ir.Status)) != nil {
+		problems = append(problems, fmt.Sprintf("status %q is not valid", ir.Status))
+	}
+	if ir.Datacenter == nil || ir.Datacenter.Name == "" {
+		problems = append(problems, "datacenter name must be defined")
+	}
+
+	portEnabled := false
+	for _, port := range []struct {
+		name string
+		port *Port
+	}{{"port", ir.Port}, {"secure port", ir.SecPort}} {
+		if port.port == nil || port.port.Enabled != "true" {
+			continue
+		}
+		portEnabled = true
+		if _, ok := port.port.Number(); !ok {
+			problems = append(problems, fmt.Sprintf("%s %v is not valid", port.name, port.port.Value))
+		}
+	}
+	if !portEnabled {
+		problems = append(problems, "at least one port must be enabled")
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("invalid instance: %s", strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+// preferredIP returns the first IPv4 address of an up, non-loopback interface.
+// If there is no such address, a global unicast IPv6 address is returned.
+func preferredIP() (string, error) {
+	ifaces, err := net.Interfaces()
+	if err != nil {
+		return "", err
+	}
+
+	var ipv6 string
+	for _, iface := range ifaces {
+		if iface.Flags&net.FlagUp == 0 || iface.Flags&net.FlagLoopback != 0 {
+			continue
+		}
+		addrs, err := iface.Addrs()
+		if err != nil {
+			continue
+		}
+		for _, addr := range addrs {
+			ipNet, ok := addr.(*net.IPNet)
+			if !ok || !ipNet.IP.IsGlobalUnicast() {
+				continue
+			}
+			if ip4 := ipNet.IP.To4(); ip4 != nil {
+				return ip4.String(), nil
+			}
+			if ipv6 == "" {
+				ipv6 = ipNet.IP.String()
+			}
+		}
+	}
+
+	if ipv6 == "" {
+		return "", errors.New("no non-loopback IP address found")
+	}
+	return ipv6, nil
+}
@@ -0,0 +1,109 @@
+// Copyright 2016 IBM Corporation
+//
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+//
+//       http://www.apache.org/licenses/LICENSE-2.0
+//
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+
+//Package goEurekaClient Implements a go client that interacts with a eureka server
+package goEurekaClient
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestInstanceBuilder(t *testing.T) {
+	inst, err := NewInstanceBuilder("APP1").
+		HostName("inst1").
+		IPAddr("10.0.0.1").
+		VIPAddress("vip1").
+		Port(8080).
+		Lease(10*time.Second, 30*time.Second).
+		Metadata("zone", "zone1").
+		HealthCheckURL("http://{ipAddr}:{port}/{app}/health").
+		Build()
+	if err != nil {
+		t.Fatalf("error = %v", err)
+	}
+
+	if inst.ID != "inst1" {
+		t.Errorf("instance id should be resolved from the host name, got %s", inst.ID)
+	}
+	if inst.Port.Enabled != "true" || inst.Port.Value != 8080 || inst.SecPort.Enabled != "false" {
+		t.Errorf("unexpected ports %v %v", inst.Port, inst.SecPort)
+	}
+	if inst.HomePage != "http://inst1:8080/" || inst.StatusPage != "http://inst1:8080/info" {
+		t.Errorf("unexpected default URLs %s %s", inst.HomePage, inst.StatusPage)
+	}
+	if inst.HealthCheck != "http://10.0.0.1:8080/app1/health" {
+		t.Errorf("unexpected health check URL %s", inst.HealthCheck)
+	}
+	if inst.Datacenter.Name != defaultDatacenterName || inst.Lease.RenewalInt != 10 || inst.Lease.DurationInt != 30 {
+		t.Errorf("unexpected datacenter %v or lease %v", inst.Datacenter, inst.Lease)
+	}
+
+	var metadata map[string]string
+	if err = json.Unmarshal(inst.Metadata, &metadata); err != nil || metadata["zone"] != "zone1" {
+		t.Errorf("unexpected metadata %s", inst.Metadata)
+	}
+}
+
+func TestInstanceBuilderSecurePortOnly(t *testing.T) {
+	inst, err := NewInstanceBuilder("APP1").HostName("inst1").IPAddr("10.0.0.1").SecurePort(8443).Build()
+	if err != nil {
+		t.Fatalf("error = %v", err)
+	}
+	if inst.Port.Enabled != "false" || inst.SecPort.Enabled != "true" {
+		t.Errorf("unexpected ports %v %v", inst.Port, inst.SecPort)
+	}
+	if inst.HomePage != "https://inst1:8443/" {
+		t.Errorf("unexpected home page URL %s", inst.HomePage)
+	}
+}
+
+func TestInstanceBuilderDetectsAddress(t *testing.T) {
+	inst, err := NewInstanceBuilder("APP1").Port(8080).Build()
+	if err != nil {
+		// Sandboxes without a non-loopback interface can't detect an address
+		if strings.Contains(err.Error(), "detecting") {
+			t.Skip(err)
+		}
+		t.Fatalf("error = %v", err)
+	}
+	if inst.HostName == "" || inst.IPAddr == "" {
+		t.Errorf("host name and IP address should be detected, got %q %q", inst.HostName, inst.IPAddr)
+	}
+}
+
+func TestInstanceValidate(t *testing.T) {
+	_, err := NewInstanceBuilder("").HostName("inst1").IPAddr("not-an-ip").Port(70000).Build()
+	if err == nil {
+		t.Fatal("invalid instance should be rejected")
+	}
+	for _, problem := range []string{"application name", "IP address \"not-an-ip\"", "port 70000"} {
+		if !strings.Contains(err.Error(), problem) {
+			t.Errorf("error should report %s, got %v", problem, err)
+		}
+	}
+
+	_, err = NewInstanceBuilder("APP1").HostName("inst1").IPAddr("10.0.0.1").Build()
+	if err == nil || !strings.Contains(err.Error(), "at least one port") {
+		t.Errorf("instance without ports should be rejected, got %v", err)
+	}
+
+	inst := &Instance{Application: "APP1", HostName: "inst1", IPAddr: "10.0.0.1",
+		Datacenter: &DatacenterInfo{Name: "MyOwn"}, Port: &Port{Enabled: "true", Value: float64(8080)}}
+	if err = inst.Validate(); err != nil {
+		t.Errorf("instance unmarshaled from JSON should be valid, error = %v", err)
+	}
+}
@@ -0,0 +1,89 @@
+// Copyright 2016 IBM Corporation
+//
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+//
+//       http://www.apache.org/licenses/LICENSE-2.0
+//
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+
+//Package goEurekaClient Implements a go client that interacts with a eureka server
+package goEurekaClient
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	kubernetesDatacenterName = "Kubernetes"
+	kubernetesPodUID         = "pod-uid"
+)
+
+// kubernetesPodFields maps the datacenter metadata keys to the environment variables and downward API
+// volume files exposing them. Environment variables take precedence over files.
+var kubernetesPodFields = []struct {
+	key      string
+	env      string
+	file     string
+	optional bool
+}{
+	{kubernetesPodUID, "POD_UID", "uid", false},
+	{"pod-name", "POD_NAME", "name", false},
+	{"pod-namespace", "POD_NAMESPACE", "namespace", false},
+	{"pod-ip", "POD_IP", "ip", true},
+	{"node-name", "NODE_NAME", "node", true},
+}
+
+// Kubernetes datacenter information
+type kubernetesIdentifier struct{}
+
+// Returns the unique identifier of this datacenter info
+func (kubernetes *kubernetesIdentifier) GetID(dcinfo *DatacenterInfo) string {
+	return metadataString(dcinfo, kubernetesPodUID)
+}
+
+type kubernetesMetadataProvider struct {
+	podInfoDir string
+}
+
+// NewKubernetesMetadataProvider creates a provider which reads the pod information exposed through the
+// downward API, either as environment variables (POD_UID, POD_NAME, POD_NAMESPACE, POD_IP, NODE_NAME)
+// or as files (uid, name, namespace, ip, node) in podInfoDir. An empty podInfoDir means environment variables only.
+func NewKubernetesMetadataProvider(podInfoDir string) DatacenterInfoProvider {
+	return &kubernetesMetadataProvider{podInfoDir: podInfoDir}
+}
+
+// DatacenterInfo returns the Kubernetes datacenter information of the running pod.
+func (p *kubernetesMetadataProvider) DatacenterInfo() (*DatacenterInfo, error) {
+	metadata := DatacenterMetadata{}
+	for _, field := range kubernetesPodFields {
+		value := os.Getenv(field.env)
+		if value == "" && p.podInfoDir != "" {
+			if content, err := ioutil.ReadFile(filepath.Join(p.podInfoDir, field.file)); err == nil {
+				value = strings.TrimSpace(string(content))
+			}
+		}
+		if value == "" {
+			if !field.optional {
+				return nil, errors.New("pod information " + field.env + " not found")
+			}
+			continue
+		}
+		metadata[field.key] = value
+	}
+
+	return &DatacenterInfo{
+		Class:    customDatacenterClass,
+		Name:     kubernetesDatacenterName,
+		Metadata: metadata,
+	}, nil
+}
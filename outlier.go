@@ -0,0 +1,320 @@
+// Copyright 2016 IBM Corporation
+//
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+//
+//       http://www.apache.org/licenses/LICENSE-2.0
+//
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+
+//Package goEurekaClient Implements a go client that interacts with a eureka server
+package goEurekaClient
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	defaultConsecutiveFailures = 5
+	defaultErrorRate           = 0.5
+	defaultMinimumRequests     = 10
+	defaultOutlierInterval     = 10 * time.Second
+	defaultBaseEjectionTime    = 30 * time.Second
+	defaultMaxEjectionTime     = 300 * time.Second
+	defaultMaxEjectionPercent  = 10
+)
+
+// OutlierDetectorConfig defines when instances are ejected, and for how long.
+type OutlierDetectorConfig struct {
+	ConsecutiveFailures int           // consecutive failures ejecting an instance, default 5
+	ErrorRate           float64       // failure ratio within an interval ejecting an instance, default 0.5
+	MinimumRequests     int           // reports within an interval before the error rate applies, default 10
+	Interval            time.Duration // error rate interval, default 10s
+	BaseEjectionTime    time.Duration // duration of the first ejection, doubled on each ejection, default 30s
+	MaxEjectionTime     time.Duration // maximal duration of an ejection, default 300s
+	MaxEjectionPercent  int           // maximal percentage of the instances of a vip address excluded, default 10
+}
+
+// OutlierState describes the outlier detection state of an instance.
+type OutlierState struct {
+	InstanceID          string    `json:"instanceId"`
+	Ejected             bool      `json:"ejected"`
+	EjectedUntil        time.Time `json:"ejectedUntil"`
+	Ejections           int       `json:"ejections"`
+	ConsecutiveFailures int       `json:"consecutiveFailures"`
+	Successes           int       `json:"successes"` // within the current interval
+	Failures            int       `json:"failures"`  // within the current interval
+}
+
+// OutlierDetector is a discovery cache whose selections exclude the instances ejected after failing requests.
+// Callers report the outcome of their requests by instance ID. An instance is ejected after ConsecutiveFailures
+// consecutive failures, or when its error rate within an interval reaches ErrorRate. Each ejection of an instance
+// lasts twice as long as the previous one, up to MaxEjectionTime, and the count is reset once an instance stays
+// in the selections for MaxEjectionTime.
+//
+// Only the Select methods exclude ejected instances, and at most MaxEjectionPercent of the instances of a vip
+// address are excluded, the earliest ejected first. At least one instance is excluded when a vip address has
+// several instances, and the last instance is never excluded.
+type OutlierDetector interface {
+	DiscoveryCache
+	ReportSuccess(instanceID string)
+	ReportFailure(instanceID string)
+	States() []OutlierState
+	Handler() http.Handler
+}
+
+type outlierState struct {
+	ejectedAt           time.Time
+	ejectedUntil        time.Time
+	ejections           int
+	consecutiveFailures int
+	intervalStart       time.Time
+	successes           int
+	failures            int
+}
+
+type outlierDetector struct {
+	DiscoveryCache
+	config OutlierDetectorConfig
+	now    func() time.Time
+
+	sync.Mutex
+	states map[string]*outlierState
+	pruned time.Time // last pruning of the states
+}
+
+// NewOutlierDetector creates an outlier detector over the cache.
+func NewOutlierDetector(cache DiscoveryCache, config OutlierDetectorConfig) (OutlierDetector, error) {
+	if cache == nil {
+		return nil, errors.New("discovery cache must be defined")
+	}
+	if config.ErrorRate < 0 || config.ErrorRate > 1 {
+		return nil, fmt.Errorf("error rate %v is not between 0 and 1", config.ErrorRate)
+	}
+	if config.MaxEjectionPercent < 0 || config.MaxEjectionPercent > 100 {
+		return nil, fmt.Errorf("max ejection percent %d is not between 0 and 100", config.MaxEjectionPercent)
+	}
+
+	if config.ConsecutiveFailures <= 0 {
+		config.ConsecutiveFailures = defaultConsecutiveFailures
+	}
+	if config.ErrorRate == 0 {
+		config.ErrorRate = defaultErrorRate
+	}
+	if config.MinimumRequests <= 0 {
+		config.MinimumRequests = defaultMinimumRequests
+	}
+	if config.Interval <= 0 {
+		config.Interval = defaultOutlierInterval
+	}
+	if config.BaseEjectionTime <= 0 {
+		config.BaseEjectionTime = defaultBaseEjectionTime
+	}
+	if config.MaxEjectionTime <= 0 {
+		config.MaxEjectionTime = defaultMaxEjectionTime
+	}
+	if config.MaxEjectionTime < config.BaseEjectionTime {
+		config.MaxEjectionTime = config.BaseEjectionTime
+	}
+	if config.MaxEjectionPercent == 0 {
+		config.MaxEjectionPercent = defaultMaxEjectionPercent
+	}
+
+	return &outlierDetector{
+		DiscoveryCache: cache,
+		config:         config,
+		now:            time.Now,
+		states:         map[string]*outlierState{},
+	}, nil
+}
+
+// ReportSuccess records a successful request to an instance.
+func (o *outlierDetector) ReportSuccess(instanceID string) {
+	o.Lock()
+	defer o.Unlock()
+
+	state := o.state(instanceID, o.now())
+	state.consecutiveFailures = 0
+	state.successes++
+}
+
+// ReportFailure records a failed request to an instance, and ejects the instance when it crosses a threshold.
+func (o *outlierDetector) ReportFailure(instanceID string) {
+	o.Lock()
+	defer o.Unlock()
+
+	now := o.now()
+	state := o.state(instanceID, now)
+	state.consecutiveFailures++
+	state.failures++
+	if now.Before(state.ejectedUntil) {
+		return
+	}
+
+	reason := ""
+	total := state.successes + state.failures
+	switch {
+	case state.consecutiveFailures >= o.config.ConsecutiveFailures:
+		reason = fmt.Sprintf("%d consecutive failures", state.consecutiveFailures)
+	case total >= o.config.MinimumRequests && float64(state.failures)/float64(total) >= o.config.ErrorRate:
+		reason = fmt.Sprintf("%d failures out of %d requests", state.failures, total)
+	default:
+		return
+	}
+
+	state.ejections++
+	duration := o.config.BaseEjectionTime
+	for i := 1; i < state.ejections && duration < o.config.MaxEjectionTime; i++ {
+		duration *= 2
+	}
+	if duration > o.config.MaxEjectionTime {
+		duration = o.config.MaxEjectionTime
+	}
+	state.ejectedAt = now
+	state.ejectedUntil = now.Add(duration)
+	state.consecutiveFailures = 0
+	state.intervalStart, state.successes, state.failures = now, 0, 0
+	log.Printf("Instance %s ejected for %v after %s\n", instanceID, duration, reason)
+}
+
+// state returns the state of an instance, starting a new interval and resetting the ejection count when due.
+// The states of the other instances are pruned once per interval.
+func (o *outlierDetector) state(instanceID string, now time.Time) *outlierState {
+	if now.Sub(o.pruned) >= o.config.Interval {
+		o.prune(now)
+	}
+	state, ok := o.states[instanceID]
+	if !ok {
+		state = &outlierState{intervalStart: now}
+		o.states[instanceID] = state
+	}
+	if now.Sub(state.intervalStart) >= o.config.Interval {
+		state.intervalStart, state.successes, state.failures = now, 0, 0
+	}
+	if state.ejections > 0 && now.Sub(state.ejectedUntil) >= o.config.MaxEjectionTime {
+		state.ejections = 0
+	}
+	return state
+}
+
+// prune drops the states of the instances which are not ejected, whose ejection count is due for a reset,
+// and whose interval expired, so that the states of instances which are no longer reported do not accumulate.
+func (o *outlierDetector) prune(now time.Time) {
+	o.pruned = now
+	for id, state := range o.states {
+		if now.Sub(state.intervalStart) >= o.config.Interval && now.Sub(state.ejectedUntil) >= o.config.MaxEjectionTime {
+			delete(o.states, id)
+		}
+	}
+}
+
+// SelectInstancesByVip returns the UP instances of the vip address selected by the cache, excluding ejected ones.
+func (o *outlierDetector) SelectInstancesByVip(vipAddress string) ([]*Instance, error) {
+	instances, err := o.DiscoveryCache.SelectInstancesByVip(vipAddress)
+	if err != nil {
+		return nil, err
+	}
+	return o.exclude(instances), nil
+}
+
+// SelectInstancesBySecVip returns the UP instances of the secure vip address selected by the cache,
+// excluding ejected ones.
+func (o *outlierDetector) SelectInstancesBySecVip(secVipAddress string) ([]*Instance, error) {
+	instances, err := o.DiscoveryCache.SelectInstancesBySecVip(secVipAddress)
+	if err != nil {
+		return nil, err
+	}
+	return o.exclude(instances), nil
+}
+
+// exclude removes the ejected instances, up to the maximal ejection percentage, the earliest ejected first.
+func (o *outlierDetector) exclude(instances []*Instance) []*Instance {
+	o.Lock()
+	defer o.Unlock()
+
+	now := o.now()
+	type ejected struct {
+		index int
+		at    time.Time
+	}
+	var candidates []ejected
+	for i, inst := range instances {
+		if state, ok := o.states[instanceKey(inst)]; ok && now.Before(state.ejectedUntil) {
+			candidates = append(candidates, ejected{index: i, at: state.ejectedAt})
+		}
+	}
+	if len(candidates) == 0 {
+		return instances
+	}
+
+	limit := len(instances) * o.config.MaxEjectionPercent / 100
+	if limit < 1 {
+		limit = 1
+	}
+	if limit > len(instances)-1 {
+		limit = len(instances) - 1
+	}
+	sort.SliceStable(candidates, func(i, j int) bool { return candidates[i].at.Before(candidates[j].at) })
+	if len(candidates) > limit {
+		candidates = candidates[:limit]
+	}
+
+	excluded := make(map[int]bool, len(candidates))
+	for _, candidate := range candidates {
+		excluded[candidate.index] = true
+	}
+	selected := make([]*Instance, 0, len(instances)-len(excluded))
+	for i, inst := range instances {
+		if !excluded[i] {
+			selected = append(selected, inst)
+		}
+	}
+	return selected
+}
+
+// States returns the outlier detection states of the reported instances, sorted by instance ID.
+func (o *outlierDetector) States() []OutlierState {
+	o.Lock()
+	defer o.Unlock()
+
+	now := o.now()
+	o.prune(now)
+	states := make([]OutlierState, 0, len(o.states))
+	for id := range o.states {
+		state := o.state(id, now)
+		outlier := OutlierState{
+			InstanceID:          id,
+			Ejected:             now.Before(state.ejectedUntil),
+			Ejections:           state.ejections,
+			ConsecutiveFailures: state.consecutiveFailures,
+			Successes:           state.successes,
+			Failures:            state.failures,
+		}
+		if outlier.Ejected {
+			outlier.EjectedUntil = state.ejectedUntil
+		}
+		states = append(states, outlier)
+	}
+	sort.Slice(states, func(i, j int) bool { return states[i].InstanceID < states[j].InstanceID })
+	return states
+}
+
+// Handler returns an HTTP handler serving the outlier detection states as JSON, for debugging.
+func (o *outlierDetector) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(o.States())
+	})
+}
@@ -0,0 +1,178 @@
+// Copyright 2016 IBM Corporation
+//
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+//
+//       http://www.apache.org/licenses/LICENSE-2.0
+//
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+
+//Package goEurekaClient Implements a go client that interacts with a eureka server
+package goEurekaClient
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// selectionCache is a DiscoveryCache selecting a fixed list of instances.
+type selectionCache struct {
+	DiscoveryCache
+	instances []*Instance
+}
+
+func (c *selectionCache) SelectInstancesByVip(vipAddress string) ([]*Instance, error) {
+	return c.instances, nil
+}
+
+func newTestOutlierDetector(t *testing.T, config OutlierDetectorConfig, ids ...string) (*outlierDetector, *time.Time) {
+	cache := &selectionCache{}
+	for _, id := range ids {
+		cache.instances = append(cache.instances, &Instance{ID: id, HostName: id, Status: string(UP)})
+	}
+	detector, err := NewOutlierDetector(cache, config)
+	if err != nil {
+		t.Fatalf("error = %v", err)
+	}
+	o := detector.(*outlierDetector)
+	now := time.Unix(1000, 0)
+	o.now = func() time.Time { return now }
+	return o, &now
+}
+
+func selectedIDs(t *testing.T, o *outlierDetector) []string {
+	instances, err := o.SelectInstancesByVip("vip1")
+	if err != nil {
+		t.Fatalf("error = %v", err)
+	}
+	var ids []string
+	for _, inst := range instances {
+		ids = append(ids, inst.ID)
+	}
+	return ids
+}
+
+func TestOutlierConsecutiveFailures(t *testing.T) {
+	o, now := newTestOutlierDetector(t, OutlierDetectorConfig{ConsecutiveFailures: 3, BaseEjectionTime: 10 * time.Second,
+		MaxEjectionTime: 30 * time.Second}, "inst1", "inst2")
+
+	o.ReportFailure("inst1")
+	o.ReportFailure("inst1")
+	o.ReportSuccess("inst1")
+	o.ReportFailure("inst1")
+	o.ReportFailure("inst1")
+	if ids := selectedIDs(t, o); len(ids) != 2 {
+		t.Fatalf("a success should reset the consecutive failures, selected %v", ids)
+	}
+
+	o.ReportFailure("inst1")
+	if ids := selectedIDs(t, o); len(ids) != 1 || ids[0] != "inst2" {
+		t.Fatalf("inst1 should be ejected, selected %v", ids)
+	}
+
+	// Ejection times double on each ejection, up to the maximum
+	for i, expected := range []time.Duration{10 * time.Second, 20 * time.Second, 30 * time.Second} {
+		if i > 0 {
+			for j := 0; j < 3; j++ {
+				o.ReportFailure("inst1")
+			}
+		}
+		state := o.States()[0]
+		if !state.Ejected || state.Ejections != i+1 || state.EjectedUntil.Sub(*now) != expected {
+			t.Errorf("ejection %d: unexpected state %+v, expected an ejection of %v", i+1, state, expected)
+		}
+		*now = state.EjectedUntil
+	}
+	if ids := selectedIDs(t, o); len(ids) != 2 {
+		t.Errorf("inst1 should be selected after its ejection, selected %v", ids)
+	}
+
+	// The ejection count is reset after staying selected for the maximal ejection time
+	*now = now.Add(30 * time.Second)
+	o.ReportSuccess("inst1")
+	if state := o.States()[0]; state.Ejections != 0 {
+		t.Errorf("ejections = %d, expected the count to be reset", state.Ejections)
+	}
+}
+
+func TestOutlierPrunesStates(t *testing.T) {
+	o, now := newTestOutlierDetector(t, OutlierDetectorConfig{ConsecutiveFailures: 2, Interval: 10 * time.Second,
+		BaseEjectionTime: 10 * time.Second, MaxEjectionTime: 30 * time.Second}, "inst1", "inst2", "inst3")
+
+	o.ReportFailure("inst1")
+	o.ReportFailure("inst1")
+	o.ReportFailure("inst2")
+	*now = now.Add(5 * time.Second)
+	o.ReportSuccess("inst3")
+	*now = now.Add(5 * time.Second)
+	if states := o.States(); len(states) != 2 || states[0].InstanceID != "inst1" || states[1].InstanceID != "inst3" {
+		t.Errorf("states = %+v, expected the ejected and recently reported instances only", states)
+	}
+
+	// The state of an ejected instance is kept until its ejection count is reset
+	*now = now.Add(15 * time.Second)
+	if states := o.States(); len(states) != 1 || states[0].InstanceID != "inst1" || states[0].Ejections != 1 {
+		t.Errorf("states = %+v, expected the state of the ejected instance only", states)
+	}
+	*now = now.Add(15 * time.Second)
+	if states := o.States(); len(states) != 0 {
+		t.Errorf("states = %+v, expected every state to be pruned", states)
+	}
+}
+
+func TestOutlierErrorRate(t *testing.T) {
+	o, now := newTestOutlierDetector(t, OutlierDetectorConfig{ConsecutiveFailures: 100, ErrorRate: 0.5, MinimumRequests: 4,
+		Interval: 10 * time.Second}, "inst1", "inst2")
+
+	o.ReportFailure("inst1")
+	o.ReportSuccess("inst1")
+	o.ReportFailure("inst1")
+	*now = now.Add(10 * time.Second)
+	o.ReportSuccess("inst1")
+	if ids := selectedIDs(t, o); len(ids) != 2 {
+		t.Fatalf("reports of a previous interval should not count, selected %v", ids)
+	}
+
+	o.ReportFailure("inst1")
+	o.ReportSuccess("inst1")
+	o.ReportFailure("inst1")
+	if ids := selectedIDs(t, o); len(ids) != 1 || ids[0] != "inst2" {
+		t.Errorf("inst1 should be ejected at the error rate, selected %v", ids)
+	}
+}
+
+func TestOutlierMaxEjectionPercent(t *testing.T) {
+	o, now := newTestOutlierDetector(t, OutlierDetectorConfig{ConsecutiveFailures: 1, MaxEjectionPercent: 50},
+		"inst1", "inst2", "inst3", "inst4")
+
+	for _, id := range []string{"inst3", "inst1", "inst2", "inst4"} {
+		o.ReportFailure(id)
+		*now = now.Add(time.Second)
+	}
+	if ids := selectedIDs(t, o); len(ids) != 2 || ids[0] != "inst2" || ids[1] != "inst4" {
+		t.Errorf("only the 2 earliest ejected instances should be excluded, selected %v", ids)
+	}
+
+	single, _ := newTestOutlierDetector(t, OutlierDetectorConfig{ConsecutiveFailures: 1}, "inst1")
+	single.ReportFailure("inst1")
+	if ids := selectedIDs(t, single); len(ids) != 1 {
+		t.Errorf("the last instance should never be excluded, selected %v", ids)
+	}
+
+	recorder := httptest.NewRecorder()
+	o.Handler().ServeHTTP(recorder, httptest.NewRequest("GET", "/outliers", nil))
+	var states []OutlierState
+	if err := json.Unmarshal(recorder.Body.Bytes(), &states); err != nil {
+		t.Fatalf("error = %v", err)
+	}
+	if len(states) != 4 || states[0].InstanceID != "inst1" || !states[0].Ejected {
+		t.Errorf("unexpected states %+v", states)
+	}
+}
@@ -15,6 +15,11 @@
 //Package goEurekaClient Implements a go client that interacts with a eureka server
 package goEurekaClient
 
+import (
+	"context"
+	"errors"
+)
+
 // Registrator type defines the eureka client registrator.
 type Registrator interface {
 	Register(*Instance) error
@@ -22,6 +27,12 @@ type Registrator interface {
 	Heartbeat(*Instance) error
 	SetStatus(inst *Instance, status StatusType) error
 	SetMetadataKey(inst *Instance, key string, value string) error
+	RemoveStatusOverride(inst *Instance) error
+	RemoveStatusOverrideWithValue(inst *Instance, status StatusType) error
+	// Drain takes an instance out of service, keeps it alive until the caches of the consumers drop it, and
+	// deregisters it. When the context is done during the delay, the instance is deregistered right away.
+	Drain(ctx context.Context, inst *Instance, config DrainConfig) error
+	ConfigUpdater
 }
 
 type registrator struct {
@@ -40,8 +51,20 @@ func NewRegistrator(config *Config, handler InstanceEventHandler) (Registrator,
 	return newRegistrator, nil
 }
 
-// Register registers an instance in the registry.
+// UpdateConfig replaces the server list and HTTP client settings used by the following requests.
+// Registered instances keep their leases, and their heartbeats are sent to the new servers.
+func (r *registrator) UpdateConfig(config *Config) error {
+	return r.client.updateConfig(config)
+}
+
+// Register validates an instance and registers it in the registry.
 func (r *registrator) Register(instance *Instance) error {
+	if instance == nil {
+		return errors.New("instance must be defined")
+	}
+	if err := instance.Validate(); err != nil {
+		return err
+	}
 	return r.client.register(instance)
 
 }
@@ -66,3 +89,20 @@ func (r *registrator) SetStatus(instance *Instance, status StatusType) error {
 func (r *registrator) SetMetadataKey(inst *Instance, key string, value string) error {
 	return r.client.setMetadataKey(inst, key, value)
 }
+
+// RemoveStatusOverride removes the overridden status of an instance in the registry.
+func (r *registrator) RemoveStatusOverride(inst *Instance) error {
+	return r.client.removeStatusOverride(inst, "")
+}
+
+// RemoveStatusOverrideWithValue removes the overridden status of an instance in the registry,
+// and sets its status to the given value.
+func (r *registrator) RemoveStatusOverrideWithValue(inst *Instance, status StatusType) error {
+	return r.client.removeStatusOverride(inst, status)
+}
+
+// Drain takes an instance out of service, keeps it alive for the drain delay and deregisters it.
+// The default delay is the poll interval of the config, or 30s, plus the margin.
+func (r *registrator) Drain(ctx context.Context, inst *Instance, config DrainConfig) error {
+	return drainInstance(ctx, r, inst, config, r.client.currentSettings().pollInterval)
+}
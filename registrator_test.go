@@ -15,6 +15,88 @@
 //Package goEurekaClient Implements a go client that interacts with a eureka server
 package goEurekaClient
 
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestRemoveStatusOverride(t *testing.T) {
+	var method, path, value string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		method, path, value = r.Method, r.URL.Path, r.URL.Query().Get("value")
+	}))
+	defer server.Close()
+
+	conf := &Config{
+		ConnectTimeoutSeconds: 10 * time.Second,
+		ServiceUrls:           map[string][]string{"eureka": {server.URL}},
+		UseJSON:               true,
+	}
+	reg, err := NewRegistrator(conf, nil)
+	if err != nil {
+		t.Fatalf("error = %v", err)
+	}
+	inst := &Instance{Application: "APP1", HostName: "inst1"}
+
+	if err = reg.RemoveStatusOverride(inst); err != nil {
+		t.Errorf("Failed to remove status override. error: %v", err)
+	}
+	if method != "DELETE" || path != "/apps/APP1/inst1/status" || value != "" {
+		t.Errorf("unexpected request %s %s?value=%s", method, path, value)
+	}
+
+	if err = reg.RemoveStatusOverrideWithValue(inst, DOWN); err != nil {
+		t.Errorf("Failed to remove status override. error: %v", err)
+	}
+	if method != "DELETE" || path != "/apps/APP1/inst1/status" || value != "DOWN" {
+		t.Errorf("unexpected request %s %s?value=%s", method, path, value)
+	}
+
+	if err = reg.RemoveStatusOverrideWithValue(inst, "BOGUS"); err == nil {
+		t.Error("an invalid status should be rejected")
+	}
+}
+
+func TestRegisterValidatesInstance(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	conf := &Config{
+		ConnectTimeoutSeconds: 10 * time.Second,
+		ServiceUrls:           map[string][]string{"eureka": {server.URL}},
+		UseJSON:               true,
+	}
+	reg, err := NewRegistrator(conf, nil)
+	if err != nil {
+		t.Fatalf("error = %v", err)
+	}
+
+	if err = reg.Register(nil); err == nil {
+		t.Error("a nil instance should be rejected")
+	}
+	if err = reg.Register(&Instance{Application: "APP1", HostName: "inst1"}); err == nil {
+		t.Error("an instance without an address, datacenter and port should be rejected")
+	}
+	if requests != 0 {
+		t.Errorf("invalid instances should not reach the server, got %d requests", requests)
+	}
+
+	inst := &Instance{Application: "APP1", HostName: "inst1", IPAddr: "10.0.0.1",
+		Datacenter: &DatacenterInfo{Name: "MyOwn"}, Port: &Port{Enabled: "true", Value: 8080}}
+	if err = reg.Register(inst); err != nil {
+		t.Errorf("Failed to register instance. error: %v", err)
+	}
+	if requests != 1 {
+		t.Errorf("expected a single registration request, got %d", requests)
+	}
+}
+
 /*
 import (
 	"time"
@@ -0,0 +1,242 @@
+// Copyright 2016 IBM Corporation
+//
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+//
+//       http://www.apache.org/licenses/LICENSE-2.0
+//
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+
+//Package goEurekaClient Implements a go client that interacts with a eureka server
+package goEurekaClient
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// decodeApplicationsList decodes a registry response, {"applications": {...}}, from a stream, without reading
+// it whole. visit is called with each application once it is decoded, so that the caller may index its
+// instances without keeping the list of applications. The eureka server encodes a single application or
+// instance as an object, and several as an array: both are handled as they are read.
+// The returned version is nil when the response has no applications object.
+func decodeApplicationsList(r io.Reader, visit func(*Application)) (*appVersion, error) {
+	dec := json.NewDecoder(r)
+	var version *appVersion
+	_, err := decodeObject(dec, func(key string) error {
+		if key != "applications" {
+			return skipValue(dec)
+		}
+		var err error
+		version, err = decodeApplications(dec, visit)
+		return err
+	})
+	return version, err
+}
+
+// decodeApplications decodes the applications object.
+func decodeApplications(dec *json.Decoder, visit func(*Application)) (*appVersion, error) {
+	version := &appVersion{}
+	found, err := decodeObject(dec, func(key string) error {
+		switch key {
+		case "versions__delta":
+			var delta interface{}
+			if err := dec.Decode(&delta); err != nil {
+				return err
+			}
+			return parseVersionDelta(delta, &version.VersionDelta)
+		case "apps__hashcode":
+			return dec.Decode(&version.Hashcode)
+		case "application":
+			return decodeOneOrMany(dec, func(opened bool) error {
+				app, err := decodeApplication(dec, opened)
+				if app != nil {
+					visit(app)
+				}
+				return err
+			})
+		default:
+			return skipValue(dec)
+		}
+	})
+	if !found {
+		return nil, err
+	}
+	return version, err
+}
+
+// parseVersionDelta reads the delta version, which some servers encode as a string.
+func parseVersionDelta(value interface{}, delta *int64) error {
+	switch v := value.(type) {
+	case nil:
+		return nil
+	case float64:
+		*delta = int64(v)
+		return nil
+	case string:
+		parsed, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			return fmt.Errorf("versions__delta %q is not a number", v)
+		}
+		*delta = parsed
+		return nil
+	}
+	return fmt.Errorf("versions__delta %v is not a number", value)
+}
+
+// decodeApplication decodes an application object, whose opening brace is already read when opened is set.
+// It returns nil for a null application.
+func decodeApplication(dec *json.Decoder, opened bool) (*Application, error) {
+	app := &Application{}
+	field := func(key string) error {
+		switch key {
+		case "name":
+			return dec.Decode(&app.Name)
+		case "instance":
+			return decodeOneOrMany(dec, func(opened bool) error {
+				inst := &Instance{}
+				if opened {
+					if err := decodeFields(dec, inst); err != nil {
+						return err
+					}
+				} else {
+					if err := dec.Decode(&inst); err != nil || inst == nil {
+						return err
+					}
+				}
+				app.Instances = append(app.Instances, inst)
+				return nil
+			})
+		default:
+			return skipValue(dec)
+		}
+	}
+
+	if opened {
+		return app, decodeFieldsWith(dec, field)
+	}
+	found, err := decodeObject(dec, field)
+	if !found {
+		return nil, err
+	}
+	return app, err
+}
+
+// decodeOneOrMany decodes a value which is either an array or a single object. element is called for each
+// element of an array, with opened unset, and for a single object, with opened set since its opening brace
+// is already read.
+func decodeOneOrMany(dec *json.Decoder, element func(opened bool) error) error {
+	tok, err := dec.Token()
+	if err != nil || tok == nil {
+		return err
+	}
+	switch tok {
+	case json.Delim('['):
+		for dec.More() {
+			if err := element(false); err != nil {
+				return err
+			}
+		}
+		_, err = dec.Token()
+		return err
+	case json.Delim('{'):
+		return element(true)
+	}
+	return fmt.Errorf("expected an object or an array, got %v", tok)
+}
+
+// decodeObject decodes an object, calling field with each key: field must decode the value.
+// It returns whether an object was found, since the value may be null.
+func decodeObject(dec *json.Decoder, field func(key string) error) (bool, error) {
+	tok, err := dec.Token()
+	if err != nil || tok == nil {
+		return false, err
+	}
+	if tok != json.Delim('{') {
+		return false, fmt.Errorf("expected an object, got %v", tok)
+	}
+	return true, decodeFieldsWith(dec, field)
+}
+
+// decodeFieldsWith decodes the fields of an object whose opening brace is already read, and its closing brace.
+func decodeFieldsWith(dec *json.Decoder, field func(key string) error) error {
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return err
+		}
+		key, ok := tok.(string)
+		if !ok {
+			return fmt.Errorf("expected an object key, got %v", tok)
+		}
+		if err = field(key); err != nil {
+			return err
+		}
+	}
+	_, err := dec.Token()
+	return err
+}
+
+// decodeFields decodes the fields of an object whose opening brace is already read into the struct pointed to
+// by v, matching the keys to the JSON names of its fields like json.Unmarshal.
+func decodeFields(dec *json.Decoder, v interface{}) error {
+	value := reflect.ValueOf(v).Elem()
+	fields := jsonFields(value.Type())
+	return decodeFieldsWith(dec, func(key string) error {
+		index, ok := fields[key]
+		if !ok {
+			for name, i := range fields {
+				if strings.EqualFold(name, key) {
+					index, ok = i, true
+					break
+				}
+			}
+		}
+		if !ok {
+			return skipValue(dec)
+		}
+		return dec.Decode(value.Field(index).Addr().Interface())
+	})
+}
+
+var jsonFieldsCache sync.Map // reflect.Type to map[string]int
+
+// jsonFields maps the JSON names of the fields of a struct type to their indexes.
+func jsonFields(t reflect.Type) map[string]int {
+	if fields, ok := jsonFieldsCache.Load(t); ok {
+		return fields.(map[string]int)
+	}
+
+	fields := map[string]int{}
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.PkgPath != "" {
+			continue
+		}
+		name := strings.Split(field.Tag.Get("json"), ",")[0]
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		fields[name] = i
+	}
+	jsonFieldsCache.Store(t, fields)
+	return fields
+}
+
+func skipValue(dec *json.Decoder) error {
+	var skipped json.RawMessage
+	return dec.Decode(&skipped)
+}
@@ -0,0 +1,167 @@
+// Copyright 2016 IBM Corporation
+//
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+//
+//       http://www.apache.org/licenses/LICENSE-2.0
+//
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+
+//Package goEurekaClient Implements a go client that interacts with a eureka server
+package goEurekaClient
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// decodeStreaming decodes a registry response into applications with the streaming decoder.
+func decodeStreaming(data []byte) (*Applications, error) {
+	var list []*Application
+	version, err := decodeApplicationsList(bytes.NewReader(data), func(app *Application) {
+		list = append(list, app)
+	})
+	if version == nil {
+		return nil, err
+	}
+	return &Applications{appVersion: *version, Application: list}, err
+}
+
+func TestDecodeApplicationsList(t *testing.T) {
+	responses := []string{
+		// several applications, with a single instance and with several instances
+		fmt.Sprintf(`{"applications":{"versions__delta":3,"apps__hashcode":"UP_3_","application":[
+			{"name":"APP1","instance":%s},
+			{"instance":[%s,%s],"name":"APP2","extra":{"a":[1,2]}}]}}`,
+			registryInstanceJSON("APP1", "inst1", "vip1", "UP", "us-east-1a"),
+			registryInstanceJSON("APP2", "inst2", "vip2", "UP", "us-east-1a"),
+			registryInstanceJSON("APP2", "inst3", "vip2", "DOWN", "us-east-1b")),
+		// a single application
+		fmt.Sprintf(`{"applications":{"application":{"name":"APP1","instance":[%s]}},"other":null}`,
+			registryInstanceJSON("APP1", "inst1", "vip1", "UP", "us-east-1a")),
+		// no applications
+		`{"applications":{"versions__delta":1,"apps__hashcode":"","application":[]}}`,
+		`{"applications":{"application":null}}`,
+		`{"applications":null}`,
+		`{}`,
+	}
+
+	for _, response := range responses {
+		var expected applicationsList
+		if err := json.Unmarshal([]byte(response), &expected); err != nil {
+			t.Fatalf("error = %v", err)
+		}
+		apps, err := decodeStreaming([]byte(response))
+		if err != nil {
+			t.Errorf("error = %v, decoding %s", err, response)
+			continue
+		}
+		if expected.Applications == nil || len(expected.Applications.Application) == 0 {
+			// An empty array is decoded as no applications
+			if (apps == nil) != (expected.Applications == nil) || apps != nil && len(apps.Application) != 0 {
+				t.Errorf("applications %v, expected %v", apps, expected.Applications)
+			}
+			continue
+		}
+		if !reflect.DeepEqual(apps, expected.Applications) {
+			t.Errorf("applications %+v, expected %+v", apps, expected.Applications)
+		}
+	}
+
+	apps, err := decodeStreaming([]byte(`{"applications":{"versions__delta":"7","application":[]}}`))
+	if err != nil || apps.VersionDelta != 7 {
+		t.Errorf("a string delta version should be read, got %v (%v)", apps, err)
+	}
+	for _, response := range []string{`{"applications":{"application":[{"name":"APP1"`, `{"applications":[]}`,
+		`{"applications":{"application":"APP1"}}`} {
+		if _, err = decodeStreaming([]byte(response)); err == nil {
+			t.Errorf("decoding %s should fail", response)
+		}
+	}
+}
+
+// registryResponse generates a registry response with the given number of applications, every fourth one
+// having a single instance encoded as an object.
+func registryResponse(apps, instances int) []byte {
+	var b strings.Builder
+	b.WriteString(`{"applications":{"versions__delta":1,"apps__hashcode":"UP_1_","application":[`)
+	for i := 0; i < apps; i++ {
+		if i > 0 {
+			b.WriteString(",")
+		}
+		name := fmt.Sprintf("APP%d", i)
+		vip := fmt.Sprintf("vip%d", i)
+		if i%4 == 0 {
+			fmt.Fprintf(&b, `{"name":"%s","instance":%s}`, name,
+				registryInstanceJSON(name, name+"-inst0", vip, "UP", "us-east-1a"))
+			continue
+		}
+		fmt.Fprintf(&b, `{"name":"%s","instance":[`, name)
+		for j := 0; j < instances; j++ {
+			if j > 0 {
+				b.WriteString(",")
+			}
+			b.WriteString(registryInstanceJSON(name, fmt.Sprintf("%s-inst%d", name, j), vip, "UP", "us-east-1a"))
+		}
+		b.WriteString("]}")
+	}
+	b.WriteString("]}}")
+	return []byte(b.String())
+}
+
+func TestDecodeApplicationsListLargeRegistry(t *testing.T) {
+	data := registryResponse(100, 5)
+	var expected applicationsList
+	if err := json.Unmarshal(data, &expected); err != nil {
+		t.Fatalf("error = %v", err)
+	}
+	apps, err := decodeStreaming(data)
+	if err != nil {
+		t.Fatalf("error = %v", err)
+	}
+	if !reflect.DeepEqual(apps, expected.Applications) {
+		t.Error("the streaming decoder should decode the registry like json.Unmarshal")
+	}
+}
+
+// BenchmarkDecodeRegistryUnmarshal measures the decoding of a registry response as fetchApps did it:
+// reading the whole body, and unmarshaling it, retrying each application with a single instance.
+func BenchmarkDecodeRegistryUnmarshal(b *testing.B) {
+	data := registryResponse(1000, 5)
+	b.SetBytes(int64(len(data)))
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		body, err := ioutil.ReadAll(bytes.NewReader(data))
+		if err != nil {
+			b.Fatal(err)
+		}
+		var appsList applicationsList
+		if err = json.Unmarshal(body, &appsList); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// BenchmarkDecodeRegistryStreaming measures the decoding of a registry response by the streaming decoder.
+func BenchmarkDecodeRegistryStreaming(b *testing.B) {
+	data := registryResponse(1000, 5)
+	b.SetBytes(int64(len(data)))
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := decodeStreaming(data); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
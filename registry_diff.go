@@ -0,0 +1,286 @@
+// Copyright 2016 IBM Corporation
+//
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+//
+//       http://www.apache.org/licenses/LICENSE-2.0
+//
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+
+//Package goEurekaClient Implements a go client that interacts with a eureka server
+package goEurekaClient
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+)
+
+// InstanceRef identifies an instance of an application.
+type InstanceRef struct {
+	Application string `json:"app"`
+	ID          string `json:"instanceId"`
+}
+
+// InstanceMismatch describes a field of an instance whose value differs between two registries.
+// The field is either "status", which compares the effective statuses, or "metadata.<key>".
+type InstanceMismatch struct {
+	InstanceRef
+	Field string `json:"field"`
+	Left  string `json:"left"`
+	Right string `json:"right"`
+}
+
+// RegistryDiff is the result of comparing two registries.
+// Instances of an application missing on one side are reported only through the application.
+type RegistryDiff struct {
+	Time                    time.Time          `json:"time"`
+	Left                    string             `json:"left"`
+	Right                   string             `json:"right"`
+	ApplicationsOnlyInLeft  []string           `json:"applicationsOnlyInLeft"`
+	ApplicationsOnlyInRight []string           `json:"applicationsOnlyInRight"`
+	InstancesOnlyInLeft     []InstanceRef      `json:"instancesOnlyInLeft"`
+	InstancesOnlyInRight    []InstanceRef      `json:"instancesOnlyInRight"`
+	Mismatches              []InstanceMismatch `json:"mismatches"`
+}
+
+// Empty returns true if no difference was found.
+func (d *RegistryDiff) Empty() bool {
+	return len(d.ApplicationsOnlyInLeft) == 0 && len(d.ApplicationsOnlyInRight) == 0 &&
+		len(d.InstancesOnlyInLeft) == 0 && len(d.InstancesOnlyInRight) == 0 && len(d.Mismatches) == 0
+}
+
+// DiffRegistries fetches the registries of the servers defined by the left and right configurations, and compares them.
+func DiffRegistries(left, right *Config) (*RegistryDiff, error) {
+	leftApps, err := fetchRegistry(left)
+	if err != nil {
+		return nil, fmt.Errorf("fetching left registry %v", err)
+	}
+	rightApps, err := fetchRegistry(right)
+	if err != nil {
+		return nil, fmt.Errorf("fetching right registry %v", err)
+	}
+
+	diff := diffApplications(leftApps, rightApps)
+	diff.Left = registryName(left)
+	diff.Right = registryName(right)
+	return diff, nil
+}
+
+// DiffZones compares the registries served by two zones of the ServiceUrls of the configuration.
+func DiffZones(config *Config, leftZone, rightZone string) (*RegistryDiff, error) {
+	left, err := zoneConfig(config, leftZone)
+	if err != nil {
+		return nil, err
+	}
+	right, err := zoneConfig(config, rightZone)
+	if err != nil {
+		return nil, err
+	}
+	return DiffRegistries(left, right)
+}
+
+// zoneConfig returns a copy of the configuration whose service URLs are those of a single zone.
+func zoneConfig(config *Config, zone string) (*Config, error) {
+	urls, ok := config.ServiceUrls[zone]
+	if !ok || len(urls) == 0 {
+		return nil, fmt.Errorf("zone %s has no service URLs", zone)
+	}
+	zoneConf := *config
+	zoneConf.ServiceUrls = map[string][]string{zone: urls}
+	zoneConf.PreferSameZone = false
+	return &zoneConf, nil
+}
+
+// RunRegistryDiff compares the registries every interval until the context is done,
+// and passes each result to the report function.
+func RunRegistryDiff(ctx context.Context, left, right *Config, interval time.Duration, report func(*RegistryDiff, error)) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		report(DiffRegistries(left, right))
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func fetchRegistry(config *Config) ([]*Application, error) {
+	cl, err := newClient(config, nil)
+	if err != nil {
+		return nil, err
+	}
+	apps, err := cl.fetchApps("apps/")
+	if err != nil {
+		return nil, err
+	}
+	if apps == nil {
+		return nil, nil
+	}
+	return apps.Application, nil
+}
+
+func registryName(config *Config) string {
+	var urls []string
+	for _, zoneURLs := range config.ServiceUrls {
+		urls = append(urls, zoneURLs...)
+	}
+	sort.Strings(urls)
+	return strings.Join(urls, ",")
+}
+
+// diffApplications compares two lists of applications.
+func diffApplications(left, right []*Application) *RegistryDiff {
+	diff := &RegistryDiff{
+		Time:                    time.Now().UTC(),
+		ApplicationsOnlyInLeft:  []string{},
+		ApplicationsOnlyInRight: []string{},
+		InstancesOnlyInLeft:     []InstanceRef{},
+		InstancesOnlyInRight:    []InstanceRef{},
+		Mismatches:              []InstanceMismatch{},
+	}
+
+	leftIndex := indexApplications(left)
+	rightIndex := indexApplications(right)
+
+	for _, appName := range sortedApplications(leftIndex) {
+		rightInsts, ok := rightIndex[appName]
+		if !ok {
+			diff.ApplicationsOnlyInLeft = append(diff.ApplicationsOnlyInLeft, appName)
+			continue
+		}
+
+		leftInsts := leftIndex[appName]
+		for _, id := range sortedInstanceIDs(leftInsts) {
+			rightInst, ok := rightInsts[id]
+			if !ok {
+				diff.InstancesOnlyInLeft = append(diff.InstancesOnlyInLeft, InstanceRef{appName, id})
+				continue
+			}
+			diff.Mismatches = append(diff.Mismatches, diffInstances(InstanceRef{appName, id}, leftInsts[id], rightInst)...)
+		}
+		for _, id := range sortedInstanceIDs(rightInsts) {
+			if _, ok := leftInsts[id]; !ok {
+				diff.InstancesOnlyInRight = append(diff.InstancesOnlyInRight, InstanceRef{appName, id})
+			}
+		}
+	}
+	for _, appName := range sortedApplications(rightIndex) {
+		if _, ok := leftIndex[appName]; !ok {
+			diff.ApplicationsOnlyInRight = append(diff.ApplicationsOnlyInRight, appName)
+		}
+	}
+
+	return diff
+}
+
+func diffInstances(ref InstanceRef, left, right *Instance) []InstanceMismatch {
+	var mismatches []InstanceMismatch
+	if left.EffectiveStatus() != right.EffectiveStatus() {
+		mismatches = append(mismatches, InstanceMismatch{ref, "status",
+			string(left.EffectiveStatus()), string(right.EffectiveStatus())})
+	}
+
+	leftMetadata := decodeMetadata(left.Metadata)
+	rightMetadata := decodeMetadata(right.Metadata)
+	for _, key := range sortedMetadataKeys(leftMetadata, rightMetadata) {
+		if !reflect.DeepEqual(leftMetadata[key], rightMetadata[key]) {
+			mismatches = append(mismatches, InstanceMismatch{ref, "metadata." + key,
+				metadataValue(leftMetadata, key), metadataValue(rightMetadata, key)})
+		}
+	}
+	return mismatches
+}
+
+// indexApplications maps application names to their instances by ID.
+func indexApplications(apps []*Application) map[string]map[string]*Instance {
+	index := map[string]map[string]*Instance{}
+	for _, app := range apps {
+		if app == nil {
+			continue
+		}
+		insts := index[app.Name]
+		if insts == nil {
+			insts = map[string]*Instance{}
+			index[app.Name] = insts
+		}
+		for _, inst := range app.Instances {
+			insts[instanceKey(inst)] = inst
+		}
+	}
+	return index
+}
+
+// instanceKey returns the ID of an instance, falling back to the ID resolved from its datacenter information.
+func instanceKey(inst *Instance) string {
+	if inst.ID != "" {
+		return inst.ID
+	}
+	if id, err := resolveInstanceID(inst); err == nil {
+		return id
+	}
+	return inst.HostName
+}
+
+func decodeMetadata(raw json.RawMessage) map[string]interface{} {
+	metadata := map[string]interface{}{}
+	if len(raw) > 0 {
+		json.Unmarshal(raw, &metadata)
+	}
+	return metadata
+}
+
+func metadataValue(metadata map[string]interface{}, key string) string {
+	value, ok := metadata[key]
+	if !ok {
+		return ""
+	}
+	if s, ok := value.(string); ok {
+		return s
+	}
+	return fmt.Sprintf("%v", value)
+}
+
+func sortedApplications(index map[string]map[string]*Instance) []string {
+	names := make([]string, 0, len(index))
+	for name := range index {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func sortedInstanceIDs(insts map[string]*Instance) []string {
+	ids := make([]string, 0, len(insts))
+	for id := range insts {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// sortedMetadataKeys returns the keys present in either metadata.
+func sortedMetadataKeys(left, right map[string]interface{}) []string {
+	keys := make([]string, 0, len(left)+len(right))
+	for key := range left {
+		keys = append(keys, key)
+	}
+	for key := range right {
+		if _, ok := left[key]; !ok {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
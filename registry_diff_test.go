@@ -0,0 +1,85 @@
+// Copyright 2016 IBM Corporation
+//
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+//
+//       http://www.apache.org/licenses/LICENSE-2.0
+//
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+
+//Package goEurekaClient Implements a go client that interacts with a eureka server
+package goEurekaClient
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func registryServer(body string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, body)
+	}))
+}
+
+func TestDiffRegistries(t *testing.T) {
+	withMetadata := func(instJSON, metadata string) string {
+		return strings.Replace(instJSON, `"leaseInfo"`, `"metadata":`+metadata+`,"leaseInfo"`, 1)
+	}
+
+	left := registryServer(fmt.Sprintf(`{"applications":{"application":[`+
+		`{"name":"APP1","instance":[%s,%s]},{"name":"APP2","instance":%s}]}}`,
+		withMetadata(registryInstanceJSON("APP1", "inst1", "vip1", "UP", "us-east-1a"), `{"version":"1","owner":"a"}`),
+		registryInstanceJSON("APP1", "inst2", "vip1", "UP", "us-east-1a"),
+		registryInstanceJSON("APP2", "inst3", "vip2", "UP", "us-east-1a")))
+	defer left.Close()
+	right := registryServer(fmt.Sprintf(`{"applications":{"application":[`+
+		`{"name":"APP1","instance":[%s,%s]},{"name":"APP3","instance":%s}]}}`,
+		withMetadata(registryInstanceJSON("APP1", "inst1", "vip1", "DOWN", "us-east-1a"), `{"owner":"a","version":"2"}`),
+		registryInstanceJSON("APP1", "inst4", "vip1", "UP", "us-east-1a"),
+		registryInstanceJSON("APP3", "inst5", "vip3", "UP", "us-east-1a")))
+	defer right.Close()
+
+	conf := &Config{
+		ConnectTimeoutSeconds: 10 * time.Second,
+		ServiceUrls:           map[string][]string{"old": {left.URL}, "new": {right.URL}},
+		UseJSON:               true,
+	}
+	diff, err := DiffZones(conf, "old", "new")
+	if err != nil {
+		t.Fatalf("error = %v", err)
+	}
+
+	if diff.Empty() || diff.Left != left.URL || diff.Right != right.URL {
+		t.Errorf("unexpected registries compared: %+v", diff)
+	}
+	if !reflect.DeepEqual(diff.ApplicationsOnlyInLeft, []string{"APP2"}) ||
+		!reflect.DeepEqual(diff.ApplicationsOnlyInRight, []string{"APP3"}) {
+		t.Errorf("applications only in left %v, only in right %v", diff.ApplicationsOnlyInLeft, diff.ApplicationsOnlyInRight)
+	}
+	if !reflect.DeepEqual(diff.InstancesOnlyInLeft, []InstanceRef{{"APP1", "inst2"}}) ||
+		!reflect.DeepEqual(diff.InstancesOnlyInRight, []InstanceRef{{"APP1", "inst4"}}) {
+		t.Errorf("instances only in left %v, only in right %v", diff.InstancesOnlyInLeft, diff.InstancesOnlyInRight)
+	}
+
+	expected := []InstanceMismatch{
+		{InstanceRef{"APP1", "inst1"}, "status", "UP", "DOWN"},
+		{InstanceRef{"APP1", "inst1"}, "metadata.version", "1", "2"},
+	}
+	if !reflect.DeepEqual(diff.Mismatches, expected) {
+		t.Errorf("mismatches = %v, expected %v", diff.Mismatches, expected)
+	}
+
+	if _, err = DiffZones(conf, "old", "missing"); err == nil {
+		t.Error("comparing an undefined zone should fail")
+	}
+}
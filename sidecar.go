@@ -0,0 +1,269 @@
+// Copyright 2016 IBM Corporation
+//
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+//
+//       http://www.apache.org/licenses/LICENSE-2.0
+//
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+
+//Package goEurekaClient Implements a go client that interacts with a eureka server
+package goEurekaClient
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+const (
+	defaultReadinessInterval = 5 * time.Second
+	defaultHeartbeatInterval = 30 * time.Second
+)
+
+// ReadinessSource reports whether the workload is ready to serve traffic.
+type ReadinessSource interface {
+	Ready(ctx context.Context) (bool, error)
+}
+
+// ReadinessFunc adapts an ordinary function to the ReadinessSource interface.
+type ReadinessFunc func(ctx context.Context) (bool, error)
+
+// Ready calls f(ctx).
+func (f ReadinessFunc) Ready(ctx context.Context) (bool, error) {
+	return f(ctx)
+}
+
+// NewFileReadiness creates a readiness source which reports ready while the given file exists.
+func NewFileReadiness(path string) ReadinessSource {
+	return ReadinessFunc(func(ctx context.Context) (bool, error) {
+		_, err := os.Stat(path)
+		if os.IsNotExist(err) {
+			return false, nil
+		}
+		return err == nil, err
+	})
+}
+
+// NewHTTPReadiness creates a readiness source which reports ready while GET requests to the url
+// respond with a 2xx status code.
+func NewHTTPReadiness(url string) ReadinessSource {
+	check := NewHTTPHealthCheck(url)
+	return ReadinessFunc(func(ctx context.Context) (bool, error) {
+		return check.Check(ctx) == nil, nil
+	})
+}
+
+// NewDownwardAPIReadiness creates a readiness source reading a pod label or annotation from a
+// downward API volume file, which holds key="value" lines. The pod is ready when the key is "true".
+func NewDownwardAPIReadiness(path, key string) ReadinessSource {
+	return ReadinessFunc(func(ctx context.Context) (bool, error) {
+		file, err := os.Open(path)
+		if err != nil {
+			return false, err
+		}
+		defer file.Close()
+
+		scanner := bufio.NewScanner(file)
+		for scanner.Scan() {
+			parts := strings.SplitN(scanner.Text(), "=", 2)
+			if len(parts) != 2 || parts[0] != key {
+				continue
+			}
+			value, err := strconv.Unquote(parts[1])
+			if err != nil {
+				value = parts[1]
+			}
+			return value == "true", nil
+		}
+		return false, scanner.Err()
+	})
+}
+
+// NewPodInstanceBuilder creates an instance builder for the running pod, with the pod name as host name,
+// the pod IP address, and the Kubernetes datacenter information read by NewKubernetesMetadataProvider.
+func NewPodInstanceBuilder(appName, podInfoDir string) (*InstanceBuilder, error) {
+	dcinfo, err := NewKubernetesMetadataProvider(podInfoDir).DatacenterInfo()
+	if err != nil {
+		return nil, err
+	}
+
+	builder := NewInstanceBuilder(appName).Datacenter(dcinfo)
+	if name := metadataString(dcinfo, "pod-name"); name != "" {
+		builder.HostName(name)
+	}
+	if ip := metadataString(dcinfo, "pod-ip"); ip != "" {
+		builder.IPAddr(ip)
+	}
+	return builder, nil
+}
+
+// SidecarConfig defines the instance registered by a sidecar and the way its readiness is tracked.
+type SidecarConfig struct {
+	Instance          *Instance
+	Readiness         ReadinessSource
+	ReadinessInterval time.Duration // default 5s
+	HeartbeatInterval time.Duration // default the lease renewal interval of the instance, or 30s
+	DrainDelay        time.Duration // time the instance stays OUT_OF_SERVICE before deregistration, default 0
+}
+
+// Sidecar registers an instance on behalf of a workload, and keeps its status in sync with the
+// workload readiness: UP while ready, DOWN when it stops being ready. The instance is STARTING
+// until the workload becomes ready for the first time.
+type Sidecar interface {
+	// Run registers the instance and keeps it alive until the context is done.
+	// It then marks the instance OUT_OF_SERVICE, waits for the drain delay and deregisters it.
+	Run(ctx context.Context) error
+	// RunUntilSignal runs the sidecar until one of the signals is received. The default signals are SIGTERM and SIGINT.
+	RunUntilSignal(ctx context.Context, signals ...os.Signal) error
+}
+
+type sidecar struct {
+	registrator Registrator
+	config      SidecarConfig
+	instance    *Instance
+}
+
+// NewSidecar creates a sidecar registering instances through the registrator.
+func NewSidecar(registrator Registrator, config SidecarConfig) (Sidecar, error) {
+	if registrator == nil {
+		return nil, errors.New("registrator must be defined")
+	}
+	if config.Instance == nil {
+		return nil, errors.New("instance must be defined")
+	}
+	if config.Readiness == nil {
+		return nil, errors.New("readiness source must be defined")
+	}
+
+	if config.ReadinessInterval <= 0 {
+		config.ReadinessInterval = defaultReadinessInterval
+	}
+	if config.HeartbeatInterval <= 0 {
+		config.HeartbeatInterval = defaultHeartbeatInterval
+		if config.Instance.Lease != nil && config.Instance.Lease.RenewalInt > 0 {
+			config.HeartbeatInterval = time.Duration(config.Instance.Lease.RenewalInt) * time.Second
+		}
+	}
+
+	inst := *config.Instance
+	inst.Status = string(STARTING)
+	return &sidecar{registrator: registrator, config: config, instance: &inst}, nil
+}
+
+// Run registers the instance and keeps it alive until the context is done.
+func (s *sidecar) Run(ctx context.Context) error {
+	ready := s.ready(ctx)
+	if ready {
+		s.instance.Status = string(UP)
+	}
+	if err := s.registrator.Register(s.instance); err != nil {
+		return fmt.Errorf("Failed to register instance. error: %s", err)
+	}
+
+	heartbeats := time.NewTicker(s.config.HeartbeatInterval)
+	defer heartbeats.Stop()
+	probes := time.NewTicker(s.config.ReadinessInterval)
+	defer probes.Stop()
+
+	for {
+		select {
+		case <-heartbeats.C:
+			s.heartbeat()
+		case <-probes.C:
+			if r := s.ready(ctx); r != ready && s.setStatus(r) {
+				ready = r
+			}
+		case <-ctx.Done():
+			return s.drain()
+		}
+	}
+}
+
+// RunUntilSignal runs the sidecar until one of the signals is received.
+func (s *sidecar) RunUntilSignal(ctx context.Context, signals ...os.Signal) error {
+	ctx, cancel := ContextWithSignals(ctx, signals...)
+	defer cancel()
+	return s.Run(ctx)
+}
+
+func (s *sidecar) ready(ctx context.Context) bool {
+	ready, err := s.config.Readiness.Ready(ctx)
+	if err != nil {
+		log.Printf("Failed to check readiness. error: %s\n", err)
+		return false
+	}
+	return ready
+}
+
+// setStatus reports the status matching the readiness, returning whether it was reported.
+func (s *sidecar) setStatus(ready bool) bool {
+	status := DOWN
+	if ready {
+		status = UP
+	}
+	if err := s.registrator.SetStatus(s.instance, status); err != nil {
+		log.Printf("Failed to set status %v. error: %s\n", status, err)
+		return false
+	}
+	s.instance.Status = string(status)
+	return true
+}
+
+// heartbeat renews the lease, registering the instance again if the registry lost it.
+func (s *sidecar) heartbeat() {
+	if err := s.registrator.Heartbeat(s.instance); err != nil {
+		log.Printf("Failed to send heartbeat, registering again. error: %s\n", err)
+		if err = s.registrator.Register(s.instance); err != nil {
+			log.Printf("Failed to register instance. error: %s\n", err)
+		}
+	}
+}
+
+// drain takes the instance out of service, and keeps it alive for the drain delay before deregistering it.
+func (s *sidecar) drain() error {
+	config := DrainConfig{
+		Phases:            []DrainPhase{DrainOutOfService, DrainWait, DrainDeregister},
+		Delay:             s.config.DrainDelay,
+		HeartbeatInterval: s.config.HeartbeatInterval,
+	}
+	if s.config.DrainDelay <= 0 {
+		config.Phases = []DrainPhase{DrainOutOfService, DrainDeregister}
+	}
+	return drainInstance(context.Background(), s.registrator, s.instance, config, 0)
+}
+
+// ContextWithSignals returns a context of parent which is canceled when one of the signals is received.
+// The default signals are SIGTERM and SIGINT.
+func ContextWithSignals(parent context.Context, signals ...os.Signal) (context.Context, context.CancelFunc) {
+	if len(signals) == 0 {
+		signals = []os.Signal{syscall.SIGTERM, os.Interrupt}
+	}
+
+	ctx, cancel := context.WithCancel(parent)
+	ch := make(chan os.Signal, 1)
+	signal.Notify(ch, signals...)
+	go func() {
+		defer signal.Stop(ch)
+		select {
+		case sig := <-ch:
+			log.Printf("signal %v received. stop running...", sig)
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+	return ctx, cancel
+}
@@ -0,0 +1,151 @@
+// Copyright 2016 IBM Corporation
+//
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+//
+//       http://www.apache.org/licenses/LICENSE-2.0
+//
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+
+//Package goEurekaClient Implements a go client that interacts with a eureka server
+package goEurekaClient
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// callRecorder is a Registrator which records the calls it receives.
+type callRecorder struct {
+	Registrator
+	sync.Mutex
+	calls []string
+}
+
+func (r *callRecorder) record(call string) error {
+	r.Lock()
+	defer r.Unlock()
+	r.calls = append(r.calls, call)
+	return nil
+}
+
+func (r *callRecorder) Register(inst *Instance) error {
+	return r.record("register " + inst.Status)
+}
+
+func (r *callRecorder) Deregister(inst *Instance) error {
+	return r.record("deregister")
+}
+
+func (r *callRecorder) SetStatus(inst *Instance, status StatusType) error {
+	return r.record("status " + string(status))
+}
+
+func (r *callRecorder) Heartbeat(inst *Instance) error {
+	return nil
+}
+
+func (r *callRecorder) recorded() []string {
+	r.Lock()
+	defer r.Unlock()
+	return append([]string(nil), r.calls...)
+}
+
+func waitForCalls(t *testing.T, recorder *callRecorder, count int) []string {
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		if calls := recorder.recorded(); len(calls) >= count {
+			return calls
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	t.Fatalf("expected %d calls, got %v", count, recorder.recorded())
+	return nil
+}
+
+func TestSidecarReadiness(t *testing.T) {
+	var ready int32
+	readiness := ReadinessFunc(func(ctx context.Context) (bool, error) {
+		return atomic.LoadInt32(&ready) == 1, nil
+	})
+
+	recorder := &callRecorder{}
+	sc, err := NewSidecar(recorder, SidecarConfig{
+		Instance:          &Instance{Application: "APP1", HostName: "pod1", Status: string(UP)},
+		Readiness:         readiness,
+		ReadinessInterval: 10 * time.Millisecond,
+		DrainDelay:        20 * time.Millisecond,
+	})
+	if err != nil {
+		t.Fatalf("error = %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- sc.Run(ctx) }()
+
+	waitForCalls(t, recorder, 1)
+	atomic.StoreInt32(&ready, 1)
+	waitForCalls(t, recorder, 2)
+	atomic.StoreInt32(&ready, 0)
+	waitForCalls(t, recorder, 3)
+
+	start := time.Now()
+	cancel()
+	if err = <-done; err != nil {
+		t.Errorf("error = %v", err)
+	}
+	if time.Since(start) < 20*time.Millisecond {
+		t.Errorf("deregistration should wait for the drain delay")
+	}
+
+	expected := []string{"register STARTING", "status UP", "status DOWN", "status OUT_OF_SERVICE", "deregister"}
+	calls := recorder.recorded()
+	if len(calls) != len(expected) {
+		t.Fatalf("calls = %v, expected %v", calls, expected)
+	}
+	for i := range expected {
+		if calls[i] != expected[i] {
+			t.Errorf("calls = %v, expected %v", calls, expected)
+			break
+		}
+	}
+}
+
+func TestReadinessSources(t *testing.T) {
+	dir, err := ioutil.TempDir("", "readiness")
+	if err != nil {
+		t.Fatalf("error = %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	readyFile := filepath.Join(dir, "ready")
+	fileReadiness := NewFileReadiness(readyFile)
+	if ready, err := fileReadiness.Ready(context.Background()); ready || err != nil {
+		t.Errorf("missing file should not be ready, got %v (%v)", ready, err)
+	}
+	ioutil.WriteFile(readyFile, nil, 0644)
+	if ready, err := fileReadiness.Ready(context.Background()); !ready || err != nil {
+		t.Errorf("existing file should be ready, got %v (%v)", ready, err)
+	}
+
+	annotations := filepath.Join(dir, "annotations")
+	ioutil.WriteFile(annotations, []byte("app=\"app1\"\neureka/ready=\"true\"\n"), 0644)
+	if ready, err := NewDownwardAPIReadiness(annotations, "eureka/ready").Ready(context.Background()); !ready || err != nil {
+		t.Errorf("annotation should be ready, got %v (%v)", ready, err)
+	}
+	if ready, err := NewDownwardAPIReadiness(annotations, "app").Ready(context.Background()); ready || err != nil {
+		t.Errorf("annotation should not be ready, got %v (%v)", ready, err)
+	}
+}
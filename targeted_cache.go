@@ -0,0 +1,187 @@
+// Copyright 2016 IBM Corporation
+//
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+//
+//       http://www.apache.org/licenses/LICENSE-2.0
+//
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+
+//Package goEurekaClient Implements a go client that interacts with a eureka server
+package goEurekaClient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const defaultMaxBackoffFactor = 10
+
+// RefreshTarget is an application or a VIP address refreshed by a targeted discovery cache.
+type RefreshTarget struct {
+	App        string        // application name, fetched through apps/{app}
+	VIP        string        // VIP address, fetched through vips/{vip}
+	Interval   time.Duration // default the poll interval of the cache
+	MaxBackoff time.Duration // longest interval after consecutive failures, default 10 times the interval
+}
+
+func (t RefreshTarget) path() string {
+	if t.App != "" {
+		return "apps/" + t.App
+	}
+	return "vips/" + t.VIP
+}
+
+// NewTargetedDiscoveryCache creates a discovery cache which refreshes only the given applications and VIP
+// addresses, instead of the whole registry. Each target is fetched at its own interval, which doubles after
+// each consecutive failure up to its maximum backoff. The lookups and notifications are those of a cache
+// refreshing the whole registry, restricted to the instances of the targets. Only the local region is fetched.
+// pollInterval is the default interval of the targets. When it is not positive, the poll interval of the config
+// is used, and 30s when neither is set. The intervals of the targets are not changed by config updates.
+func NewTargetedDiscoveryCache(config *Config, pollInterval time.Duration, handler InstanceEventHandler,
+	targets ...RefreshTarget) (DiscoveryCache, error) {
+	if len(targets) == 0 {
+		return nil, errors.New("at least one refresh target must be defined")
+	}
+	cache, err := NewDiscoveryCache(config, pollInterval, handler)
+	if err != nil {
+		return nil, err
+	}
+	targetedCache := cache.(*discoveryCache)
+
+	for i, target := range targets {
+		if (target.App == "") == (target.VIP == "") {
+			return nil, fmt.Errorf("refresh target %d must define either an application or a VIP address", i)
+		}
+		if target.Interval <= 0 {
+			target.Interval = targetedCache.pollInterval
+		}
+		if target.MaxBackoff < target.Interval {
+			target.MaxBackoff = defaultMaxBackoffFactor * target.Interval
+		}
+		targetedCache.targets = append(targetedCache.targets, target)
+	}
+	return targetedCache, nil
+}
+
+// targetRefresher keeps the applications fetched for each target, and fills the cache with their union.
+type targetRefresher struct {
+	client  *client
+	handler InstanceEventHandler
+
+	sync.Mutex
+	apps [][]*Application // by target index
+}
+
+func (cl *client) runTargets(targets []RefreshTarget, ctx context.Context) {
+	r := &targetRefresher{client: cl, handler: cl.handler, apps: make([][]*Application, len(targets))}
+	var wg sync.WaitGroup
+	for i, target := range targets {
+		wg.Add(1)
+		go func(i int, target RefreshTarget) {
+			defer wg.Done()
+			r.run(ctx, i, target)
+		}(i, target)
+	}
+	wg.Wait()
+	log.Printf("stop chan received. stop running discovery cache...")
+}
+
+// run refreshes a target until the context is done.
+func (r *targetRefresher) run(ctx context.Context, index int, target RefreshTarget) {
+	failures := 0
+	for {
+		wait := target.Interval
+		apps, err := r.fetch(target)
+		if err != nil {
+			failures++
+			wait = backoff(target.Interval, target.MaxBackoff, failures)
+			log.Printf("Failed to refresh %s, retrying in %v. error: %s\n", target.path(), wait, err)
+		} else {
+			failures = 0
+			r.update(index, apps)
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		}
+	}
+}
+
+// backoff returns the interval doubled for each failure, up to max.
+func backoff(interval, max time.Duration, failures int) time.Duration {
+	wait := interval
+	for i := 0; i < failures && wait < max; i++ {
+		wait *= 2
+	}
+	if wait > max {
+		wait = max
+	}
+	return wait
+}
+
+// fetch returns the applications of a target, with the IDs and regions of their instances resolved.
+// A target unknown to the registry has no applications.
+func (r *targetRefresher) fetch(target RefreshTarget) ([]*Application, error) {
+	var apps *Applications
+	var err error
+	if target.App != "" {
+		apps, err = r.client.fetchApp(target.path())
+	} else {
+		apps, err = r.client.fetchApps(target.path())
+	}
+	if statusErr, ok := err.(*statusError); ok && statusErr.code == http.StatusNotFound {
+		return nil, nil
+	}
+	if err != nil || apps == nil {
+		return nil, err
+	}
+
+	for _, app := range apps.Application {
+		resolved := app.Instances[:0]
+		for _, inst := range app.Instances {
+			id, err := resolveInstanceID(inst)
+			if err != nil {
+				log.Printf("Failed to resolve instance ID. error: %s\n", err)
+				continue
+			}
+			inst.ID = id
+			inst.Region = r.client.instanceRegion(inst)
+			resolved = append(resolved, inst)
+		}
+		app.Instances = resolved
+	}
+	return apps.Application, nil
+}
+
+// update replaces the applications of a target, and fills the cache with the applications of all the targets.
+func (r *targetRefresher) update(index int, apps []*Application) {
+	r.Lock()
+	defer r.Unlock()
+	r.apps[index] = apps
+
+	dict := newDictionary()
+	for _, targetApps := range r.apps {
+		for _, app := range targetApps {
+			for _, inst := range app.Instances {
+				dict.Add(inst, inst.ID, app)
+			}
+		}
+	}
+
+	r.client.apply(&dict, r.client.populateDiff(&dict), r.handler)
+}
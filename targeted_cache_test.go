@@ -0,0 +1,128 @@
+// Copyright 2016 IBM Corporation
+//
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+//
+//       http://www.apache.org/licenses/LICENSE-2.0
+//
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+
+//Package goEurekaClient Implements a go client that interacts with a eureka server
+package goEurekaClient
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestTargetedDiscoveryCache(t *testing.T) {
+	var lock sync.Mutex
+	requests := map[string]int{}
+	app1Status := "UP"
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lock.Lock()
+		defer lock.Unlock()
+		requests[r.URL.Path]++
+
+		switch r.URL.Path {
+		case "/apps/APP1":
+			fmt.Fprintf(w, `{"application":{"name":"APP1","instance":[%s]}}`,
+				registryInstanceJSON("APP1", "inst1", "vip1", app1Status, "us-east-1a"))
+		case "/vips/vip2":
+			fmt.Fprintf(w, `{"applications":{"application":[{"name":"APP2","instance":[%s,%s]}]}}`,
+				registryInstanceJSON("APP2", "inst2", "vip2", "UP", "us-east-1a"),
+				registryInstanceJSON("APP2", "inst3", "vip2", "UP", "us-east-1b"))
+		case "/apps/FLAKY":
+			w.WriteHeader(http.StatusForbidden)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+	count := func(path string) int {
+		lock.Lock()
+		defer lock.Unlock()
+		return requests[path]
+	}
+
+	var eventsLock sync.Mutex
+	var events []string
+	record := func(event string) {
+		eventsLock.Lock()
+		defer eventsLock.Unlock()
+		events = append(events, event)
+	}
+	handler := EventFuncs{
+		AddFunc:    func(inst *Instance) { record("added " + inst.HostName) },
+		UpdateFunc: func(oldInst, newInst *Instance) { record("modified " + newInst.HostName) },
+		DeleteFunc: func(inst *Instance) { record("deleted " + inst.HostName) },
+	}
+
+	conf := &Config{
+		ConnectTimeoutSeconds: 10 * time.Second,
+		ServiceUrls:           map[string][]string{"eureka": {server.URL}},
+		UseJSON:               true,
+	}
+	if _, err := NewTargetedDiscoveryCache(conf, 0, nil, RefreshTarget{App: "APP1", VIP: "vip1"}); err == nil {
+		t.Error("a target with both an application and a VIP address should be rejected")
+	}
+	cache, err := NewTargetedDiscoveryCache(conf, 5*time.Millisecond, handler,
+		RefreshTarget{App: "APP1"},
+		RefreshTarget{VIP: "vip2", Interval: time.Hour},
+		RefreshTarget{VIP: "missing"},
+		RefreshTarget{App: "FLAKY", Interval: 5 * time.Millisecond, MaxBackoff: 40 * time.Millisecond})
+	if err != nil {
+		t.Fatalf("error = %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	cache.Run(ctx)
+
+	waitFor(t, "the targets", func() bool {
+		insts, err := cache.GetInstancesByVip("vip2")
+		app, _ := cache.GetApplication("APP1")
+		return err == nil && len(insts) == 2 && app != nil
+	})
+
+	lock.Lock()
+	app1Status = "DOWN"
+	lock.Unlock()
+	waitFor(t, "the status change", func() bool {
+		insts, err := cache.GetApplicationInstancesByStatus("APP1", DOWN)
+		return err == nil && len(insts) == 1
+	})
+
+	time.Sleep(150 * time.Millisecond)
+	cancel()
+	if n := count("/apps/FLAKY"); n > 10 {
+		t.Errorf("the failing target should back off, got %d requests", n)
+	}
+	if count("/vips/vip2") != 1 || count("/apps/APP1") < 5 || count("/vips/missing") < 5 {
+		t.Errorf("the targets should be refreshed at their own intervals, got %v", requests)
+	}
+	if count("/apps") != 0 || count("/apps/delta") != 0 {
+		t.Errorf("the whole registry should not be fetched, got %v", requests)
+	}
+
+	eventsLock.Lock()
+	defer eventsLock.Unlock()
+	expected := map[string]bool{"added inst1": true, "added inst2": true, "added inst3": true, "modified inst1": true}
+	if len(events) != len(expected) {
+		t.Errorf("unexpected events %v", events)
+	}
+	for _, event := range events {
+		if !expected[event] {
+			t.Errorf("unexpected event %s", event)
+		}
+	}
+}
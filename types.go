@@ -18,6 +18,8 @@ package goEurekaClient
 import (
 	"encoding/json"
 	"fmt"
+	"strconv"
+	"strings"
 )
 
 const (
@@ -81,6 +83,7 @@ type Instance struct {
 	LastUpdatedTs interface{}     `json:"lastUpdatedTimestamp,omitempty"`
 	LastDirtyTs   interface{}     `json:"lastDirtyTimestamp,omitempty"`
 	ActionType    string          `json:"actionType,omitempty"`
+	Region        string          `json:"-"` // region the instance was fetched from, set by the client
 }
 
 // InstanceWrapper encapsulates information needed for a service instance registration
@@ -98,28 +101,66 @@ func (ir *Instance) String() string {
 		ir.VIPAddr, ir.IPAddr, ir.Port.Value, ir.HostName, ir.Status, mtlen)
 }
 
-func (ir *Instance) deepCopy() Instance {
-	copyPort := Port{
-		Enabled: ir.Port.Enabled,
-		Value:   ir.Port.Value,
+// EffectiveStatus returns the status of the instance, taking into account its overridden status.
+// An overridden status of UNKNOWN means that no override is in effect.
+func (ir *Instance) EffectiveStatus() StatusType {
+	if ir.OvrStatus != "" && StatusType(ir.OvrStatus) != UNKNOWN {
+		return StatusType(ir.OvrStatus)
+	}
+	return StatusType(ir.Status)
+}
+
+// Number returns the port number when the port is enabled and valid.
+func (p *Port) Number() (int, bool) {
+	if p == nil || p.Enabled != "true" {
+		return 0, false
+	}
+	number, err := strconv.ParseUint(strings.TrimSpace(fmt.Sprintf("%v", p.Value)), 10, 16)
+	if err != nil || number == 0 {
+		return 0, false
 	}
-	copySecPort := Port{
-		Enabled: ir.SecPort.Enabled,
-		Value:   ir.SecPort.Value,
+	return int(number), true
+}
+
+// ServicePort returns the non-secure port of the instance if it is enabled, and the secure port otherwise.
+func (ir *Instance) ServicePort() (int, bool) {
+	if number, ok := ir.Port.Number(); ok {
+		return number, true
+	}
+	return ir.SecPort.Number()
+}
+
+// AvailabilityZone returns the availability zone reported in the datacenter metadata of the instance,
+// or an empty string when it is not reported.
+func (ir *Instance) AvailabilityZone() string {
+	if ir.Datacenter == nil {
+		return ""
 	}
+	zone, _ := ir.Datacenter.Metadata[availabilityZoneKey].(string)
+	return zone
+}
 
-	copyDatacenter := DatacenterInfo{
-		Class:    ir.Datacenter.Class,
-		Metadata: ir.Datacenter.Metadata,
-		Name:     ir.Datacenter.Name,
+// filterByStatus returns the instances whose effective status is one of the given statuses.
+// If no status is given, only UP instances are returned.
+func filterByStatus(insts []*Instance, statuses []StatusType) []*Instance {
+	if len(statuses) == 0 {
+		statuses = []StatusType{UP}
 	}
-	copyLease := LeaseInfo{
-		DurationInt:    ir.Lease.DurationInt,
-		LastRenewalTs:  ir.Lease.LastRenewalTs,
-		RegistrationTs: ir.Lease.RegistrationTs,
-		RenewalInt:     ir.Lease.RenewalInt,
+
+	filtered := []*Instance{}
+	for _, inst := range insts {
+		status := inst.EffectiveStatus()
+		for _, s := range statuses {
+			if status == s {
+				filtered = append(filtered, inst)
+				break
+			}
+		}
 	}
+	return filtered
+}
 
+func (ir *Instance) deepCopy() Instance {
 	copyInst := Instance{
 		ID:            ir.ID,
 		HostName:      ir.HostName,
@@ -131,26 +172,41 @@ func (ir *Instance) deepCopy() Instance {
 		Status:        ir.Status,
 		OvrStatus:     ir.OvrStatus,
 		CountryID:     ir.CountryID,
-		Port:          &copyPort,
-		SecPort:       &copySecPort,
 		HomePage:      ir.HomePage,
 		StatusPage:    ir.StatusPage,
 		HealthCheck:   ir.HealthCheck,
-		Datacenter:    &copyDatacenter,
-		Lease:         &copyLease,
 		Metadata:      ir.Metadata,
 		CordServer:    ir.CordServer,
 		LastUpdatedTs: ir.LastDirtyTs,
 		LastDirtyTs:   ir.LastDirtyTs,
 		ActionType:    ir.ActionType,
+		Region:        ir.Region,
+	}
+
+	// Optional parts of the instance are copied only when present
+	if ir.Port != nil {
+		copyPort := *ir.Port
+		copyInst.Port = &copyPort
+	}
+	if ir.SecPort != nil {
+		copySecPort := *ir.SecPort
+		copyInst.SecPort = &copySecPort
+	}
+	if ir.Datacenter != nil {
+		copyDatacenter := *ir.Datacenter
+		copyInst.Datacenter = &copyDatacenter
+	}
+	if ir.Lease != nil {
+		copyLease := *ir.Lease
+		copyInst.Lease = &copyLease
 	}
 	return copyInst
 }
 
-//StatusType defines status of the application instances.
+// StatusType defines status of the application instances.
 type StatusType string
 
-//DataCenterType defines the DataCenter
+// DataCenterType defines the DataCenter
 type DataCenterType string
 
 // InstanceEventHandler can handle notifications for events that happen
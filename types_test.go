@@ -0,0 +1,44 @@
+// Copyright 2016 IBM Corporation
+//
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+//
+//       http://www.apache.org/licenses/LICENSE-2.0
+//
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+
+//Package goEurekaClient Implements a go client that interacts with a eureka server
+package goEurekaClient
+
+import "testing"
+
+func TestInstanceServicePortAndZone(t *testing.T) {
+	inst := &Instance{
+		Port:       &Port{Enabled: "false", Value: float64(8080)},
+		SecPort:    &Port{Enabled: "true", Value: "8443"},
+		Datacenter: &DatacenterInfo{Metadata: DatacenterMetadata{availabilityZoneKey: "us-east-1a"}},
+	}
+	if port, ok := inst.ServicePort(); !ok || port != 8443 {
+		t.Errorf("port = %d (%v), expected the secure port when the port is disabled", port, ok)
+	}
+	inst.Port.Enabled = "true"
+	if port, ok := inst.ServicePort(); !ok || port != 8080 {
+		t.Errorf("port = %d (%v), expected the non-secure port", port, ok)
+	}
+	inst.Port.Value, inst.SecPort = float64(70000), nil
+	if port, ok := inst.ServicePort(); ok {
+		t.Errorf("port = %d, expected an invalid port to be ignored", port)
+	}
+
+	if zone := inst.AvailabilityZone(); zone != "us-east-1a" {
+		t.Errorf("zone = %q", zone)
+	}
+	if zone := (&Instance{}).AvailabilityZone(); zone != "" {
+		t.Errorf("zone = %q, expected no zone without datacenter info", zone)
+	}
+}
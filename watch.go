@@ -0,0 +1,210 @@
+// Copyright 2016 IBM Corporation
+//
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+//
+//       http://www.apache.org/licenses/LICENSE-2.0
+//
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+
+//Package goEurekaClient Implements a go client that interacts with a eureka server
+package goEurekaClient
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Watcher streams the instance sets of VIP addresses. It is implemented by the DiscoveryCache returned by
+// NewDiscoveryCache, which may be asserted to a Watcher.
+type Watcher interface {
+	// Watch returns a stream of the instance sets of a VIP address, sent when the set changes.
+	Watch(ctx context.Context, vipAddress string) <-chan []*Instance
+}
+
+// Watch returns a stream of the instance sets of a VIP address. The first snapshot is sent once the instances
+// are known, and the following ones only when the set changes: an instance is added, removed or modified.
+// Lease renewals are not changes. A snapshot which is not received before the next change is replaced by
+// the newer one. The channel is closed when the context is done.
+//
+// By default the snapshots follow the cache refresh, which needs the cache to run. When the config sets
+// a watch interval, the VIP address is fetched from the servers at that interval instead, and a VIP address
+// unknown to the servers has no instances.
+func (d *discoveryCache) Watch(ctx context.Context, vipAddress string) <-chan []*Instance {
+	snapshots := make(chan []*Instance)
+	if interval := d.client.currentSettings().watchInterval; interval > 0 {
+		go d.client.watchFetches(ctx, vipAddress, snapshots)
+	} else {
+		go d.client.watchCache(ctx, vipAddress, snapshots)
+	}
+	return snapshots
+}
+
+// watchCache sends the snapshots of the VIP address in the cache, evaluated after each refresh.
+func (cl *client) watchCache(ctx context.Context, vipAddress string, snapshots chan<- []*Instance) {
+	refreshed := make(chan struct{}, 1)
+	refreshed <- struct{}{}
+	cl.watchersLock.Lock()
+	if cl.watchers == nil {
+		cl.watchers = map[chan struct{}]struct{}{}
+	}
+	cl.watchers[refreshed] = struct{}{}
+	cl.watchersLock.Unlock()
+	defer func() {
+		cl.watchersLock.Lock()
+		delete(cl.watchers, refreshed)
+		cl.watchersLock.Unlock()
+	}()
+
+	sendSnapshots(ctx, snapshots, refreshed, func() ([]*Instance, bool) {
+		cl.Lock()
+		defer cl.Unlock()
+		return cl.dictionary.GetInstancesByVip(vipAddress), cl.filled
+	})
+}
+
+// notifyWatchers triggers the evaluation of the cache watches after a refresh.
+func (cl *client) notifyWatchers() {
+	cl.watchersLock.Lock()
+	defer cl.watchersLock.Unlock()
+	for refreshed := range cl.watchers {
+		select {
+		case refreshed <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// watchFetches sends the snapshots of the VIP address fetched from the servers every watch interval.
+func (cl *client) watchFetches(ctx context.Context, vipAddress string, snapshots chan<- []*Instance) {
+	fetched := make(chan struct{}, 1)
+	var lock sync.Mutex
+	var instances []*Instance
+	var ok bool
+	fetch := func() {
+		insts, err := cl.fetchInstancesByVip(vipAddress)
+		if statusErr, ok := err.(*statusError); ok && statusErr.code == http.StatusNotFound {
+			// The registry holds no instance of the VIP address
+			insts, err = nil, nil
+		}
+		if err != nil {
+			log.Printf("Failed to fetch instances of vip %s. error: %s\n", vipAddress, err)
+			return
+		}
+		lock.Lock()
+		instances, ok = insts, true
+		lock.Unlock()
+		select {
+		case fetched <- struct{}{}:
+		default:
+		}
+	}
+
+	go func() {
+		fetch()
+		for {
+			timer := time.NewTimer(cl.currentSettings().watchInterval)
+			select {
+			case <-timer.C:
+				fetch()
+			case <-ctx.Done():
+				timer.Stop()
+				return
+			}
+		}
+	}()
+
+	sendSnapshots(ctx, snapshots, fetched, func() ([]*Instance, bool) {
+		lock.Lock()
+		defer lock.Unlock()
+		return instances, ok
+	})
+}
+
+// sendSnapshots evaluates the snapshot on each trigger, and sends it when it differs from the last one sent.
+// A pending snapshot is replaced when a newer one is evaluated. The snapshots channel is closed when the
+// context is done.
+func sendSnapshots(ctx context.Context, snapshots chan<- []*Instance, triggers <-chan struct{},
+	evaluate func() ([]*Instance, bool)) {
+	defer close(snapshots)
+
+	var sentSignature, pendingSignature []string
+	var sent bool
+	var pending []*Instance
+	for {
+		var out chan<- []*Instance
+		if pending != nil {
+			out = snapshots
+		}
+
+		select {
+		case out <- pending:
+			sentSignature, sent = pendingSignature, true
+			pending = nil
+		case <-triggers:
+			instances, ok := evaluate()
+			if !ok {
+				continue
+			}
+			signature := snapshotSignature(instances)
+			if sent && equalStrings(signature, sentSignature) {
+				// Back to the snapshot already sent, nothing to send
+				pending = nil
+				continue
+			}
+			pending, pendingSignature = sortedSnapshot(instances), signature
+		case <-ctx.Done():
+			log.Printf("stop chan received. stop watching...")
+			return
+		}
+	}
+}
+
+// sortedSnapshot returns a copy of the instances, ordered by application and ID.
+func sortedSnapshot(instances []*Instance) []*Instance {
+	snapshot := make([]*Instance, len(instances))
+	copy(snapshot, instances)
+	sort.Slice(snapshot, func(i, j int) bool {
+		if snapshot[i].Application != snapshot[j].Application {
+			return snapshot[i].Application < snapshot[j].Application
+		}
+		return instanceKey(snapshot[i]) < instanceKey(snapshot[j])
+	})
+	return snapshot
+}
+
+// snapshotSignature describes the instances without their leases, which change on every renewal.
+func snapshotSignature(instances []*Instance) []string {
+	signature := make([]string, 0, len(instances))
+	for _, inst := range instances {
+		stripped := *inst
+		stripped.Lease = nil
+		stripped.ActionType = ""
+		data, _ := json.Marshal(&stripped)
+		signature = append(signature, inst.Application+"/"+instanceKey(inst)+" "+string(data)+" "+inst.Region)
+	}
+	sort.Strings(signature)
+	return signature
+}
+
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
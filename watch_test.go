@@ -0,0 +1,183 @@
+// Copyright 2016 IBM Corporation
+//
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+//
+//       http://www.apache.org/licenses/LICENSE-2.0
+//
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+
+//Package goEurekaClient Implements a go client that interacts with a eureka server
+package goEurekaClient
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// vipServer serves a registry with a single instance of vip1, whose status may change. The registry holds
+// no instance when the status is empty.
+type vipServer struct {
+	sync.Mutex
+	status   string
+	paths    []string
+	requests int32
+}
+
+func (s *vipServer) setStatus(status string) {
+	s.Lock()
+	defer s.Unlock()
+	s.status = status
+}
+
+func (s *vipServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.Lock()
+	status := s.status
+	s.paths = append(s.paths, r.URL.Path)
+	s.Unlock()
+	atomic.AddInt32(&s.requests, 1)
+
+	if strings.HasPrefix(r.URL.Path, "/apps/delta") {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	if status == "" {
+		if strings.HasPrefix(r.URL.Path, "/vips/") {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		fmt.Fprint(w, `{"applications":{}}`)
+		return
+	}
+	fmt.Fprintf(w, `{"applications":{"application":{"name":"APP1","instance":%s}}}`,
+		registryInstanceJSON("APP1", "inst1", "vip1", status, "us-east-1a"))
+}
+
+// waitForRequests waits until the server received count more requests.
+func (s *vipServer) waitForRequests(t *testing.T, count int32) {
+	target := atomic.LoadInt32(&s.requests) + count
+	waitFor(t, "requests", func() bool { return atomic.LoadInt32(&s.requests) >= target })
+}
+
+func receiveSnapshot(t *testing.T, snapshots <-chan []*Instance) []*Instance {
+	select {
+	case snapshot := <-snapshots:
+		return snapshot
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected a snapshot")
+		return nil
+	}
+}
+
+func TestWatchFollowsCache(t *testing.T) {
+	registry := &vipServer{status: "UP"}
+	server := httptest.NewServer(registry)
+	defer server.Close()
+
+	conf := &Config{
+		ConnectTimeoutSeconds: 10 * time.Second,
+		ServiceUrls:           map[string][]string{"eureka": {server.URL}},
+		UseJSON:               true,
+	}
+	cache, err := NewDiscoveryCache(conf, 5*time.Millisecond, nil)
+	if err != nil {
+		t.Fatalf("error = %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	snapshots := cache.(Watcher).Watch(ctx, "vip1")
+	cache.Run(ctx)
+
+	if snapshot := receiveSnapshot(t, snapshots); len(snapshot) != 1 || snapshot[0].Status != "UP" {
+		t.Fatalf("unexpected initial snapshot %v", snapshot)
+	}
+	registry.waitForRequests(t, 5)
+	select {
+	case snapshot := <-snapshots:
+		t.Fatalf("an unchanged set should not be sent, got %v", snapshot)
+	default:
+	}
+
+	registry.setStatus("DOWN")
+	if snapshot := receiveSnapshot(t, snapshots); len(snapshot) != 1 || snapshot[0].Status != "DOWN" {
+		t.Fatalf("unexpected snapshot %v", snapshot)
+	}
+
+	// Changes which are not received are coalesced into the latest one
+	registry.setStatus("OUT_OF_SERVICE")
+	registry.waitForRequests(t, 3)
+	registry.setStatus("STARTING")
+	registry.waitForRequests(t, 3)
+	if snapshot := receiveSnapshot(t, snapshots); len(snapshot) != 1 || snapshot[0].Status != "STARTING" {
+		t.Fatalf("unexpected snapshot %v", snapshot)
+	}
+	registry.waitForRequests(t, 3)
+	select {
+	case snapshot := <-snapshots:
+		t.Fatalf("the coalesced changes should be sent once, got %v", snapshot)
+	default:
+	}
+
+	cancel()
+	for range snapshots {
+	}
+}
+
+func TestWatchFetchesVip(t *testing.T) {
+	registry := &vipServer{status: "UP"}
+	server := httptest.NewServer(registry)
+	defer server.Close()
+
+	conf := &Config{
+		ConnectTimeoutSeconds: 10 * time.Second,
+		ServiceUrls:           map[string][]string{"eureka": {server.URL}},
+		UseJSON:               true,
+		WatchInterval:         5 * time.Millisecond,
+	}
+	cache, err := NewDiscoveryCache(conf, time.Hour, nil)
+	if err != nil {
+		t.Fatalf("error = %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	snapshots := cache.(Watcher).Watch(ctx, "vip1")
+
+	if snapshot := receiveSnapshot(t, snapshots); len(snapshot) != 1 || snapshot[0].HostName != "inst1" {
+		t.Fatalf("unexpected initial snapshot %v", snapshot)
+	}
+	registry.setStatus("DOWN")
+	if snapshot := receiveSnapshot(t, snapshots); len(snapshot) != 1 || snapshot[0].Status != "DOWN" {
+		t.Fatalf("unexpected snapshot %v", snapshot)
+	}
+	registry.setStatus("")
+	if snapshot := receiveSnapshot(t, snapshots); snapshot == nil || len(snapshot) != 0 {
+		t.Fatalf("a vip unknown to the registry should have an empty snapshot, got %v", snapshot)
+	}
+	registry.setStatus("UP")
+	if snapshot := receiveSnapshot(t, snapshots); len(snapshot) != 1 || snapshot[0].Status != "UP" {
+		t.Fatalf("unexpected snapshot %v", snapshot)
+	}
+
+	cancel()
+	for range snapshots {
+	}
+	registry.Lock()
+	defer registry.Unlock()
+	for _, path := range registry.paths {
+		if path != "/vips/vip1" {
+			t.Errorf("only the vip should be fetched, got a request to %s", path)
+		}
+	}
+}
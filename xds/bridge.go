@@ -0,0 +1,328 @@
+// Copyright 2016 IBM Corporation
+//
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+//
+//       http://www.apache.org/licenses/LICENSE-2.0
+//
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+
+// Package xds serves the vip addresses of the eureka registry as Envoy clusters over the xDS protocol.
+package xds
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	eureka "github.com/amalgam8/go-eureka-client"
+	clusterv3 "github.com/envoyproxy/go-control-plane/envoy/config/cluster/v3"
+	corev3 "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	endpointv3 "github.com/envoyproxy/go-control-plane/envoy/config/endpoint/v3"
+	clusterservice "github.com/envoyproxy/go-control-plane/envoy/service/cluster/v3"
+	discoveryservice "github.com/envoyproxy/go-control-plane/envoy/service/discovery/v3"
+	endpointservice "github.com/envoyproxy/go-control-plane/envoy/service/endpoint/v3"
+	"github.com/envoyproxy/go-control-plane/pkg/cache/types"
+	cachev3 "github.com/envoyproxy/go-control-plane/pkg/cache/v3"
+	resourcev3 "github.com/envoyproxy/go-control-plane/pkg/resource/v3"
+	serverv3 "github.com/envoyproxy/go-control-plane/pkg/server/v3"
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+const (
+	defaultConnectTimeout = 5 * time.Second
+	defaultResyncInterval = 30 * time.Second
+
+	// snapshotNode is the snapshot cache key shared by all Envoy nodes, which all receive the same configuration.
+	snapshotNode = "eureka"
+)
+
+// ApplicationSource lists the applications of the registry. It is implemented by eureka.DiscoveryCache.
+type ApplicationSource interface {
+	GetApplications() ([]*eureka.Application, error)
+}
+
+// Config defines the way registry contents are translated into Envoy resources.
+type Config struct {
+	ConnectTimeout time.Duration // cluster connection timeout, default 5s
+	ResyncInterval time.Duration // interval of snapshot rebuilds when no event is received, default 30s
+}
+
+// Bridge builds versioned CDS and EDS snapshots from the registry, with a cluster per vip address.
+// Endpoints are grouped by locality, derived from the region and availability zone of the instances,
+// and their health is derived from the instance status.
+type Bridge interface {
+	// Run rebuilds the snapshot on registry events and every resync interval, until the context is done.
+	Run(ctx context.Context)
+	// Register registers the ADS, CDS and EDS services on the gRPC server.
+	Register(server *grpc.Server)
+	// Version returns the version of the current snapshot, or an empty string before the first snapshot.
+	Version() string
+}
+
+type bridge struct {
+	source    ApplicationSource
+	config    Config
+	cache     cachev3.SnapshotCache
+	server    serverv3.Server
+	changed   chan struct{}
+	unsubFunc func()
+
+	sync.Mutex
+	version   int
+	resources map[resourcev3.Type][]types.Resource
+}
+
+// NewBridge creates a bridge reading the registry from the source. If events is not nil, the bridge
+// subscribes to it to rebuild the snapshot as soon as the registry changes.
+func NewBridge(source ApplicationSource, events eureka.EventBroadcaster, config Config) (Bridge, error) {
+	if source == nil {
+		return nil, errors.New("application source must be defined")
+	}
+	if config.ConnectTimeout <= 0 {
+		config.ConnectTimeout = defaultConnectTimeout
+	}
+	if config.ResyncInterval <= 0 {
+		config.ResyncInterval = defaultResyncInterval
+	}
+
+	snapshots := cachev3.NewSnapshotCache(false, constantHash{}, nil)
+	b := &bridge{
+		source:  source,
+		config:  config,
+		cache:   snapshots,
+		server:  serverv3.NewServer(context.Background(), snapshots, nil),
+		changed: make(chan struct{}, 1),
+	}
+
+	if events != nil {
+		notify := func() {
+			select {
+			case b.changed <- struct{}{}:
+			default:
+			}
+		}
+		b.unsubFunc = events.Subscribe(eureka.EventFuncs{
+			AddFunc:    func(*eureka.Instance) { notify() },
+			UpdateFunc: func(*eureka.Instance, *eureka.Instance) { notify() },
+			DeleteFunc: func(*eureka.Instance) { notify() },
+		})
+	}
+	return b, nil
+}
+
+// Run rebuilds the snapshot on registry events and every resync interval, until the context is done.
+func (b *bridge) Run(ctx context.Context) {
+	if b.unsubFunc != nil {
+		defer b.unsubFunc()
+	}
+
+	b.update(ctx)
+	ticker := time.NewTicker(b.config.ResyncInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-b.changed:
+			b.update(ctx)
+		case <-ticker.C:
+			b.update(ctx)
+		case <-ctx.Done():
+			log.Printf("stop chan received. stop running xds bridge...")
+			return
+		}
+	}
+}
+
+// Register registers the ADS, CDS and EDS services on the gRPC server.
+func (b *bridge) Register(server *grpc.Server) {
+	discoveryservice.RegisterAggregatedDiscoveryServiceServer(server, b.server)
+	clusterservice.RegisterClusterDiscoveryServiceServer(server, b.server)
+	endpointservice.RegisterEndpointDiscoveryServiceServer(server, b.server)
+}
+
+// Version returns the version of the current snapshot.
+func (b *bridge) Version() string {
+	b.Lock()
+	defer b.Unlock()
+	if b.version == 0 {
+		return ""
+	}
+	return strconv.Itoa(b.version)
+}
+
+// update builds the resources from the registry, and publishes a new snapshot version if they changed.
+func (b *bridge) update(ctx context.Context) {
+	apps, err := b.source.GetApplications()
+	if err != nil {
+		log.Printf("Failed to get applications. error: %s\n", err)
+		return
+	}
+	resources := b.buildResources(apps)
+
+	b.Lock()
+	defer b.Unlock()
+	if b.version > 0 && equalResources(b.resources, resources) {
+		return
+	}
+
+	snapshot, err := cachev3.NewSnapshot(strconv.Itoa(b.version+1), resources)
+	if err != nil {
+		log.Printf("Failed to create snapshot. error: %s\n", err)
+		return
+	}
+	if err = snapshot.Consistent(); err != nil {
+		log.Printf("Inconsistent snapshot. error: %s\n", err)
+		return
+	}
+	if err = b.cache.SetSnapshot(ctx, snapshotNode, snapshot); err != nil {
+		log.Printf("Failed to set snapshot. error: %s\n", err)
+		return
+	}
+	b.version++
+	b.resources = resources
+}
+
+// buildResources creates an EDS cluster and its load assignment for every vip address, sorted by name.
+func (b *bridge) buildResources(apps []*eureka.Application) map[resourcev3.Type][]types.Resource {
+	byVip := map[string][]*eureka.Instance{}
+	for _, app := range apps {
+		for _, inst := range app.Instances {
+			if inst.VIPAddr != "" {
+				byVip[inst.VIPAddr] = append(byVip[inst.VIPAddr], inst)
+			}
+		}
+	}
+
+	vips := make([]string, 0, len(byVip))
+	for vip := range byVip {
+		vips = append(vips, vip)
+	}
+	sort.Strings(vips)
+
+	clusters := make([]types.Resource, 0, len(vips))
+	endpoints := make([]types.Resource, 0, len(vips))
+	for _, vip := range vips {
+		clusters = append(clusters, b.cluster(vip))
+		endpoints = append(endpoints, loadAssignment(vip, byVip[vip]))
+	}
+	return map[resourcev3.Type][]types.Resource{
+		resourcev3.ClusterType:  clusters,
+		resourcev3.EndpointType: endpoints,
+	}
+}
+
+func (b *bridge) cluster(vip string) *clusterv3.Cluster {
+	return &clusterv3.Cluster{
+		Name:                 vip,
+		ConnectTimeout:       durationpb.New(b.config.ConnectTimeout),
+		ClusterDiscoveryType: &clusterv3.Cluster_Type{Type: clusterv3.Cluster_EDS},
+		EdsClusterConfig: &clusterv3.Cluster_EdsClusterConfig{
+			EdsConfig: &corev3.ConfigSource{
+				ResourceApiVersion:    corev3.ApiVersion_V3,
+				ConfigSourceSpecifier: &corev3.ConfigSource_Ads{Ads: &corev3.AggregatedConfigSource{}},
+			},
+		},
+	}
+}
+
+// loadAssignment groups the instances of a vip address by locality, in a deterministic order.
+func loadAssignment(vip string, insts []*eureka.Instance) *endpointv3.ClusterLoadAssignment {
+	sort.Slice(insts, func(i, j int) bool {
+		return insts[i].IPAddr+insts[i].HostName < insts[j].IPAddr+insts[j].HostName
+	})
+
+	var localities []*endpointv3.LocalityLbEndpoints
+	byLocality := map[string]*endpointv3.LocalityLbEndpoints{}
+	for _, inst := range insts {
+		port, ok := inst.ServicePort()
+		if !ok {
+			continue
+		}
+
+		locality := instanceLocality(inst)
+		key := locality.Region + "/" + locality.Zone
+		group, ok := byLocality[key]
+		if !ok {
+			group = &endpointv3.LocalityLbEndpoints{Locality: locality}
+			byLocality[key] = group
+			localities = append(localities, group)
+		}
+		group.LbEndpoints = append(group.LbEndpoints, &endpointv3.LbEndpoint{
+			HostIdentifier: &endpointv3.LbEndpoint_Endpoint{
+				Endpoint: &endpointv3.Endpoint{
+					Address: &corev3.Address{
+						Address: &corev3.Address_SocketAddress{
+							SocketAddress: &corev3.SocketAddress{
+								Address:       inst.IPAddr,
+								PortSpecifier: &corev3.SocketAddress_PortValue{PortValue: uint32(port)},
+							},
+						},
+					},
+					Hostname: inst.HostName,
+				},
+			},
+			HealthStatus: healthStatus(inst.EffectiveStatus()),
+		})
+	}
+
+	sort.Slice(localities, func(i, j int) bool {
+		li, lj := localities[i].Locality, localities[j].Locality
+		return li.Region+"/"+li.Zone < lj.Region+"/"+lj.Zone
+	})
+	return &endpointv3.ClusterLoadAssignment{ClusterName: vip, Endpoints: localities}
+}
+
+// instanceLocality derives the locality of an instance from its region and the availability zone of its datacenter.
+func instanceLocality(inst *eureka.Instance) *corev3.Locality {
+	return &corev3.Locality{Region: inst.Region, Zone: inst.AvailabilityZone()}
+}
+
+// healthStatus maps the status of an instance to the health of its endpoint.
+func healthStatus(status eureka.StatusType) corev3.HealthStatus {
+	switch status {
+	case eureka.UP:
+		return corev3.HealthStatus_HEALTHY
+	case eureka.OUTOFSERVICE:
+		return corev3.HealthStatus_DRAINING
+	case eureka.DOWN, eureka.STARTING:
+		return corev3.HealthStatus_UNHEALTHY
+	default:
+		return corev3.HealthStatus_UNKNOWN
+	}
+}
+
+func equalResources(a, b map[resourcev3.Type][]types.Resource) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for typ, resources := range a {
+		other := b[typ]
+		if len(resources) != len(other) {
+			return false
+		}
+		for i := range resources {
+			if !proto.Equal(resources[i], other[i]) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// constantHash maps all Envoy nodes to the same snapshot.
+type constantHash struct{}
+
+func (constantHash) ID(node *corev3.Node) string {
+	return snapshotNode
+}
@@ -0,0 +1,191 @@
+// Copyright 2016 IBM Corporation
+//
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+//
+//       http://www.apache.org/licenses/LICENSE-2.0
+//
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+
+package xds
+
+import (
+	"context"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	eureka "github.com/amalgam8/go-eureka-client"
+	clusterv3 "github.com/envoyproxy/go-control-plane/envoy/config/cluster/v3"
+	corev3 "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	endpointv3 "github.com/envoyproxy/go-control-plane/envoy/config/endpoint/v3"
+	discoveryservice "github.com/envoyproxy/go-control-plane/envoy/service/discovery/v3"
+	resourcev3 "github.com/envoyproxy/go-control-plane/pkg/resource/v3"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+type fakeSource struct {
+	sync.Mutex
+	apps []*eureka.Application
+}
+
+func (f *fakeSource) GetApplications() ([]*eureka.Application, error) {
+	f.Lock()
+	defer f.Unlock()
+	return f.apps, nil
+}
+
+func (f *fakeSource) set(apps ...*eureka.Application) {
+	f.Lock()
+	defer f.Unlock()
+	f.apps = apps
+}
+
+func testInstance(host, ip, vip, zone string, status eureka.StatusType) *eureka.Instance {
+	return &eureka.Instance{
+		HostName: host,
+		IPAddr:   ip,
+		VIPAddr:  vip,
+		Status:   string(status),
+		Region:   "us-east-1",
+		Port:     &eureka.Port{Enabled: "true", Value: float64(8080)},
+		Datacenter: &eureka.DatacenterInfo{Name: "Amazon",
+			Metadata: eureka.DatacenterMetadata{"availability-zone": zone}},
+	}
+}
+
+// startBridge serves the bridge on an in-process gRPC server, and returns an ADS client stream.
+func startBridge(t *testing.T, b Bridge) (discoveryservice.AggregatedDiscoveryService_StreamAggregatedResourcesClient, func()) {
+	listener := bufconn.Listen(1 << 20)
+	server := grpc.NewServer()
+	b.Register(server)
+	go server.Serve(listener)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return listener.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatalf("error = %v", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	stream, err := discoveryservice.NewAggregatedDiscoveryServiceClient(conn).StreamAggregatedResources(ctx)
+	if err != nil {
+		t.Fatalf("error = %v", err)
+	}
+	return stream, func() {
+		cancel()
+		conn.Close()
+		server.Stop()
+	}
+}
+
+func receive(t *testing.T, stream discoveryservice.AggregatedDiscoveryService_StreamAggregatedResourcesClient) *discoveryservice.DiscoveryResponse {
+	resp, err := stream.Recv()
+	if err != nil {
+		t.Fatalf("error = %v", err)
+	}
+	return resp
+}
+
+func TestBridgeServesClustersAndEndpoints(t *testing.T) {
+	source := &fakeSource{}
+	source.set(&eureka.Application{Name: "APP1", Instances: []*eureka.Instance{
+		testInstance("inst1", "10.0.0.1", "vip1", "us-east-1a", eureka.UP),
+		testInstance("inst2", "10.0.0.2", "vip1", "us-east-1b", eureka.OUTOFSERVICE),
+	}})
+	events := eureka.NewEventBroadcaster()
+	b, err := NewBridge(source, events, Config{})
+	if err != nil {
+		t.Fatalf("error = %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go b.Run(ctx)
+
+	stream, stop := startBridge(t, b)
+	defer stop()
+	node := &corev3.Node{Id: "envoy1"}
+
+	stream.Send(&discoveryservice.DiscoveryRequest{Node: node, TypeUrl: resourcev3.ClusterType})
+	resp := receive(t, stream)
+	cdsNonce := resp.Nonce
+	if resp.VersionInfo != "1" || len(resp.Resources) != 1 {
+		t.Fatalf("unexpected CDS response version %s with %d resources", resp.VersionInfo, len(resp.Resources))
+	}
+	var cluster clusterv3.Cluster
+	if err = resp.Resources[0].UnmarshalTo(&cluster); err != nil || cluster.Name != "vip1" {
+		t.Errorf("unexpected cluster %v (%v)", cluster.Name, err)
+	}
+
+	stream.Send(&discoveryservice.DiscoveryRequest{Node: node, TypeUrl: resourcev3.EndpointType, ResourceNames: []string{"vip1"}})
+	resp = receive(t, stream)
+	var assignment endpointv3.ClusterLoadAssignment
+	if err = resp.Resources[0].UnmarshalTo(&assignment); err != nil {
+		t.Fatalf("error = %v", err)
+	}
+	if len(assignment.Endpoints) != 2 {
+		t.Fatalf("expected 2 localities, got %d", len(assignment.Endpoints))
+	}
+	first, second := assignment.Endpoints[0], assignment.Endpoints[1]
+	if first.Locality.Region != "us-east-1" || first.Locality.Zone != "us-east-1a" || second.Locality.Zone != "us-east-1b" {
+		t.Errorf("unexpected localities %v, %v", first.Locality, second.Locality)
+	}
+	if first.LbEndpoints[0].HealthStatus != corev3.HealthStatus_HEALTHY ||
+		second.LbEndpoints[0].HealthStatus != corev3.HealthStatus_DRAINING {
+		t.Errorf("unexpected health statuses %v, %v", first.LbEndpoints[0].HealthStatus, second.LbEndpoints[0].HealthStatus)
+	}
+
+	// An event publishes a new version of the clusters
+	stream.Send(&discoveryservice.DiscoveryRequest{Node: node, TypeUrl: resourcev3.ClusterType,
+		VersionInfo: "1", ResponseNonce: cdsNonce})
+	added := testInstance("inst3", "10.0.0.3", "vip2", "us-east-1a", eureka.UP)
+	source.set(&eureka.Application{Name: "APP1", Instances: []*eureka.Instance{
+		testInstance("inst1", "10.0.0.1", "vip1", "us-east-1a", eureka.UP),
+		testInstance("inst2", "10.0.0.2", "vip1", "us-east-1b", eureka.OUTOFSERVICE),
+		added,
+	}})
+	events.OnAdd(added)
+
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		resp = receive(t, stream)
+		if resp.TypeUrl == resourcev3.ClusterType && resp.VersionInfo == "2" {
+			if len(resp.Resources) != 2 {
+				t.Errorf("expected 2 clusters, got %d", len(resp.Resources))
+			}
+			return
+		}
+	}
+	t.Errorf("a new cluster version should be published, current version %s", b.Version())
+}
+
+func TestBridgeKeepsVersionWhenUnchanged(t *testing.T) {
+	source := &fakeSource{}
+	source.set(&eureka.Application{Name: "APP1", Instances: []*eureka.Instance{
+		testInstance("inst1", "10.0.0.1", "vip1", "us-east-1a", eureka.UP),
+	}})
+	b, _ := NewBridge(source, nil, Config{})
+	br := b.(*bridge)
+
+	br.update(context.Background())
+	br.update(context.Background())
+	if b.Version() != "1" {
+		t.Errorf("version = %s, expected unchanged resources to keep the version", b.Version())
+	}
+
+	source.set(&eureka.Application{Name: "APP1", Instances: []*eureka.Instance{
+		testInstance("inst1", "10.0.0.1", "vip1", "us-east-1a", eureka.DOWN),
+	}})
+	br.update(context.Background())
+	if b.Version() != "2" {
+		t.Errorf("version = %s, expected a status change to bump the version", b.Version())
+	}
+}